@@ -109,3 +109,38 @@ func IsStyle(code string) bool {
 		return false
 	}
 }
+
+// MaxMessageLength is the maximum length of a single chat message the client accepts.
+const MaxMessageLength = 119
+
+// MaxInputLength is the maximum length of a chat message a client is allowed to send.
+const MaxInputLength = 100
+
+// SplitMessage splits the message into lines that fit into a single chat packet.
+// The message is split at new lines and at MaxMessageLength, color of the previous line
+// is carried over to the next one.
+func SplitMessage(msg string) []string {
+	var lines []string
+	for _, line := range strings.Split(msg, "\n") {
+		runes := []rune(line)
+		lastCode := ""
+		for {
+			if len(runes) <= MaxMessageLength {
+				lines = append(lines, string(runes))
+				break
+			}
+			cut := MaxMessageLength
+			if runes[cut-1] == ColorSymbol {
+				cut-- // do not split the color code
+			}
+			lines = append(lines, string(runes[:cut]))
+			for i := 0; i < cut-1; i++ {
+				if runes[i] == ColorSymbol {
+					lastCode = string(runes[i : i+2])
+				}
+			}
+			runes = append([]rune(lastCode), runes[cut:]...)
+		}
+	}
+	return lines
+}
@@ -71,6 +71,7 @@ func (cm *CommandManager) ExecuteCommand(sender CommandSender, buffer string) bo
 
 	if !sender.HasPermission(cmd.Permission) {
 		sender.SendMessage("you do not have permissions to execute this command")
+		return false
 	}
 
 	cm.logger.Info(sender, " executed command: /", buffer)
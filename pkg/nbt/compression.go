@@ -0,0 +1,94 @@
+package nbt
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+// Compression is the framing of NBT data
+type Compression byte
+
+const (
+	// None is uncompressed NBT data
+	None Compression = iota
+	// Gzip is NBT data compressed with gzip, used by level.dat, player files and schematics
+	Gzip
+	// Zlib is NBT data compressed with zlib, used by region files
+	Zlib
+)
+
+func (c Compression) String() string {
+	switch c {
+	case None:
+		return "none"
+	case Gzip:
+		return "gzip"
+	case Zlib:
+		return "zlib"
+	default:
+		return fmt.Sprintf("Compression(%d)", byte(c))
+	}
+}
+
+// DetectCompression detects the compression of NBT data from its first two bytes.
+func DetectCompression(header []byte) Compression {
+	switch {
+	case len(header) >= 2 && header[0] == 0x1F && header[1] == 0x8B:
+		return Gzip
+	case len(header) >= 2 && header[0]&0x0F == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0:
+		return Zlib
+	default:
+		return None
+	}
+}
+
+// NewReader returns reader of the uncompressed NBT data read from r.
+// If the compression is not known, use NewDetectingReader instead.
+func NewReader(r io.Reader, compression Compression) (io.ReadCloser, error) {
+	switch compression {
+	case None:
+		return io.NopCloser(r), nil
+	case Gzip:
+		return gzip.NewReader(r)
+	case Zlib:
+		return zlib.NewReader(r)
+	default:
+		return nil, fmt.Errorf("unknown compression: %v", compression)
+	}
+}
+
+// NewDetectingReader returns reader of the uncompressed NBT data read from r, detecting its compression.
+func NewDetectingReader(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return NewReader(buffered, DetectCompression(header))
+}
+
+// NewWriter returns writer compressing NBT data written to it into w.
+// The writer has to be closed to flush all data.
+func NewWriter(w io.Writer, compression Compression) (io.WriteCloser, error) {
+	switch compression {
+	case None:
+		return nopWriteCloser{w}, nil
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zlib:
+		return zlib.NewWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown compression: %v", compression)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,435 @@
+package nbt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/Pesekjak/173go/pkg/buff"
+)
+
+const (
+	// maxDepth is the maximum nesting of lists and compounds, protects against malicious data
+	maxDepth = 512
+	// maxLength is the maximum length of byte arrays and lists, protects against corrupted lengths
+	maxLength = 1 << 24
+	// preallocLength is the length up to which byte arrays and lists are allocated before they are read,
+	// longer ones grow as their data arrive, so a corrupted length fails at the end of the data
+	preallocLength = 1 << 16
+)
+
+var (
+	compoundType = reflect.TypeOf(Compound(nil))
+	listType     = reflect.TypeOf(List{})
+)
+
+// Decoder reads NBT data from a stream. The data are read as they are needed,
+// without loading the whole tree first.
+type Decoder struct {
+	buf   *buff.MCReader
+	depth int
+}
+
+// NewDecoder creates decoder reading uncompressed NBT data from r.
+// Use NewReader or NewDetectingReader to decode compressed data.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{buf: buff.NewReader(r)}
+}
+
+// Read reads a named root compound from uncompressed NBT data.
+func Read(r io.Reader) (name string, root Compound, err error) {
+	name, err = NewDecoder(r).Decode(&root)
+	return name, root, err
+}
+
+// Unmarshal decodes uncompressed NBT data into the value pointed to by v and returns name of the root tag.
+func Unmarshal(data []byte, v interface{}) (string, error) {
+	return NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// Decode reads the next named tag into the value pointed to by v and returns its name.
+//
+// Tags are decoded into structs (see fieldsOf for the field mapping), maps with string keys, slices,
+// arrays and values of matching kind. Numeric tags can be decoded into any numeric kind, TAG_Byte into bool.
+// Decoding into an empty interface, Compound or List produces the tree representation of the tag.
+// Tags of a compound without matching struct field are skipped.
+func (d *Decoder) Decode(v interface{}) (string, error) {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return "", fmt.Errorf("can not decode into non-pointer %T", v)
+	}
+	tagType, name, err := d.ReadHeader()
+	if err != nil {
+		return "", err
+	}
+	if tagType == TagEnd {
+		return "", fmt.Errorf("unexpected %v", TagEnd)
+	}
+	return name, d.decode(tagType, value.Elem())
+}
+
+// ReadHeader reads the type and name of the next named tag. TAG_End has no name.
+func (d *Decoder) ReadHeader() (TagType, string, error) {
+	tagType, err := d.buf.ReadByte()
+	if err != nil {
+		return TagEnd, "", err
+	}
+	if TagType(tagType) == TagEnd {
+		return TagEnd, "", nil
+	}
+	name, err := d.buf.ReadString8()
+	return TagType(tagType), name, err
+}
+
+// ReadPayload reads the payload of a tag with given type into its tree representation.
+func (d *Decoder) ReadPayload(tagType TagType) (interface{}, error) {
+	switch tagType {
+	case TagByte:
+		v, err := d.buf.ReadByte()
+		return int8(v), err
+	case TagShort:
+		return d.buf.ReadShort()
+	case TagInt:
+		return d.buf.ReadInt()
+	case TagLong:
+		return d.buf.ReadLong()
+	case TagFloat:
+		return d.buf.ReadFloat()
+	case TagDouble:
+		return d.buf.ReadDouble()
+	case TagByteArray:
+		return d.readByteArray()
+	case TagString:
+		return d.buf.ReadString8()
+	case TagList:
+		elementType, length, err := d.readListHeader()
+		if err != nil {
+			return nil, err
+		}
+		if err = d.enter(); err != nil {
+			return nil, err
+		}
+		list := List{Type: elementType, Values: make([]interface{}, 0, min(length, preallocLength))}
+		for i := 0; i < length; i++ {
+			value, err := d.ReadPayload(elementType)
+			if err != nil {
+				return nil, err
+			}
+			list.Values = append(list.Values, value)
+		}
+		d.depth--
+		return list, nil
+	case TagCompound:
+		if err := d.enter(); err != nil {
+			return nil, err
+		}
+		compound := make(Compound)
+		for {
+			childType, name, err := d.ReadHeader()
+			if err != nil {
+				return nil, err
+			}
+			if childType == TagEnd {
+				d.depth--
+				return compound, nil
+			}
+			if compound[name], err = d.ReadPayload(childType); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown tag type: %v", tagType)
+	}
+}
+
+// Skip reads and discards the payload of a tag with given type.
+func (d *Decoder) Skip(tagType TagType) error {
+	var size int64
+	switch tagType {
+	case TagByte:
+		size = 1
+	case TagShort:
+		size = 2
+	case TagInt, TagFloat:
+		size = 4
+	case TagLong, TagDouble:
+		size = 8
+	case TagByteArray:
+		length, err := d.buf.ReadInt()
+		if err != nil {
+			return err
+		}
+		if err = checkLength(TagByteArray, length); err != nil {
+			return err
+		}
+		size = int64(length)
+	case TagString:
+		length, err := d.buf.ReadShort()
+		if err != nil {
+			return err
+		}
+		size = int64(uint16(length))
+	case TagList:
+		elementType, length, err := d.readListHeader()
+		if err != nil {
+			return err
+		}
+		if err = d.enter(); err != nil {
+			return err
+		}
+		for i := 0; i < length; i++ {
+			if err = d.Skip(elementType); err != nil {
+				return err
+			}
+		}
+		d.depth--
+		return nil
+	case TagCompound:
+		if err := d.enter(); err != nil {
+			return err
+		}
+		for {
+			childType, _, err := d.ReadHeader()
+			if err != nil {
+				return err
+			}
+			if childType == TagEnd {
+				d.depth--
+				return nil
+			}
+			if err = d.Skip(childType); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown tag type: %v", tagType)
+	}
+	_, err := io.CopyN(io.Discard, d.buf, size)
+	return err
+}
+
+// enter enters a nested list or compound
+func (d *Decoder) enter() error {
+	if d.depth >= maxDepth {
+		return fmt.Errorf("NBT data is nested too deep")
+	}
+	d.depth++
+	return nil
+}
+
+func (d *Decoder) readByteArray() ([]byte, error) {
+	length, err := d.buf.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+	if err = checkLength(TagByteArray, length); err != nil {
+		return nil, err
+	}
+	data := bytes.NewBuffer(make([]byte, 0, min(int(length), preallocLength)))
+	if _, err = io.CopyN(data, d.buf, int64(length)); err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+func (d *Decoder) readListHeader() (TagType, int, error) {
+	elementType, err := d.buf.ReadByte()
+	if err != nil {
+		return TagEnd, 0, err
+	}
+	length, err := d.buf.ReadInt()
+	if err != nil {
+		return TagEnd, 0, err
+	}
+	if err = checkLength(TagList, length); err != nil {
+		return TagEnd, 0, err
+	}
+	return TagType(elementType), int(length), nil
+}
+
+// checkLength rejects the negative lengths and the lengths above maxLength read from the data
+func checkLength(tagType TagType, length int32) error {
+	if length < 0 {
+		return fmt.Errorf("negative %v length: %v", tagType, length)
+	}
+	if length > maxLength {
+		return fmt.Errorf("%v length %v exceeds the limit of %v", tagType, length, maxLength)
+	}
+	return nil
+}
+
+// decode reads payload of a tag with given type into the value
+func (d *Decoder) decode(tagType TagType, v reflect.Value) error {
+	switch {
+	case v.Kind() == reflect.Interface && v.NumMethod() == 0,
+		v.Type() == compoundType && tagType == TagCompound,
+		v.Type() == listType && tagType == TagList:
+		value, err := d.ReadPayload(tagType)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(value))
+		return nil
+	case v.Kind() == reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(tagType, v.Elem())
+	}
+
+	switch tagType {
+	case TagByte, TagShort, TagInt, TagLong, TagFloat, TagDouble:
+		value, err := d.ReadPayload(tagType)
+		if err != nil {
+			return err
+		}
+		return setNumber(v, value)
+	case TagByteArray:
+		if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Uint8 {
+			return mismatch(tagType, v)
+		}
+		data, err := d.readByteArray()
+		if err != nil {
+			return err
+		}
+		v.SetBytes(data)
+		return nil
+	case TagString:
+		if v.Kind() != reflect.String {
+			return mismatch(tagType, v)
+		}
+		s, err := d.buf.ReadString8()
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+		return nil
+	case TagList:
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return mismatch(tagType, v)
+		}
+		elementType, length, err := d.readListHeader()
+		if err != nil {
+			return err
+		}
+		if v.Kind() == reflect.Array && length > v.Len() {
+			return fmt.Errorf("can not decode %v of length %v into %v", TagList, length, v.Type())
+		}
+		if err = d.enter(); err != nil {
+			return err
+		}
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), 0, min(length, preallocLength)))
+		}
+		for i := 0; i < length; i++ {
+			if v.Kind() == reflect.Slice {
+				v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+			}
+			if err = d.decode(elementType, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		d.depth--
+		return nil
+	case TagCompound:
+		switch {
+		case v.Kind() == reflect.Struct:
+			return d.decodeStruct(v)
+		case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+			return d.decodeMap(v)
+		default:
+			return mismatch(tagType, v)
+		}
+	default:
+		return fmt.Errorf("unknown tag type: %v", tagType)
+	}
+}
+
+func (d *Decoder) decodeStruct(v reflect.Value) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	fields := fieldsOf(v.Type())
+	for {
+		childType, name, err := d.ReadHeader()
+		if err != nil {
+			return err
+		}
+		if childType == TagEnd {
+			d.depth--
+			return nil
+		}
+		f, ok := fieldByName(fields, name)
+		if !ok {
+			if err = d.Skip(childType); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = d.decode(childType, v.Field(f.index)); err != nil {
+			return fmt.Errorf("tag '%v': %v", name, err)
+		}
+	}
+}
+
+func (d *Decoder) decodeMap(v reflect.Value) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	for {
+		childType, name, err := d.ReadHeader()
+		if err != nil {
+			return err
+		}
+		if childType == TagEnd {
+			d.depth--
+			return nil
+		}
+		element := reflect.New(v.Type().Elem()).Elem()
+		if err = d.decode(childType, element); err != nil {
+			return fmt.Errorf("tag '%v': %v", name, err)
+		}
+		v.SetMapIndex(reflect.ValueOf(name).Convert(v.Type().Key()), element)
+	}
+}
+
+// setNumber stores a numeric tag value in a value of numeric or bool kind
+func setNumber(v reflect.Value, value interface{}) error {
+	number := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Bool:
+		if b, ok := value.(int8); ok {
+			v.SetBool(b != 0)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if number.CanInt() {
+			v.SetInt(number.Int())
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if number.CanInt() {
+			v.SetUint(uint64(number.Int()))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if number.CanFloat() {
+			v.SetFloat(number.Float())
+			return nil
+		}
+		if number.CanInt() {
+			v.SetFloat(float64(number.Int()))
+			return nil
+		}
+	}
+	tagType, _ := TypeOf(value)
+	return mismatch(tagType, v)
+}
+
+func mismatch(tagType TagType, v reflect.Value) error {
+	return fmt.Errorf("can not decode %v into %v", tagType, v.Type())
+}
@@ -0,0 +1,93 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// header creates the header of a root tag with empty name
+func header(tagType TagType) []byte {
+	return []byte{byte(tagType), 0, 0}
+}
+
+func length(n int32) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(n))
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	type level struct {
+		Blocks   []byte  `nbt:"Blocks"`
+		Heights  []int32 `nbt:"Heights"`
+		Name     string  `nbt:"Name"`
+		Raining  bool    `nbt:"Raining"`
+		Entities List    `nbt:"Entities"`
+	}
+	in := level{
+		Blocks:   []byte{1, 2, 3},
+		Heights:  []int32{64, 65},
+		Name:     "world",
+		Raining:  true,
+		Entities: NewList(TagCompound, Compound{"id": "Item", "Count": int8(3)}),
+	}
+	data, err := Marshal("Level", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out level
+	name, err := Unmarshal(data, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Level" || !bytes.Equal(out.Blocks, in.Blocks) || len(out.Heights) != 2 || out.Heights[1] != 65 ||
+		out.Name != in.Name || !out.Raining || len(out.Entities.Values) != 1 {
+		t.Errorf("decoded %+v, want %+v", out, in)
+	}
+	if count, _ := out.Entities.Values[0].(Compound).Byte("Count"); count != 3 {
+		t.Errorf("decoded entity count %v, want 3", count)
+	}
+}
+
+func TestDecodeCorruptLengths(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"negative byte array", append(header(TagByteArray), length(-1)...), "negative"},
+		{"byte array over limit", append(header(TagByteArray), length(maxLength+1)...), "exceeds"},
+		{"byte array past input", append(header(TagByteArray), length(maxLength)...), "EOF"},
+		{"negative list", append(header(TagList), append([]byte{byte(TagInt)}, length(-5)...)...), "negative"},
+		{"list over limit", append(header(TagList), append([]byte{byte(TagInt)}, length(1<<30)...)...), "exceeds"},
+		{"list past input", append(header(TagList), append([]byte{byte(TagInt)}, length(maxLength)...)...), "EOF"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, v := range []interface{}{new(interface{}), new([]byte), new([]int32)} {
+				if _, isBytes := v.(*[]byte); isBytes != (test.data[0] == byte(TagByteArray)) {
+					continue
+				}
+				_, err := Unmarshal(test.data, v)
+				if err == nil || !strings.Contains(err.Error(), test.want) {
+					t.Errorf("decoding into %T: got error %v, want %q", v, err, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeCorruptLengthAllocation(t *testing.T) {
+	data := append(header(TagByteArray), length(maxLength)...)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	var v []byte
+	if _, err := Unmarshal(data, &v); err == nil {
+		t.Fatal("decoded a byte array longer than the data")
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("allocated %v bytes for a truncated byte array", allocated)
+	}
+}
@@ -0,0 +1,277 @@
+package nbt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/Pesekjak/173go/pkg/buff"
+)
+
+// Encoder writes NBT data to a stream.
+type Encoder struct {
+	buf *buff.MCWriter
+}
+
+// NewEncoder creates encoder writing uncompressed NBT data to w.
+// Use NewWriter to encode compressed data.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{buf: buff.NewWriter(w)}
+}
+
+// Write writes a named root compound as uncompressed NBT data.
+func Write(w io.Writer, name string, root Compound) error {
+	return NewEncoder(w).Encode(name, root)
+}
+
+// Marshal encodes the value as a named tag into uncompressed NBT data.
+func Marshal(name string, v interface{}) ([]byte, error) {
+	var data bytes.Buffer
+	if err := NewEncoder(&data).Encode(name, v); err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+// Encode writes the value as a named tag.
+//
+// Structs (see fieldsOf for the field mapping) and maps with string keys are encoded as TAG_Compound,
+// []byte as TAG_Byte_Array, other slices and arrays as TAG_List. Bool is encoded as TAG_Byte,
+// int and uint as TAG_Int and other numeric kinds as tags of the same size. Compound, List
+// and tree values in empty interfaces are encoded as they are.
+func (e *Encoder) Encode(name string, v interface{}) error {
+	value := reflect.ValueOf(v)
+	tagType, err := typeOfValue(value)
+	if err != nil {
+		return err
+	}
+	if err = e.WriteHeader(tagType, name); err != nil {
+		return err
+	}
+	return e.encode(tagType, value)
+}
+
+// WriteHeader writes the type and name of a named tag. TAG_End is written without name.
+func (e *Encoder) WriteHeader(tagType TagType, name string) error {
+	if err := e.buf.WriteByte(byte(tagType)); err != nil {
+		return err
+	}
+	if tagType == TagEnd {
+		return nil
+	}
+	return e.buf.WriteString8(name)
+}
+
+// encode writes payload of a tag with given type represented by the value
+func (e *Encoder) encode(tagType TagType, v reflect.Value) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	if v.Type() == listType {
+		list := v.Interface().(List)
+		if err := e.writeListHeader(list.Type, len(list.Values)); err != nil {
+			return err
+		}
+		for _, element := range list.Values {
+			elementValue := reflect.ValueOf(element)
+			if elementType, err := typeOfValue(elementValue); err != nil {
+				return err
+			} else if elementType != list.Type {
+				return fmt.Errorf("%v of %v contains %v", TagList, list.Type, elementType)
+			}
+			if err := e.encode(list.Type, elementValue); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch tagType {
+	case TagByte:
+		if v.Kind() == reflect.Bool {
+			return e.buf.WriteBool(v.Bool())
+		}
+		return e.buf.WriteByte(byte(integer(v)))
+	case TagShort:
+		return e.buf.WriteShort(int16(integer(v)))
+	case TagInt:
+		return e.buf.WriteInt(int32(integer(v)))
+	case TagLong:
+		return e.buf.WriteLong(integer(v))
+	case TagFloat:
+		return e.buf.WriteFloat(float32(v.Float()))
+	case TagDouble:
+		return e.buf.WriteDouble(v.Float())
+	case TagByteArray:
+		if err := e.buf.WriteInt(int32(v.Len())); err != nil {
+			return err
+		}
+		return e.buf.WriteBytes(v.Bytes())
+	case TagString:
+		return e.buf.WriteString8(v.String())
+	case TagList:
+		elementType, err := typeOfElements(v)
+		if err != nil {
+			return err
+		}
+		if err = e.writeListHeader(elementType, v.Len()); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			element := v.Index(i)
+			if actual, err := typeOfValue(element); err != nil {
+				return err
+			} else if actual != elementType {
+				return fmt.Errorf("%v of %v contains %v", TagList, elementType, actual)
+			}
+			if err = e.encode(elementType, element); err != nil {
+				return err
+			}
+		}
+		return nil
+	case TagCompound:
+		if v.Kind() == reflect.Struct {
+			return e.encodeStruct(v)
+		}
+		return e.encodeMap(v)
+	default:
+		return fmt.Errorf("unknown tag type: %v", tagType)
+	}
+}
+
+func (e *Encoder) encodeStruct(v reflect.Value) error {
+	for _, f := range fieldsOf(v.Type()) {
+		fieldValue := v.Field(f.index)
+		if f.omitEmpty && isEmpty(fieldValue) {
+			continue
+		}
+		if err := e.encodeNamed(f.name, fieldValue); err != nil {
+			return err
+		}
+	}
+	return e.WriteHeader(TagEnd, "")
+}
+
+func (e *Encoder) encodeMap(v reflect.Value) error {
+	// sorted for deterministic output
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	for _, key := range keys {
+		if err := e.encodeNamed(key.String(), v.MapIndex(key)); err != nil {
+			return err
+		}
+	}
+	return e.WriteHeader(TagEnd, "")
+}
+
+func (e *Encoder) encodeNamed(name string, v reflect.Value) error {
+	tagType, err := typeOfValue(v)
+	if err != nil {
+		return fmt.Errorf("tag '%v': %v", name, err)
+	}
+	if err = e.WriteHeader(tagType, name); err != nil {
+		return err
+	}
+	if err = e.encode(tagType, v); err != nil {
+		return fmt.Errorf("tag '%v': %v", name, err)
+	}
+	return nil
+}
+
+func (e *Encoder) writeListHeader(elementType TagType, length int) error {
+	if err := e.buf.WriteByte(byte(elementType)); err != nil {
+		return err
+	}
+	return e.buf.WriteInt(int32(length))
+}
+
+// typeOfValue returns the tag type the value is encoded as
+func typeOfValue(v reflect.Value) (TagType, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return TagEnd, fmt.Errorf("can not encode nil value")
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return TagEnd, fmt.Errorf("can not encode nil value")
+	}
+	return typeOf(v.Type())
+}
+
+// typeOf returns the tag type values of given type are encoded as
+func typeOf(t reflect.Type) (TagType, error) {
+	if t == listType {
+		return TagList, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int8, reflect.Uint8:
+		return TagByte, nil
+	case reflect.Int16, reflect.Uint16:
+		return TagShort, nil
+	case reflect.Int, reflect.Uint, reflect.Int32, reflect.Uint32:
+		return TagInt, nil
+	case reflect.Int64, reflect.Uint64:
+		return TagLong, nil
+	case reflect.Float32:
+		return TagFloat, nil
+	case reflect.Float64:
+		return TagDouble, nil
+	case reflect.String:
+		return TagString, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return TagByteArray, nil
+		}
+		return TagList, nil
+	case reflect.Array:
+		return TagList, nil
+	case reflect.Struct:
+		return TagCompound, nil
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String {
+			return TagCompound, nil
+		}
+	case reflect.Pointer:
+		return typeOf(t.Elem())
+	}
+	return TagEnd, fmt.Errorf("values of type %v can not be represented as a NBT tag", t)
+}
+
+// typeOfElements returns the tag type of elements of a slice or array
+func typeOfElements(v reflect.Value) (TagType, error) {
+	elementType := v.Type().Elem()
+	if elementType.Kind() == reflect.Interface {
+		if v.Len() == 0 {
+			return TagByte, nil // vanilla uses TAG_Byte for empty lists
+		}
+		return typeOfValue(v.Index(0))
+	}
+	return typeOf(elementType)
+}
+
+// TypeOf returns the tag type the value is encoded as.
+func TypeOf(value interface{}) (TagType, error) {
+	return typeOfValue(reflect.ValueOf(value))
+}
+
+func integer(v reflect.Value) int64 {
+	if v.CanUint() {
+		return int64(v.Uint())
+	}
+	return v.Int()
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
@@ -0,0 +1,61 @@
+package nbt
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// field is a struct field mapped to a named tag
+type field struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+// structFields caches fields of struct types
+var structFields sync.Map // map[reflect.Type][]field
+
+// fieldsOf returns the fields of a struct type mapped to named tags.
+//
+// Exported fields are mapped using the name in their `nbt` struct tag, or their Go name if the tag is missing.
+// Fields with tag "-" are ignored, ",omitempty" option skips the field when encoding its zero value.
+func fieldsOf(t reflect.Type) []field {
+	if cached, ok := structFields.Load(t); ok {
+		return cached.([]field)
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+		tag := structField.Tag.Get("nbt")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = structField.Name
+		}
+		fields = append(fields, field{
+			name:      name,
+			index:     i,
+			omitEmpty: options == "omitempty",
+		})
+	}
+
+	cached, _ := structFields.LoadOrStore(t, fields)
+	return cached.([]field)
+}
+
+// fieldByName finds field mapped to the tag with given name
+func fieldByName(fields []field, name string) (field, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
@@ -0,0 +1,127 @@
+package nbt
+
+import "fmt"
+
+// TagType is the type of NBT tag
+type TagType byte
+
+const (
+	TagEnd TagType = iota
+	TagByte
+	TagShort
+	TagInt
+	TagLong
+	TagFloat
+	TagDouble
+	TagByteArray
+	TagString
+	TagList
+	TagCompound
+)
+
+func (t TagType) String() string {
+	switch t {
+	case TagEnd:
+		return "TAG_End"
+	case TagByte:
+		return "TAG_Byte"
+	case TagShort:
+		return "TAG_Short"
+	case TagInt:
+		return "TAG_Int"
+	case TagLong:
+		return "TAG_Long"
+	case TagFloat:
+		return "TAG_Float"
+	case TagDouble:
+		return "TAG_Double"
+	case TagByteArray:
+		return "TAG_Byte_Array"
+	case TagString:
+		return "TAG_String"
+	case TagList:
+		return "TAG_List"
+	case TagCompound:
+		return "TAG_Compound"
+	default:
+		return fmt.Sprintf("TAG_Unknown(%d)", byte(t))
+	}
+}
+
+// Compound is a TAG_Compound, a collection of named tags.
+// Values are int8 (TAG_Byte), int16 (TAG_Short), int32 (TAG_Int), int64 (TAG_Long), float32 (TAG_Float),
+// float64 (TAG_Double), []byte (TAG_Byte_Array), string (TAG_String), List (TAG_List) and Compound (TAG_Compound).
+type Compound map[string]interface{}
+
+// List is a TAG_List, a collection of unnamed tags of the same type.
+type List struct {
+	// Type of the tags in the list
+	Type TagType
+	// Values of the list, all of the Go type matching the Type
+	Values []interface{}
+}
+
+// NewList creates new list of given type
+func NewList(tagType TagType, values ...interface{}) List {
+	return List{Type: tagType, Values: values}
+}
+
+// Byte returns the TAG_Byte with given name, ok is false if there is no such tag.
+func (c Compound) Byte(name string) (v int8, ok bool) {
+	v, ok = c[name].(int8)
+	return
+}
+
+// Short returns the TAG_Short with given name, ok is false if there is no such tag.
+func (c Compound) Short(name string) (v int16, ok bool) {
+	v, ok = c[name].(int16)
+	return
+}
+
+// Int returns the TAG_Int with given name, ok is false if there is no such tag.
+func (c Compound) Int(name string) (v int32, ok bool) {
+	v, ok = c[name].(int32)
+	return
+}
+
+// Long returns the TAG_Long with given name, ok is false if there is no such tag.
+func (c Compound) Long(name string) (v int64, ok bool) {
+	v, ok = c[name].(int64)
+	return
+}
+
+// Float returns the TAG_Float with given name, ok is false if there is no such tag.
+func (c Compound) Float(name string) (v float32, ok bool) {
+	v, ok = c[name].(float32)
+	return
+}
+
+// Double returns the TAG_Double with given name, ok is false if there is no such tag.
+func (c Compound) Double(name string) (v float64, ok bool) {
+	v, ok = c[name].(float64)
+	return
+}
+
+// ByteArray returns the TAG_Byte_Array with given name, ok is false if there is no such tag.
+func (c Compound) ByteArray(name string) (v []byte, ok bool) {
+	v, ok = c[name].([]byte)
+	return
+}
+
+// String returns the TAG_String with given name, ok is false if there is no such tag.
+func (c Compound) String(name string) (v string, ok bool) {
+	v, ok = c[name].(string)
+	return
+}
+
+// List returns the TAG_List with given name, ok is false if there is no such tag.
+func (c Compound) List(name string) (v List, ok bool) {
+	v, ok = c[name].(List)
+	return
+}
+
+// Compound returns the TAG_Compound with given name, ok is false if there is no such tag.
+func (c Compound) Compound(name string) (v Compound, ok bool) {
+	v, ok = c[name].(Compound)
+	return
+}
@@ -27,6 +27,8 @@ type Connection struct {
 	logger *log.Logger
 
 	report chan system.Message
+
+	closeHandlers []func()
 }
 
 func NewConnection(tcp *net.TCPConn, logger *log.Logger, report chan system.Message) *Connection {
@@ -128,21 +130,36 @@ func (c *Connection) WritePacket(packet prot.PacketOut, flush bool) error {
 }
 
 func (c *Connection) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return io.ErrClosedPipe
+	}
 	return c.writer.Flush()
 }
 
+// OnClose registers a handler called once the connection is closed.
+// Handlers are called outside the connection lock, so they can safely use the connection.
+func (c *Connection) OnClose(handler func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeHandlers = append(c.closeHandlers, handler)
+}
+
 func (c *Connection) Close(err error) {
 	if err == nil {
 		c.CloseWith(nil, "")
+		return
 	}
 	c.CloseWith(err, base.ConvertToString(err))
 }
 
 func (c *Connection) CloseWith(err error, reason string) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
 
 	if c.closed {
+		c.mu.Unlock()
 		return
 	}
 	c.closed = true
@@ -170,6 +187,13 @@ func (c *Connection) CloseWith(err error, reason string) {
 	if err != nil {
 		c.logger.Severe("failed to close connection ", c, ": ", err)
 	}
+
+	handlers := c.closeHandlers
+	c.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler()
+	}
 }
 
 func (c *Connection) String() string {
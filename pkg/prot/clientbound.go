@@ -8,11 +8,26 @@ func init() {
 	RegisterOut(0x00, &PacketOutKeepAlive{})
 	RegisterOut(0x01, &PacketOutLogin{})
 	RegisterOut(0x02, &PacketOutHandShake{})
+	RegisterOut(0x03, &PacketOutChat{})
 	RegisterOut(0x04, &PacketOutTimeUpdate{})
 	RegisterOut(0x06, &PacketOutSpawnPosition{})
+	RegisterOut(0x09, &PacketOutRespawn{})
 	RegisterOut(0x0D, &PacketOutPlayerPositionAndLook{})
+	RegisterOut(0x15, &PacketOutPickupSpawn{})
+	RegisterOut(0x16, &PacketOutCollectItem{})
+	RegisterOut(0x1D, &PacketOutDestroyEntity{})
 	RegisterOut(0x32, &PacketOutPreChunk{})
 	RegisterOut(0x33, &PacketOutMapChunk{})
+	RegisterOut(0x34, &PacketOutMultiBlockChange{})
+	RegisterOut(0x35, &PacketOutBlockChange{})
+	RegisterOut(0x46, &PacketOutNewState{})
+	RegisterOut(0x64, &PacketOutOpenWindow{})
+	RegisterOut(0x65, &PacketOutCloseWindow{})
+	RegisterOut(0x67, &PacketOutSetSlot{})
+	RegisterOut(0x68, &PacketOutWindowItems{})
+	RegisterOut(0x69, &PacketOutUpdateProgressBar{})
+	RegisterOut(0x6A, &PacketOutTransaction{})
+	RegisterOut(0x82, &PacketOutUpdateSign{})
 	RegisterOut(0xFF, &PacketOutKick{})
 }
 
@@ -49,6 +64,16 @@ func (p *PacketOutHandShake) Push(buf *buff.MCWriter) error {
 	return pusher.Err
 }
 
+type PacketOutChat struct {
+	Message string
+}
+
+func (p *PacketOutChat) Push(buf *buff.MCWriter) error {
+	pusher := buff.NewPusher(buf)
+	pusher.Push(func() error { return buf.WriteString16(p.Message) })
+	return pusher.Err
+}
+
 type PacketOutTimeUpdate struct {
 	Time int64
 }
@@ -73,6 +98,16 @@ func (p *PacketOutSpawnPosition) Push(buf *buff.MCWriter) error {
 	return pusher.Err
 }
 
+type PacketOutRespawn struct {
+	Dimension byte
+}
+
+func (p *PacketOutRespawn) Push(buf *buff.MCWriter) error {
+	pusher := buff.NewPusher(buf)
+	pusher.Push(func() error { return buf.WriteByte(p.Dimension) })
+	return pusher.Err
+}
+
 type PacketOutPlayerPositionAndLook struct {
 	X        float64
 	Stance   float64
@@ -95,6 +130,56 @@ func (p *PacketOutPlayerPositionAndLook) Push(buf *buff.MCWriter) error {
 	return pusher.Err
 }
 
+type PacketOutPickupSpawn struct {
+	EntityId int32
+	ItemId   int16
+	Count    byte
+	Damage   int16
+	X        int32
+	Y        int32
+	Z        int32
+	Rotation byte
+	Pitch    byte
+	Roll     byte
+}
+
+func (p *PacketOutPickupSpawn) Push(buf *buff.MCWriter) error {
+	pusher := buff.NewPusher(buf)
+	pusher.Push(func() error { return buf.WriteInt(p.EntityId) })
+	pusher.Push(func() error { return buf.WriteShort(p.ItemId) })
+	pusher.Push(func() error { return buf.WriteByte(p.Count) })
+	pusher.Push(func() error { return buf.WriteShort(p.Damage) })
+	pusher.Push(func() error { return buf.WriteInt(p.X) })
+	pusher.Push(func() error { return buf.WriteInt(p.Y) })
+	pusher.Push(func() error { return buf.WriteInt(p.Z) })
+	pusher.Push(func() error { return buf.WriteByte(p.Rotation) })
+	pusher.Push(func() error { return buf.WriteByte(p.Pitch) })
+	pusher.Push(func() error { return buf.WriteByte(p.Roll) })
+	return pusher.Err
+}
+
+type PacketOutCollectItem struct {
+	CollectedId int32
+	CollectorId int32
+}
+
+func (p *PacketOutCollectItem) Push(buf *buff.MCWriter) error {
+	pusher := buff.NewPusher(buf)
+	pusher.Push(func() error { return buf.WriteInt(p.CollectedId) })
+	pusher.Push(func() error { return buf.WriteInt(p.CollectorId) })
+	return pusher.Err
+}
+
+type PacketOutDestroyEntity struct {
+	EntityId int32
+}
+
+func (p *PacketOutDestroyEntity) Push(buf *buff.MCWriter) error {
+	pusher := buff.NewPusher(buf)
+	pusher.Push(func() error { return buf.WriteInt(p.EntityId) })
+	return pusher.Err
+}
+
 type PacketOutPreChunk struct {
 	X    int32
 	Z    int32
@@ -132,6 +217,181 @@ func (p *PacketOutMapChunk) Push(buf *buff.MCWriter) error {
 	return pusher.Err
 }
 
+type PacketOutMultiBlockChange struct {
+	ChunkX int32
+	ChunkZ int32
+	// Coordinates of the blocks within the chunk, packed as x<<12 | z<<8 | y
+	Coordinates []int16
+	Types       []byte
+	Metadata    []byte
+}
+
+func (p *PacketOutMultiBlockChange) Push(buf *buff.MCWriter) error {
+	pusher := buff.NewPusher(buf)
+	pusher.Push(func() error { return buf.WriteInt(p.ChunkX) })
+	pusher.Push(func() error { return buf.WriteInt(p.ChunkZ) })
+	pusher.Push(func() error { return buf.WriteShort(int16(len(p.Coordinates))) })
+	for _, coordinates := range p.Coordinates {
+		pusher.Push(func() error { return buf.WriteShort(coordinates) })
+	}
+	pusher.Push(func() error { return buf.WriteBytes(p.Types) })
+	pusher.Push(func() error { return buf.WriteBytes(p.Metadata) })
+	return pusher.Err
+}
+
+type PacketOutBlockChange struct {
+	X        int32
+	Y        byte
+	Z        int32
+	Type     byte
+	Metadata byte
+}
+
+func (p *PacketOutBlockChange) Push(buf *buff.MCWriter) error {
+	pusher := buff.NewPusher(buf)
+	pusher.Push(func() error { return buf.WriteInt(p.X) })
+	pusher.Push(func() error { return buf.WriteByte(p.Y) })
+	pusher.Push(func() error { return buf.WriteInt(p.Z) })
+	pusher.Push(func() error { return buf.WriteByte(p.Type) })
+	pusher.Push(func() error { return buf.WriteByte(p.Metadata) })
+	return pusher.Err
+}
+
+// Reasons of the New/Invalid State packet
+const (
+	StateInvalidBed byte = 0
+	StateBeginRain  byte = 1
+	StateEndRain    byte = 2
+)
+
+type PacketOutNewState struct {
+	Reason byte
+}
+
+func (p *PacketOutNewState) Push(buf *buff.MCWriter) error {
+	pusher := buff.NewPusher(buf)
+	pusher.Push(func() error { return buf.WriteByte(p.Reason) })
+	return pusher.Err
+}
+
+// Item is an item stack as it is sent in the window packets, the id of empty slots is -1
+type Item struct {
+	ID     int16
+	Count  byte
+	Damage int16
+}
+
+func (i Item) push(pusher *buff.Pusher, buf *buff.MCWriter) {
+	pusher.Push(func() error { return buf.WriteShort(i.ID) })
+	if i.ID < 0 {
+		return
+	}
+	pusher.Push(func() error { return buf.WriteByte(i.Count) })
+	pusher.Push(func() error { return buf.WriteShort(i.Damage) })
+}
+
+type PacketOutOpenWindow struct {
+	WindowId byte
+	Type     byte
+	Title    string
+	Slots    byte
+}
+
+func (p *PacketOutOpenWindow) Push(buf *buff.MCWriter) error {
+	pusher := buff.NewPusher(buf)
+	pusher.Push(func() error { return buf.WriteByte(p.WindowId) })
+	pusher.Push(func() error { return buf.WriteByte(p.Type) })
+	pusher.Push(func() error { return buf.WriteString8(p.Title) })
+	pusher.Push(func() error { return buf.WriteByte(p.Slots) })
+	return pusher.Err
+}
+
+type PacketOutCloseWindow struct {
+	WindowId byte
+}
+
+func (p *PacketOutCloseWindow) Push(buf *buff.MCWriter) error {
+	pusher := buff.NewPusher(buf)
+	pusher.Push(func() error { return buf.WriteByte(p.WindowId) })
+	return pusher.Err
+}
+
+type PacketOutSetSlot struct {
+	// WindowId is -1 for the stack on the cursor
+	WindowId int8
+	Slot     int16
+	Item     Item
+}
+
+func (p *PacketOutSetSlot) Push(buf *buff.MCWriter) error {
+	pusher := buff.NewPusher(buf)
+	pusher.Push(func() error { return buf.WriteByte(byte(p.WindowId)) })
+	pusher.Push(func() error { return buf.WriteShort(p.Slot) })
+	p.Item.push(pusher, buf)
+	return pusher.Err
+}
+
+type PacketOutWindowItems struct {
+	WindowId byte
+	Items    []Item
+}
+
+func (p *PacketOutWindowItems) Push(buf *buff.MCWriter) error {
+	pusher := buff.NewPusher(buf)
+	pusher.Push(func() error { return buf.WriteByte(p.WindowId) })
+	pusher.Push(func() error { return buf.WriteShort(int16(len(p.Items))) })
+	for _, item := range p.Items {
+		item.push(pusher, buf)
+	}
+	return pusher.Err
+}
+
+type PacketOutUpdateProgressBar struct {
+	WindowId byte
+	Bar      int16
+	Value    int16
+}
+
+func (p *PacketOutUpdateProgressBar) Push(buf *buff.MCWriter) error {
+	pusher := buff.NewPusher(buf)
+	pusher.Push(func() error { return buf.WriteByte(p.WindowId) })
+	pusher.Push(func() error { return buf.WriteShort(p.Bar) })
+	pusher.Push(func() error { return buf.WriteShort(p.Value) })
+	return pusher.Err
+}
+
+type PacketOutTransaction struct {
+	WindowId byte
+	Action   int16
+	Accepted bool
+}
+
+func (p *PacketOutTransaction) Push(buf *buff.MCWriter) error {
+	pusher := buff.NewPusher(buf)
+	pusher.Push(func() error { return buf.WriteByte(p.WindowId) })
+	pusher.Push(func() error { return buf.WriteShort(p.Action) })
+	pusher.Push(func() error { return buf.WriteBool(p.Accepted) })
+	return pusher.Err
+}
+
+type PacketOutUpdateSign struct {
+	X     int32
+	Y     int16
+	Z     int32
+	Lines [4]string
+}
+
+func (p *PacketOutUpdateSign) Push(buf *buff.MCWriter) error {
+	pusher := buff.NewPusher(buf)
+	pusher.Push(func() error { return buf.WriteInt(p.X) })
+	pusher.Push(func() error { return buf.WriteShort(p.Y) })
+	pusher.Push(func() error { return buf.WriteInt(p.Z) })
+	for _, line := range p.Lines {
+		pusher.Push(func() error { return buf.WriteString16(line) })
+	}
+	return pusher.Err
+}
+
 type PacketOutKick struct {
 	Reason string
 }
@@ -4,8 +4,17 @@ type PacketHandler interface {
 	OnKeepAlive(packet *PacketInKeepAlive) error
 	OnLogin(packet *PacketInLogin) error
 	OnHandShake(packet *PacketInHandShake) error
+	OnChat(packet *PacketInChat) error
+	OnRespawn(packet *PacketInRespawn) error
 	OnPlayerGround(packet *PacketInPlayerGround) error
 	OnPlayerPosition(packet *PacketInPlayerPosition) error
 	OnPlayerLook(packet *PacketInPlayerLook) error
 	OnPlayerPositionAndLook(packet *PacketInPlayerPositionAndLook) error
+	OnPlayerDigging(packet *PacketInPlayerDigging) error
+	OnPlayerBlockPlacement(packet *PacketInPlayerBlockPlacement) error
+	OnHoldingChange(packet *PacketInHoldingChange) error
+	OnCloseWindow(packet *PacketInCloseWindow) error
+	OnWindowClick(packet *PacketInWindowClick) error
+	OnTransaction(packet *PacketInTransaction) error
+	OnServerListPing(packet *PacketInServerListPing) error
 }
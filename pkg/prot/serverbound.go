@@ -8,10 +8,19 @@ func init() {
 	RegisterIn(0x00, func() PacketIn { return &PacketInKeepAlive{} })
 	RegisterIn(0x01, func() PacketIn { return &PacketInLogin{} })
 	RegisterIn(0x02, func() PacketIn { return &PacketInHandShake{} })
+	RegisterIn(0x03, func() PacketIn { return &PacketInChat{} })
+	RegisterIn(0x09, func() PacketIn { return &PacketInRespawn{} })
 	RegisterIn(0x0A, func() PacketIn { return &PacketInPlayerGround{} })
 	RegisterIn(0x0B, func() PacketIn { return &PacketInPlayerPosition{} })
 	RegisterIn(0x0C, func() PacketIn { return &PacketInPlayerLook{} })
 	RegisterIn(0x0D, func() PacketIn { return &PacketInPlayerPositionAndLook{} })
+	RegisterIn(0x0E, func() PacketIn { return &PacketInPlayerDigging{} })
+	RegisterIn(0x0F, func() PacketIn { return &PacketInPlayerBlockPlacement{} })
+	RegisterIn(0x10, func() PacketIn { return &PacketInHoldingChange{} })
+	RegisterIn(0x65, func() PacketIn { return &PacketInCloseWindow{} })
+	RegisterIn(0x66, func() PacketIn { return &PacketInWindowClick{} })
+	RegisterIn(0x6A, func() PacketIn { return &PacketInTransaction{} })
+	RegisterIn(0xFE, func() PacketIn { return &PacketInServerListPing{} })
 }
 
 type PacketInKeepAlive struct {
@@ -59,6 +68,34 @@ func (p *PacketInHandShake) Handle(handler PacketHandler) error {
 	return handler.OnHandShake(p)
 }
 
+type PacketInChat struct {
+	Message string
+}
+
+func (p *PacketInChat) Pull(buf *buff.MCReader) error {
+	puller := buff.NewPuller(buf)
+	puller.Pull(func() { p.Message, puller.Err = buf.ReadString16() })
+	return puller.Err
+}
+
+func (p *PacketInChat) Handle(handler PacketHandler) error {
+	return handler.OnChat(p)
+}
+
+type PacketInRespawn struct {
+	Dimension byte
+}
+
+func (p *PacketInRespawn) Pull(buf *buff.MCReader) error {
+	puller := buff.NewPuller(buf)
+	puller.Pull(func() { p.Dimension, puller.Err = buf.ReadByte() })
+	return puller.Err
+}
+
+func (p *PacketInRespawn) Handle(handler PacketHandler) error {
+	return handler.OnRespawn(p)
+}
+
 type PacketInPlayerGround struct {
 	OnGround bool
 }
@@ -138,3 +175,146 @@ func (p *PacketInPlayerPositionAndLook) Pull(buf *buff.MCReader) error {
 func (p *PacketInPlayerPositionAndLook) Handle(handler PacketHandler) error {
 	return handler.OnPlayerPositionAndLook(p)
 }
+
+type PacketInPlayerDigging struct {
+	Status byte
+	X      int32
+	Y      byte
+	Z      int32
+	Face   byte
+}
+
+func (p *PacketInPlayerDigging) Pull(buf *buff.MCReader) error {
+	puller := buff.NewPuller(buf)
+	puller.Pull(func() { p.Status, puller.Err = buf.ReadByte() })
+	puller.Pull(func() { p.X, puller.Err = buf.ReadInt() })
+	puller.Pull(func() { p.Y, puller.Err = buf.ReadByte() })
+	puller.Pull(func() { p.Z, puller.Err = buf.ReadInt() })
+	puller.Pull(func() { p.Face, puller.Err = buf.ReadByte() })
+	return puller.Err
+}
+
+func (p *PacketInPlayerDigging) Handle(handler PacketHandler) error {
+	return handler.OnPlayerDigging(p)
+}
+
+type PacketInPlayerBlockPlacement struct {
+	X      int32
+	Y      byte
+	Z      int32
+	Face   int8
+	ItemID int16
+	Count  byte
+	Damage int16
+}
+
+func (p *PacketInPlayerBlockPlacement) Pull(buf *buff.MCReader) error {
+	puller := buff.NewPuller(buf)
+	puller.Pull(func() { p.X, puller.Err = buf.ReadInt() })
+	puller.Pull(func() { p.Y, puller.Err = buf.ReadByte() })
+	puller.Pull(func() { p.Z, puller.Err = buf.ReadInt() })
+	puller.Pull(func() {
+		var face byte
+		face, puller.Err = buf.ReadByte()
+		p.Face = int8(face)
+	})
+	puller.Pull(func() { p.ItemID, puller.Err = buf.ReadShort() })
+	if puller.Err != nil || p.ItemID < 0 {
+		return puller.Err
+	}
+	puller.Pull(func() { p.Count, puller.Err = buf.ReadByte() })
+	puller.Pull(func() { p.Damage, puller.Err = buf.ReadShort() })
+	return puller.Err
+}
+
+func (p *PacketInPlayerBlockPlacement) Handle(handler PacketHandler) error {
+	return handler.OnPlayerBlockPlacement(p)
+}
+
+type PacketInHoldingChange struct {
+	Slot int16
+}
+
+func (p *PacketInHoldingChange) Pull(buf *buff.MCReader) error {
+	puller := buff.NewPuller(buf)
+	puller.Pull(func() { p.Slot, puller.Err = buf.ReadShort() })
+	return puller.Err
+}
+
+func (p *PacketInHoldingChange) Handle(handler PacketHandler) error {
+	return handler.OnHoldingChange(p)
+}
+
+type PacketInCloseWindow struct {
+	WindowId byte
+}
+
+func (p *PacketInCloseWindow) Pull(buf *buff.MCReader) error {
+	puller := buff.NewPuller(buf)
+	puller.Pull(func() { p.WindowId, puller.Err = buf.ReadByte() })
+	return puller.Err
+}
+
+func (p *PacketInCloseWindow) Handle(handler PacketHandler) error {
+	return handler.OnCloseWindow(p)
+}
+
+type PacketInWindowClick struct {
+	WindowId byte
+	Slot     int16
+	Right    bool
+	Action   int16
+	Shift    bool
+	ItemID   int16
+	Count    byte
+	Damage   int16
+}
+
+func (p *PacketInWindowClick) Pull(buf *buff.MCReader) error {
+	puller := buff.NewPuller(buf)
+	puller.Pull(func() { p.WindowId, puller.Err = buf.ReadByte() })
+	puller.Pull(func() { p.Slot, puller.Err = buf.ReadShort() })
+	puller.Pull(func() { p.Right, puller.Err = buf.ReadBool() })
+	puller.Pull(func() { p.Action, puller.Err = buf.ReadShort() })
+	puller.Pull(func() { p.Shift, puller.Err = buf.ReadBool() })
+	puller.Pull(func() { p.ItemID, puller.Err = buf.ReadShort() })
+	if puller.Err != nil || p.ItemID < 0 {
+		return puller.Err
+	}
+	puller.Pull(func() { p.Count, puller.Err = buf.ReadByte() })
+	puller.Pull(func() { p.Damage, puller.Err = buf.ReadShort() })
+	return puller.Err
+}
+
+func (p *PacketInWindowClick) Handle(handler PacketHandler) error {
+	return handler.OnWindowClick(p)
+}
+
+type PacketInTransaction struct {
+	WindowId byte
+	Action   int16
+	Accepted bool
+}
+
+func (p *PacketInTransaction) Pull(buf *buff.MCReader) error {
+	puller := buff.NewPuller(buf)
+	puller.Pull(func() { p.WindowId, puller.Err = buf.ReadByte() })
+	puller.Pull(func() { p.Action, puller.Err = buf.ReadShort() })
+	puller.Pull(func() { p.Accepted, puller.Err = buf.ReadBool() })
+	return puller.Err
+}
+
+func (p *PacketInTransaction) Handle(handler PacketHandler) error {
+	return handler.OnTransaction(p)
+}
+
+type PacketInServerListPing struct {
+}
+
+func (p *PacketInServerListPing) Pull(*buff.MCReader) error {
+	return nil
+}
+
+func (p *PacketInServerListPing) Handle(handler PacketHandler) error {
+	return handler.OnServerListPing(p)
+}
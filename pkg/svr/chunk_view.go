@@ -0,0 +1,126 @@
+package svr
+
+import (
+	"sort"
+
+	"github.com/Pesekjak/173go/pkg/world"
+)
+
+const (
+	// loginChunks is the number of chunks around the player sent right away when joining
+	loginChunks = 9
+	// chunksPerTick is the maximum number of chunks sent to a single client per tick
+	chunksPerTick = 10
+)
+
+// chunkView tracks chunks loaded by a client
+type chunkView struct {
+	// center of the view, the chunk the client is standing in
+	center world.ChunkPos
+	// chunks that have been sent to the client
+	loaded map[world.ChunkPos]struct{}
+	// chunks in the view that still need to be sent, closest first
+	pending []world.ChunkPos
+}
+
+func newChunkView() *chunkView {
+	return &chunkView{loaded: make(map[world.ChunkPos]struct{})}
+}
+
+// updateChunks unloads chunks that left the client's view distance and queues chunks that entered it.
+// If force is false and the client has not crossed a chunk border since the last update, nothing happens.
+func (c *Client) updateChunks(force bool) error {
+	center := c.location.ToChunkPos()
+	if !force && center == c.view.center {
+		return nil
+	}
+	c.view.center = center
+
+	radius := int32(c.server.Config.ViewDistance)
+	inView := func(pos world.ChunkPos) bool {
+		return pos.X >= center.X-radius && pos.X <= center.X+radius &&
+			pos.Z >= center.Z-radius && pos.Z <= center.Z+radius
+	}
+
+	for pos := range c.view.loaded {
+		if inView(pos) {
+			continue
+		}
+		if err := c.world.HideChunk(c, pos); err != nil {
+			return err
+		}
+		delete(c.view.loaded, pos)
+	}
+
+	c.view.pending = c.view.pending[:0]
+	for x := center.X - radius; x <= center.X+radius; x++ {
+		for z := center.Z - radius; z <= center.Z+radius; z++ {
+			pos := world.NewChunkPos(x, z)
+			if _, ok := c.view.loaded[pos]; !ok {
+				c.view.pending = append(c.view.pending, pos)
+			}
+		}
+	}
+
+	// closest chunks are sent first
+	sort.Slice(c.view.pending, func(i, j int) bool {
+		return c.view.pending[i].DistanceSquared(center) < c.view.pending[j].DistanceSquared(center)
+	})
+
+	return c.connection.Flush()
+}
+
+// sendPendingChunks sends up to limit queued chunks to the client
+func (c *Client) sendPendingChunks(limit int) error {
+	if len(c.view.pending) == 0 {
+		return nil
+	}
+
+	count := min(limit, len(c.view.pending))
+	for _, pos := range c.view.pending[:count] {
+		if err := c.world.SendChunk(c, pos); err != nil {
+			return err
+		}
+		c.view.loaded[pos] = struct{}{}
+	}
+	c.view.pending = c.view.pending[count:]
+
+	return c.connection.Flush()
+}
+
+// sendPendingChunks streams queued chunks to all clients, registered as a tick handler
+func (s *Server) sendPendingChunks(int64) {
+	for _, client := range s.Clients() {
+		if err := client.sendPendingChunks(chunksPerTick); err != nil {
+			client.Disconnect(err)
+		}
+	}
+}
+
+// resendChunks sends the chunks between given corners again to all clients in the world that have them loaded
+func (s *Server) resendChunks(w *world.World, from, to world.ChunkPos) {
+	for _, client := range s.Clients() {
+		if client.world != w {
+			continue
+		}
+		if err := client.resendChunks(from, to); err != nil {
+			client.Disconnect(err)
+		}
+	}
+}
+
+// resendChunks sends the loaded chunks between given corners to the client again
+func (c *Client) resendChunks(from, to world.ChunkPos) error {
+	for x := min(from.X, to.X); x <= max(from.X, to.X); x++ {
+		for z := min(from.Z, to.Z); z <= max(from.Z, to.Z); z++ {
+			pos := world.NewChunkPos(x, z)
+			if _, ok := c.view.loaded[pos]; !ok {
+				continue
+			}
+			if err := c.world.SendChunk(c, pos); err != nil {
+				return err
+			}
+		}
+	}
+	return c.connection.Flush()
+}
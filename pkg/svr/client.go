@@ -2,15 +2,22 @@ package svr
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/Pesekjak/173go/pkg/base"
+	"github.com/Pesekjak/173go/pkg/chat"
 	"github.com/Pesekjak/173go/pkg/log"
 	"github.com/Pesekjak/173go/pkg/net"
 	"github.com/Pesekjak/173go/pkg/prot"
 	"github.com/Pesekjak/173go/pkg/world"
 	"github.com/Pesekjak/173go/pkg/world/entity_data"
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/recipe"
 )
 
+// Client is a player connected to the server.
+// Packet handlers are called on the connection goroutine, everything touching the game state
+// is scheduled to run on the server goroutine.
 type Client struct {
 	server     *Server
 	connection *net.Connection
@@ -21,15 +28,32 @@ type Client struct {
 	username string
 	location world.Location
 	world    *world.World
+	view     *chunkView
+	portal   portalState
+	onGround bool
+	digging  diggingState
+
+	inventory *inventory.PlayerInventory
+	// crafting is the crafting grid of the player's own window
+	crafting     *recipe.Grid
+	windowState  windowState
+	lastWindowId byte
 }
 
 func NewClient(server *Server, connection *net.Connection) *Client {
-	return &Client{
+	client := &Client{
 		server:     server,
 		connection: connection,
 
 		logger: server.Console.ChildLogger("client"),
+
+		view:      newChunkView(),
+		inventory: inventory.NewPlayerInventory(),
 	}
+	client.crafting = client.newCrafting(client.inventory.Window(), client.inventory.Grid, client.inventory.Result, 2)
+	client.windowState = client.playerWindow()
+	connection.OnClose(client.onClose)
+	return client
 }
 
 func (c *Client) OnLogin(packet *prot.PacketInLogin) error {
@@ -39,25 +63,39 @@ func (c *Client) OnLogin(packet *prot.PacketInLogin) error {
 	if packet.Protocol != 14 {
 		return fmt.Errorf("unsupported protocol version: %v", packet.Protocol)
 	}
+	c.schedule(c.login)
+	return nil
+}
+
+// login spawns the client in the default world
+func (c *Client) login() error {
+	if len(c.server.Clients()) >= c.server.Config.MaxPlayers {
+		c.Kick("The server is full!")
+		return nil
+	}
+
+	defaultWorld := c.server.DefaultWorld()
 
-	defaultWorld := c.server.defaultWorld
+	spawnPoint := defaultWorld.SpawnPoint
+	spawnLocation, err := defaultWorld.SpawnLocation()
+	if err != nil {
+		return err
+	}
 
 	c.id = base.NextEntityId()
-	c.location = world.NewLocation(0, 0, 0, 0, 0)
+	c.location = spawnLocation
 	c.world = defaultWorld
 
-	err := c.connection.WritePacket(&prot.PacketOutLogin{
+	err = c.connection.WritePacket(&prot.PacketOutLogin{
 		EntityId:   c.id,
 		ServerName: "", // empty on Notchian
-		MapSeed:    0,  // unused by client
+		MapSeed:    defaultWorld.Seed(),
 		Dimension:  byte(defaultWorld.Dimension()),
 	}, false)
 	if err != nil {
 		return err
 	}
 
-	spawnPoint := defaultWorld.SpawnPoint
-
 	err = c.connection.WritePacket(&prot.PacketOutSpawnPosition{
 		X: spawnPoint.X,
 		Y: spawnPoint.Y,
@@ -74,21 +112,163 @@ func (c *Client) OnLogin(packet *prot.PacketInLogin) error {
 		return err
 	}
 
+	if defaultWorld.Raining() {
+		err = c.connection.WritePacket(&prot.PacketOutNewState{Reason: prot.StateBeginRain}, false)
+		if err != nil {
+			return err
+		}
+	}
+
 	if err = defaultWorld.SpawnPlayer(c); err != nil {
 		return err
 	}
 
+	if err = c.updateChunks(true); err != nil {
+		return err
+	}
+	// chunks around the player are sent right away, the rest is streamed during the next ticks
+	if err = c.sendPendingChunks(loginChunks); err != nil {
+		return err
+	}
+
+	err = c.connection.WritePacket(&prot.PacketOutPlayerPositionAndLook{
+		X:        spawnLocation.X,
+		Stance:   spawnLocation.Y + 1.62,
+		Y:        spawnLocation.Y,
+		Z:        spawnLocation.Z,
+		Yaw:      spawnLocation.Yaw,
+		Pitch:    spawnLocation.Pitch,
+		OnGround: false,
+	}, false)
+	if err != nil {
+		return err
+	}
+	if err = c.resendWindow(); err != nil {
+		return err
+	}
+
+	c.server.addClient(c)
+	c.server.Broadcast(chat.Yellow, c.username, " joined the game.")
+	return nil
+}
+
+// OnRespawn respawns the player at the spawn of the default world.
+func (c *Client) OnRespawn(*prot.PacketInRespawn) error {
+	c.schedule(func() error {
+		if c.world == nil {
+			return fmt.Errorf("client %v respawned before logging in", c)
+		}
+		defaultWorld := c.server.DefaultWorld()
+		spawnLocation, err := defaultWorld.SpawnLocation()
+		if err != nil {
+			return err
+		}
+		return c.Respawn(defaultWorld, spawnLocation)
+	})
+	return nil
+}
+
+// Respawn moves the player to the location in given world, which may be the world the player is in.
+// The client forgets all of its chunks when the dimension changes, otherwise they are unloaded by the server,
+// so the view is sent again from scratch. It must be called on the server goroutine.
+func (c *Client) Respawn(w *world.World, location world.Location) error {
+	if err := c.closeContainer(); err != nil {
+		return err
+	}
+	previous := c.world
+	previous.RemoveEntity(c)
+	if w.Dimension() == previous.Dimension() {
+		for pos := range c.view.loaded {
+			if err := previous.HideChunk(c, pos); err != nil {
+				return err
+			}
+		}
+	}
+	c.view = newChunkView()
+	c.world = w
+	c.location = location
+	c.digging = diggingState{}
+
+	err := c.connection.WritePacket(&prot.PacketOutRespawn{Dimension: byte(w.Dimension())}, false)
+	if err != nil {
+		return err
+	}
+
+	spawnPoint := w.SpawnPoint
+	err = c.connection.WritePacket(&prot.PacketOutSpawnPosition{
+		X: spawnPoint.X,
+		Y: spawnPoint.Y,
+		Z: spawnPoint.Z,
+	}, false)
+	if err != nil {
+		return err
+	}
+
+	err = c.connection.WritePacket(&prot.PacketOutTimeUpdate{Time: w.Time()}, false)
+	if err != nil {
+		return err
+	}
+
+	if w.Raining() {
+		err = c.connection.WritePacket(&prot.PacketOutNewState{Reason: prot.StateBeginRain}, false)
+	} else if previous.Raining() {
+		err = c.connection.WritePacket(&prot.PacketOutNewState{Reason: prot.StateEndRain}, false)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = w.SpawnPlayer(c); err != nil {
+		return err
+	}
+
+	if err = c.updateChunks(true); err != nil {
+		return err
+	}
+	if err = c.sendPendingChunks(loginChunks); err != nil {
+		return err
+	}
+
 	return c.connection.WritePacket(&prot.PacketOutPlayerPositionAndLook{
-		X:        float64(spawnPoint.X),
-		Stance:   67.240000009536743,
-		Y:        float64(spawnPoint.Y),
-		Z:        float64(spawnPoint.Z),
-		Yaw:      0,
-		Pitch:    0,
+		X:        location.X,
+		Stance:   location.Y + 1.62,
+		Y:        location.Y,
+		Z:        location.Z,
+		Yaw:      location.Yaw,
+		Pitch:    location.Pitch,
 		OnGround: false,
 	}, true)
 }
 
+func (c *Client) onClose() {
+	// the removal has to run even though the connection is closed, so the client's schedule is not used
+	c.server.Schedule(func() {
+		if c.world != nil {
+			// the items on the cursor and in the crafting grid are thrown out as when the window is closed
+			if err := c.closeWindow(); err != nil {
+				c.logger.Warn("closing the window of ", c, " failed: ", err)
+			}
+			c.world.RemoveEntity(c)
+		}
+		if c.server.removeClient(c) {
+			c.server.Broadcast(chat.Yellow, c.username, " left the game.")
+		}
+	})
+}
+
+// schedule runs the task on the server goroutine, if the task fails the client is disconnected.
+// Tasks scheduled after the connection was closed are skipped.
+func (c *Client) schedule(task func() error) {
+	c.server.Schedule(func() {
+		if c.connection.IsClosed() {
+			return
+		}
+		if err := task(); err != nil {
+			c.Disconnect(err)
+		}
+	})
+}
+
 func (c *Client) OnKeepAlive(*prot.PacketInKeepAlive) error {
 	return c.connection.WritePacket(&prot.PacketOutKeepAlive{}, true)
 }
@@ -99,19 +279,80 @@ func (c *Client) OnHandShake(packet *prot.PacketInHandShake) error {
 	return c.connection.WritePacket(&prot.PacketOutHandShake{Hash: "-"}, true)
 }
 
-func (c *Client) OnPlayerGround(*prot.PacketInPlayerGround) error {
+func (c *Client) OnChat(packet *prot.PacketInChat) error {
+	message := strings.TrimSpace(packet.Message)
+	if len([]rune(message)) > chat.MaxInputLength {
+		return fmt.Errorf("chat message too long")
+	}
+	if strings.ContainsRune(message, chat.ColorSymbol) {
+		return fmt.Errorf("illegal characters in chat")
+	}
+	if message == "" {
+		return nil
+	}
+
+	c.schedule(func() error {
+		if c.world == nil {
+			return fmt.Errorf("client %v sent a chat message before logging in", c)
+		}
+
+		if strings.HasPrefix(message, "/") {
+			c.server.CommandManager.ExecuteCommand(c, message[1:])
+			return nil
+		}
+
+		c.server.Broadcast("<", c.username, "> ", message)
+		return nil
+	})
+	return nil
+}
+
+func (c *Client) OnPlayerGround(packet *prot.PacketInPlayerGround) error {
+	c.schedule(func() error {
+		c.onGround = packet.OnGround
+		return nil
+	})
 	return nil
 }
 
-func (c *Client) OnPlayerPosition(*prot.PacketInPlayerPosition) error {
+func (c *Client) OnPlayerPosition(packet *prot.PacketInPlayerPosition) error {
+	c.schedule(func() error {
+		if c.world == nil {
+			return nil // position packets are sent even before login
+		}
+		c.location = world.NewLocation(packet.X, packet.Y, packet.Z, c.location.Yaw, c.location.Pitch)
+		c.onGround = packet.OnGround
+		return c.updateChunks(false)
+	})
 	return nil
 }
 
-func (c *Client) OnPlayerLook(*prot.PacketInPlayerLook) error {
+func (c *Client) OnPlayerLook(packet *prot.PacketInPlayerLook) error {
+	c.schedule(func() error {
+		c.location.Yaw = packet.Yaw
+		c.location.Pitch = packet.Pitch
+		return nil
+	})
 	return nil
 }
 
-func (c *Client) OnPlayerPositionAndLook(*prot.PacketInPlayerPositionAndLook) error {
+func (c *Client) OnPlayerPositionAndLook(packet *prot.PacketInPlayerPositionAndLook) error {
+	c.schedule(func() error {
+		if c.world == nil {
+			return nil
+		}
+		c.location = world.NewLocation(packet.X, packet.Y, packet.Z, packet.Yaw, packet.Pitch)
+		c.onGround = packet.OnGround
+		return c.updateChunks(false)
+	})
+	return nil
+}
+
+func (c *Client) OnServerListPing(*prot.PacketInServerListPing) error {
+	// the client splits the response at the color symbol, so it can not be part of the MOTD
+	motd := strings.ReplaceAll(chat.StripColorCodes(c.server.Config.MOTD), string(chat.ColorSymbol), "")
+	c.Kick(fmt.Sprintf("%s%c%d%c%d", motd, chat.ColorSymbol, len(c.server.Clients()), chat.ColorSymbol,
+		c.server.Config.MaxPlayers))
 	return nil
 }
 
@@ -147,6 +388,25 @@ func (c *Client) Username() string {
 	return c.username
 }
 
+func (c *Client) Name() string {
+	return c.username
+}
+
+func (c *Client) SendMessage(message ...interface{}) {
+	for _, line := range chat.SplitMessage(base.ConvertToString(message...)) {
+		if err := c.connection.WritePacket(&prot.PacketOutChat{Message: line}, true); err != nil {
+			if !c.connection.IsClosed() {
+				c.logger.Severe("failed to send message to ", c, ": ", err)
+			}
+			return
+		}
+	}
+}
+
+func (c *Client) HasPermission(string) bool {
+	return c.server.IsOperator(c.username)
+}
+
 func (c *Client) IsOnline() bool {
 	return c.connection.IsActive()
 }
@@ -1,6 +1,14 @@
 package svr
 
-import "github.com/Pesekjak/173go/pkg/cmd"
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Pesekjak/173go/pkg/chat"
+	"github.com/Pesekjak/173go/pkg/cmd"
+	"github.com/Pesekjak/173go/pkg/world"
+)
 
 func registerCommands(server *Server) {
 	server.CommandManager.RegisterCommand(cmd.Command{
@@ -15,4 +23,200 @@ func registerCommands(server *Server) {
 			return true
 		},
 	})
+	server.CommandManager.RegisterCommand(cmd.Command{
+		Label:      "save-all",
+		Usage:      "/save-all",
+		Permission: "server.save",
+		Handler: func(sender cmd.CommandSender, args []string) bool {
+			if len(args) != 0 {
+				return false
+			}
+			if err := server.Save(); err != nil {
+				sender.SendMessage(chat.Red, "Failed to save the world: ", err)
+				return true
+			}
+			sender.SendMessage(chat.Gold, "The world has been saved")
+			return true
+		},
+	})
+	server.CommandManager.RegisterCommand(cmd.Command{
+		Label:      "tps",
+		Usage:      "/tps",
+		Permission: "server.tps",
+		Handler: func(sender cmd.CommandSender, args []string) bool {
+			if len(args) != 0 {
+				return false
+			}
+			average := server.AverageTickDuration()
+			tps := float64(TicksPerSecond)
+			if average > TickInterval {
+				tps = float64(time.Second) / float64(average)
+			}
+			sender.SendMessage(chat.Gold, fmt.Sprintf("TPS: %.1f, average tick: %v, last tick: %v",
+				tps, average.Round(time.Microsecond), server.TickDuration().Round(time.Microsecond)))
+			return true
+		},
+	})
+	server.CommandManager.RegisterCommand(cmd.Command{
+		Label:      "world",
+		Usage:      "/world list | create <name> [dimension] [generator] [seed] | load <name> | unload <name> | tp <name> [player]",
+		Permission: "server.world",
+		Handler: func(sender cmd.CommandSender, args []string) bool {
+			if len(args) == 0 {
+				return false
+			}
+			switch args[0] {
+			case "list":
+				return len(args) == 1 && listWorlds(server, sender)
+			case "create":
+				return len(args) >= 2 && len(args) <= 5 && createWorld(server, sender, args[1:])
+			case "load":
+				return len(args) == 2 && loadWorld(server, sender, args[1])
+			case "unload":
+				return len(args) == 2 && unloadWorld(server, sender, args[1])
+			case "tp":
+				return len(args) >= 2 && len(args) <= 3 && teleportToWorld(server, sender, args[1:])
+			default:
+				return false
+			}
+		},
+	})
+	server.CommandManager.RegisterCommand(cmd.Command{
+		Label:      "relight",
+		Usage:      "/relight <radius> [world chunkX chunkZ]",
+		Permission: "server.relight",
+		Handler: func(sender cmd.CommandSender, args []string) bool {
+			if len(args) != 1 && len(args) != 4 {
+				return false
+			}
+			return relight(server, sender, args)
+		},
+	})
+}
+
+func listWorlds(server *Server, sender cmd.CommandSender) bool {
+	for _, w := range server.Worlds() {
+		players := len(w.Players())
+		sender.SendMessage(chat.Gold, w.Name(), chat.White, " (", w.Dimension(), ", ", players, " players)")
+	}
+	return true
+}
+
+func createWorld(server *Server, sender cmd.CommandSender, args []string) bool {
+	config := WorldConfig{Name: args[0], Dimension: "overworld", Generator: "default"}
+	if len(args) > 1 {
+		config.Dimension = args[1]
+		if config.Dimension == "nether" {
+			config.Generator = "nether"
+		}
+	}
+	if len(args) > 2 {
+		config.Generator = args[2]
+	}
+	if len(args) > 3 {
+		config.Seed = args[3]
+	}
+	w, err := server.CreateWorld(config)
+	if err != nil {
+		sender.SendMessage(chat.Red, "Failed to create the world: ", err)
+		return true
+	}
+	sender.SendMessage(chat.Gold, "Created world ", w.Name(), " with seed ", w.Seed())
+	return true
+}
+
+func loadWorld(server *Server, sender cmd.CommandSender, name string) bool {
+	if exists, err := world.Exists(name); err != nil || !exists {
+		sender.SendMessage(chat.Red, "There is no world called ", name)
+		return true
+	}
+	if _, err := server.LoadWorld(server.Config.WorldConfig(name)); err != nil {
+		sender.SendMessage(chat.Red, "Failed to load the world: ", err)
+		return true
+	}
+	sender.SendMessage(chat.Gold, "Loaded world ", name)
+	return true
+}
+
+func unloadWorld(server *Server, sender cmd.CommandSender, name string) bool {
+	if err := server.UnloadWorld(name); err != nil {
+		sender.SendMessage(chat.Red, "Failed to unload the world: ", err)
+		return true
+	}
+	sender.SendMessage(chat.Gold, "Unloaded world ", name)
+	return true
+}
+
+func teleportToWorld(server *Server, sender cmd.CommandSender, args []string) bool {
+	w, ok := server.World(args[0])
+	if !ok {
+		sender.SendMessage(chat.Red, "World ", args[0], " is not loaded")
+		return true
+	}
+
+	client, ok := sender.(*Client)
+	if len(args) > 1 {
+		client, ok = server.Client(args[1])
+		if !ok {
+			sender.SendMessage(chat.Red, "Player ", args[1], " is not online")
+			return true
+		}
+	} else if !ok {
+		return false // the console has to name the player
+	}
+
+	spawn, err := w.SpawnLocation()
+	if err != nil {
+		sender.SendMessage(chat.Red, "Failed to find the spawn of ", w.Name(), ": ", err)
+		return true
+	}
+	if err = client.Respawn(w, spawn); err != nil {
+		client.Disconnect(err)
+		sender.SendMessage(chat.Red, "Failed to move ", client.Name(), " to ", w.Name(), ": ", err)
+		return true
+	}
+	sender.SendMessage(chat.Gold, "Moved ", client.Name(), " to ", w.Name())
+	return true
+}
+
+// maxRelightRadius limits the radius of chunks relit at once
+const maxRelightRadius = 16
+
+func relight(server *Server, sender cmd.CommandSender, args []string) bool {
+	radius, err := strconv.Atoi(args[0])
+	if err != nil || radius < 0 || radius > maxRelightRadius {
+		sender.SendMessage(chat.Red, "The radius must be a number of chunks between 0 and ", maxRelightRadius)
+		return true
+	}
+
+	var w *world.World
+	var center world.ChunkPos
+	if len(args) == 4 {
+		var ok bool
+		if w, ok = server.World(args[1]); !ok {
+			sender.SendMessage(chat.Red, "World ", args[1], " is not loaded")
+			return true
+		}
+		x, errX := strconv.ParseInt(args[2], 10, 32)
+		z, errZ := strconv.ParseInt(args[3], 10, 32)
+		if errX != nil || errZ != nil {
+			return false
+		}
+		center = world.NewChunkPos(int32(x), int32(z))
+	} else if client, ok := sender.(*Client); ok {
+		w = client.world
+		center = client.location.ToChunkPos()
+	} else {
+		return false // the console has to name the world and the chunk
+	}
+
+	r := int32(radius)
+	from, to := center.Add(world.NewChunkPos(-r, -r)), center.Add(world.NewChunkPos(r, r))
+	if err = w.Relight(from, to); err != nil {
+		sender.SendMessage(chat.Red, "Failed to relight the chunks: ", err)
+		return true
+	}
+	server.resendChunks(w, from, to)
+	sender.SendMessage(chat.Gold, "Relit ", (2*radius+1)*(2*radius+1), " chunks of ", w.Name())
+	return true
 }
@@ -3,11 +3,69 @@ package svr
 type Config struct {
 	Address string `json:"address"`
 	Port    int    `json:"port"`
+
+	// MOTD is the message of the day displayed in the server list
+	MOTD string `json:"motd"`
+	// MaxPlayers is the maximum number of players that can be online at once
+	MaxPlayers int `json:"max_players"`
+	// ViewDistance is the radius of chunks around a player that are sent to the client
+	ViewDistance int `json:"view_distance"`
+
+	// LevelName is the name of the world players join the game in
+	LevelName string `json:"level_name"`
+	// Worlds are the worlds loaded when the server starts, the default world is loaded
+	// with the default settings if it is not listed
+	Worlds []WorldConfig `json:"worlds"`
+
+	// Operators are usernames of players with all permissions
+	Operators []string `json:"operators"`
+
+	// RecipeFile is a data file with the recipes added to the Notchian ones, it is loaded if it exists
+	RecipeFile string `json:"recipe_file"`
 }
 
 func NewDefaultConfig() Config {
 	return Config{
 		Address: "localhost",
 		Port:    1000,
+
+		MOTD:         "A 173go server",
+		MaxPlayers:   20,
+		ViewDistance: 10,
+
+		LevelName: "world",
+		Worlds: []WorldConfig{
+			{Name: "world", Dimension: "overworld", Generator: "default", Portal: "world_nether"},
+			{Name: "world_nether", Dimension: "nether", Generator: "nether", Portal: "world"},
+		},
+
+		Operators: []string{},
+
+		RecipeFile: "recipes.json",
+	}
+}
+
+// WorldConfig describes how a world is created. Worlds that already exist keep their seed.
+type WorldConfig struct {
+	// Name is the name of the directory the world is stored in
+	Name string `json:"name"`
+	// Dimension of the world, "overworld" or "nether"
+	Dimension string `json:"dimension"`
+	// Generator is the name of the generator of new chunks, "default", "flat" or "nether"
+	Generator string `json:"generator"`
+	// Seed of a newly created world. If empty, the seed of the default world is used,
+	// or a random seed for the default world itself.
+	Seed string `json:"seed"`
+	// Portal is the name of the world the nether portals of this world lead to, if empty the portals do nothing
+	Portal string `json:"portal"`
+}
+
+// WorldConfig returns configuration of the world with given name, or the default configuration if it is not listed.
+func (c *Config) WorldConfig(name string) WorldConfig {
+	for _, config := range c.Worlds {
+		if config.Name == name {
+			return config
+		}
 	}
+	return WorldConfig{Name: name, Dimension: "overworld", Generator: "default"}
 }
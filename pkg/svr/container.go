@@ -0,0 +1,137 @@
+package svr
+
+import (
+	"slices"
+
+	"github.com/Pesekjak/173go/pkg/prot"
+	"github.com/Pesekjak/173go/pkg/world"
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+	"github.com/Pesekjak/173go/pkg/world/recipe"
+)
+
+// maxWindowDistanceSquared is the squared distance from the player to the centers of the blocks
+// whose window the player can keep open
+const maxWindowDistanceSquared = 64
+
+// useBlock opens the window of the clicked block, if the block has one. It reports whether the block was used.
+func (c *Client) useBlock(pos world.BlockPos) (bool, error) {
+	block, err := c.world.GetBlock(pos.X, pos.Y, pos.Z)
+	if err != nil {
+		return false, err
+	}
+	id := c.nextWindowId()
+	switch block.Material() {
+	case material.Chest:
+		halves, err := c.world.ChestHalves(pos)
+		if err != nil || len(halves) == 0 {
+			// the chest is blocked, but it is still used
+			return true, err
+		}
+		chests := make([]*inventory.Inventory, len(halves))
+		for i, half := range halves {
+			if chests[i], err = c.world.Container(half); err != nil {
+				return true, err
+			}
+		}
+		window := inventory.NewChestWindow(id, c.inventory, chests...)
+		return true, c.openWindow(window, nil, halves, material.Chest)
+	case material.Furnace, material.FurnaceLit:
+		furnace, err := c.world.Furnace(pos)
+		if err != nil {
+			return true, err
+		}
+		window := inventory.NewFurnaceWindow(id, c.inventory, furnace.Inventory, furnace.ProgressBars)
+		return true, c.openWindow(window, nil, []world.BlockPos{pos}, material.Furnace, material.FurnaceLit)
+	case material.Dispenser:
+		dispenser, err := c.world.Container(pos)
+		if err != nil {
+			return true, err
+		}
+		window := inventory.NewDispenserWindow(id, c.inventory, dispenser)
+		return true, c.openWindow(window, nil, []world.BlockPos{pos}, material.Dispenser)
+	case material.CraftingTable:
+		// the crafting grid belongs to the player, the items left in it are thrown away when the window closes
+		grid, result := inventory.NewInventory(inventory.WorkbenchGridSize), inventory.NewInventory(1)
+		window := inventory.NewWorkbenchWindow(id, c.inventory, grid, result)
+		crafting := c.newCrafting(window, grid, result, 3)
+		return true, c.openWindow(window, crafting, []world.BlockPos{pos}, material.CraftingTable)
+	}
+	return false, nil
+}
+
+// newCrafting creates the crafting grid shown in the window, its result is the first slot of the window
+func (c *Client) newCrafting(window *inventory.Window, grid, result *inventory.Inventory, width int) *recipe.Grid {
+	crafting := recipe.NewGrid(c.server.Recipes, grid, result, width)
+	window.Slots[0].OnTake = crafting.Take
+	return crafting
+}
+
+// nextWindowId returns the id of the next window opened by the player, the ids cycle from 1 to 100
+func (c *Client) nextWindowId() byte {
+	return c.lastWindowId%100 + 1
+}
+
+// openWindow closes the open window and opens the window of the blocks at the positions. The window stays open
+// while the blocks are of the given materials and the player is close to them.
+func (c *Client) openWindow(window *inventory.Window, crafting *recipe.Grid, blocks []world.BlockPos,
+	materials ...*material.Block) error {
+	if err := c.closeContainer(); err != nil {
+		return err
+	}
+	err := c.connection.WritePacket(&prot.PacketOutOpenWindow{
+		WindowId: window.Id,
+		Type:     byte(window.Type()),
+		Title:    window.Name(),
+		Slots:    window.Size(),
+	}, false)
+	if err != nil {
+		return err
+	}
+	c.lastWindowId = window.Id
+	c.windowState = windowState{
+		window:    window,
+		crafting:  crafting,
+		world:     c.world,
+		blocks:    blocks,
+		materials: materials,
+	}
+	return c.resendWindow()
+}
+
+// closeContainer closes the window of a container the player has open, the player's own window is kept open
+func (c *Client) closeContainer() error {
+	if c.windowState.blocks == nil {
+		return nil
+	}
+	err := c.connection.WritePacket(&prot.PacketOutCloseWindow{WindowId: c.windowState.window.Id}, true)
+	if err != nil {
+		return err
+	}
+	return c.closeWindow()
+}
+
+// windowValid checks whether the blocks of the open window are still there and close enough to the player
+func (c *Client) windowValid() (bool, error) {
+	state := &c.windowState
+	if state.blocks == nil {
+		return true, nil
+	}
+	if state.world != c.world {
+		return false, nil
+	}
+	for _, pos := range state.blocks {
+		block, err := c.world.GetBlock(pos.X, pos.Y, pos.Z)
+		if err != nil {
+			return false, err
+		}
+		if !slices.Contains(state.materials, block.Material()) {
+			return false, nil
+		}
+		center := world.NewLocation(float64(pos.X)+0.5, float64(pos.Y)+0.5, float64(pos.Z)+0.5, 0, 0)
+		if c.location.DistanceToSquared(center) > maxWindowDistanceSquared {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,257 @@
+package svr
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Pesekjak/173go/pkg/prot"
+	"github.com/Pesekjak/173go/pkg/world"
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+const (
+	// digStart is the digging status sent when the player starts digging a block
+	digStart = 0
+	// digFinish is the digging status sent when the client thinks the block is broken
+	digFinish = 2
+	// digDropItem is the digging status sent when the player drops the held item, dropping items is not supported
+	// and the held stack is sent back
+	digDropItem = 4
+
+	// maxDigDistanceSquared is the squared distance from the player to the blocks the player can dig
+	maxDigDistanceSquared = 36
+	// digTolerance is the part of the digging time accepted when the client finishes digging,
+	// the latency of the client makes the digging look shorter to the server
+	digTolerance = 0.7
+	// eyeHeight is the height of the player's eyes above the feet
+	eyeHeight = 1.62
+)
+
+// diggingState tracks the block the player is digging
+type diggingState struct {
+	// world and block are the world the block is in and its material, digging stops when either changes
+	world   *world.World
+	pos     world.BlockPos
+	block   *material.Block
+	started int64
+	active  bool
+	// delayed is set when the client finished digging too early,
+	// the block breaks once the player has been digging for the whole digging time
+	delayed bool
+}
+
+func (c *Client) OnPlayerDigging(packet *prot.PacketInPlayerDigging) error {
+	c.schedule(func() error {
+		if c.world == nil {
+			return fmt.Errorf("client %v dug a block before logging in", c)
+		}
+		pos := world.NewBlockPos(packet.X, int32(packet.Y), packet.Z)
+		if pos.Y >= int32(world.ChunkHeight) {
+			return nil
+		}
+		switch packet.Status {
+		case digStart:
+			return c.startDigging(pos)
+		case digFinish:
+			return c.finishDigging(pos)
+		case digDropItem:
+			return c.resendHeld()
+		}
+		return nil
+	})
+	return nil
+}
+
+// startDigging starts digging the block, blocks broken in a single tick are broken at once
+func (c *Client) startDigging(pos world.BlockPos) error {
+	if !c.canReach(pos, maxDigDistanceSquared) {
+		return c.resendBlock(pos)
+	}
+	block, err := c.world.GetBlock(pos.X, pos.Y, pos.Z)
+	if err != nil {
+		return err
+	}
+	if block.Material() == material.Air {
+		return nil
+	}
+
+	progress, err := c.digProgress(block.Material())
+	if err != nil {
+		return err
+	}
+	if progress <= 0 {
+		// the block can not be broken at all
+		c.digging = diggingState{}
+		return nil
+	}
+	c.digging = diggingState{
+		world:   c.world,
+		pos:     pos,
+		block:   block.Material(),
+		started: c.server.CurrentTick(),
+		active:  true,
+	}
+	if progress >= 1 {
+		return c.breakBlock(pos)
+	}
+	return nil
+}
+
+// finishDigging breaks the block if the player has been digging it long enough. The client breaks
+// the block on its own, so the block is sent back if it is not broken yet.
+func (c *Client) finishDigging(pos world.BlockPos) error {
+	if c.digging.active && c.digging.pos == pos && c.canReach(pos, maxDigDistanceSquared) {
+		progress, ok, err := c.currentDigProgress()
+		if err != nil {
+			return err
+		}
+		elapsed := c.server.CurrentTick() - c.digging.started
+		if ok && progress*float32(elapsed+1) >= digTolerance {
+			return c.breakBlock(pos)
+		}
+		c.digging.delayed = ok
+	}
+	return c.resendBlock(pos)
+}
+
+// tickDigging breaks the blocks the players finished digging too early once their digging time passes,
+// registered as a tick handler
+func (s *Server) tickDigging(tick int64) {
+	for _, client := range s.Clients() {
+		if !client.digging.delayed {
+			continue
+		}
+		if err := client.tickDelayedDigging(tick); err != nil {
+			client.Disconnect(err)
+		}
+	}
+}
+
+// tickDelayedDigging breaks the block the player finished digging too early if the digging time has passed
+// and the player can still reach it
+func (c *Client) tickDelayedDigging(tick int64) error {
+	progress, ok, err := c.currentDigProgress()
+	if err != nil || !ok {
+		return err
+	}
+	if !c.canReach(c.digging.pos, maxDigDistanceSquared) {
+		c.digging = diggingState{}
+		return nil
+	}
+	if progress*float32(tick-c.digging.started+1) >= 1 {
+		return c.breakBlock(c.digging.pos)
+	}
+	return nil
+}
+
+// currentDigProgress returns the part of the dug block the player breaks in a single tick. The digging stops
+// and ok is false if the player is in another world, the block has changed or it can not be broken anymore.
+func (c *Client) currentDigProgress() (progress float32, ok bool, err error) {
+	pos := c.digging.pos
+	if c.world != c.digging.world {
+		c.digging = diggingState{}
+		return 0, false, nil
+	}
+	block, err := c.world.GetBlock(pos.X, pos.Y, pos.Z)
+	if err != nil {
+		return 0, false, err
+	}
+	if block.Material() != c.digging.block {
+		c.digging = diggingState{}
+		return 0, false, nil
+	}
+	if progress, err = c.digProgress(block.Material()); err != nil {
+		return 0, false, err
+	}
+	if progress <= 0 {
+		c.digging = diggingState{}
+		return 0, false, nil
+	}
+	return progress, true, nil
+}
+
+// breakBlock breaks the block dug by the player, its loot drops if the held item harvests it.
+// The held tool is damaged by breaking the block.
+func (c *Client) breakBlock(pos world.BlockPos) error {
+	c.digging = diggingState{}
+	block, err := c.world.GetBlock(pos.X, pos.Y, pos.Z)
+	if err != nil {
+		return err
+	}
+	broken, held := block.Material(), c.heldItem()
+	harvest := material.CanHarvest(held.Material, broken)
+	if err = c.world.BreakBlock(pos.X, pos.Y, pos.Z, harvest); err != nil {
+		return err
+	}
+	if item, ok := held.Material.(*material.Item); ok {
+		held.Damage(item.BreakDamage(broken))
+		c.inventory.SetHeldStack(held)
+	}
+	return nil
+}
+
+// digProgress returns the part of the block the player breaks in a single tick, as the Notchian server
+// computes it. Digging is five times slower with the head under water and again when not standing on ground.
+func (c *Client) digProgress(block *material.Block) (float32, error) {
+	progress := material.DigProgress(c.heldItem().Material, block)
+	eyesY := int32(math.Floor(c.location.Y + eyeHeight))
+	if eyesY >= 0 && eyesY < int32(world.ChunkHeight) {
+		eyes, err := c.world.GetBlock(int32(math.Floor(c.location.X)), eyesY, int32(math.Floor(c.location.Z)))
+		if err != nil {
+			return 0, err
+		}
+		if eyes.Material().Group == material.GroupWater {
+			progress /= 5
+		}
+	}
+	if !c.onGround {
+		progress /= 5
+	}
+	return progress, nil
+}
+
+// heldItem returns the item stack the player holds
+func (c *Client) heldItem() inventory.ItemStack {
+	return c.inventory.HeldStack()
+}
+
+// resendHeld sends the held stack to the client again, when the client changed it on its own. It is sent
+// in the player's window, and the open container window, which shows the hotbar too, sends it again as well.
+func (c *Client) resendHeld() error {
+	if c.windowState.window != c.inventory.Window() {
+		c.windowState.window.Resend(c.inventory.Main, c.inventory.Held)
+	}
+	return c.connection.WritePacket(&prot.PacketOutSetSlot{
+		WindowId: int8(c.inventory.Window().Id),
+		Slot:     int16(inventory.PlayerHotbarStart + c.inventory.Held),
+		Item:     protItem(c.heldItem()),
+	}, true)
+}
+
+// canReach checks whether the block is close enough to the player to be dug or clicked
+func (c *Client) canReach(pos world.BlockPos, maxDistanceSquared float64) bool {
+	dx := c.location.X - (float64(pos.X) + 0.5)
+	dy := c.location.Y - (float64(pos.Y) + 0.5) + 1.5
+	dz := c.location.Z - (float64(pos.Z) + 0.5)
+	return dx*dx+dy*dy+dz*dz <= maxDistanceSquared
+}
+
+// resendBlock sends the block to the client again, when the client changed it on its own
+// but the change was rejected
+func (c *Client) resendBlock(pos world.BlockPos) error {
+	if pos.Y < 0 || pos.Y >= int32(world.ChunkHeight) {
+		return nil
+	}
+	block, err := c.world.GetBlock(pos.X, pos.Y, pos.Z)
+	if err != nil {
+		return err
+	}
+	return c.connection.WritePacket(&prot.PacketOutBlockChange{
+		X:        pos.X,
+		Y:        byte(pos.Y),
+		Z:        pos.Z,
+		Type:     byte(block.Material().Id()),
+		Metadata: block.Data(),
+	}, true)
+}
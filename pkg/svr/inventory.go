@@ -0,0 +1,233 @@
+package svr
+
+import (
+	"fmt"
+
+	"github.com/Pesekjak/173go/pkg/prot"
+	"github.com/Pesekjak/173go/pkg/world"
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+	"github.com/Pesekjak/173go/pkg/world/recipe"
+)
+
+// windowState is the window the player has open and whether the client has to confirm a rejected click
+// before its clicks are processed again
+type windowState struct {
+	window *inventory.Window
+	// crafting is the crafting grid of the window, its items are thrown away when the window closes
+	crafting *recipe.Grid
+	// blocks are the positions of the blocks whose window is open, with the materials they have to keep,
+	// in the world they are in. They are not set for the player's own window.
+	world     *world.World
+	blocks    []world.BlockPos
+	materials []*material.Block
+
+	// rejected is the action number of the rejected click, the client confirms it after it applied
+	// the window items sent with the rejection
+	rejected   int16
+	confirming bool
+}
+
+func (c *Client) Inventory() *inventory.PlayerInventory {
+	return c.inventory
+}
+
+func (c *Client) OnHoldingChange(packet *prot.PacketInHoldingChange) error {
+	c.schedule(func() error {
+		if packet.Slot < 0 || packet.Slot >= inventory.HotbarSize {
+			c.logger.Warn(c, " tried to hold an invalid slot ", packet.Slot)
+			return nil
+		}
+		c.inventory.Held = int(packet.Slot)
+		return nil
+	})
+	return nil
+}
+
+func (c *Client) OnWindowClick(packet *prot.PacketInWindowClick) error {
+	c.schedule(func() error {
+		if c.world == nil {
+			return fmt.Errorf("client %v clicked in a window before logging in", c)
+		}
+		// clicks in windows that are already closed, or made before the client applied a rollback, are ignored
+		window := c.windowState.window
+		if packet.WindowId != window.Id || c.windowState.confirming {
+			return nil
+		}
+		// the changes made by others are sent first, so the client can not click on the stacks it does not know
+		if err := c.syncWindow(); err != nil {
+			return err
+		}
+
+		clicked, thrown, ok := window.Click(int(packet.Slot), packet.Right, packet.Shift, &c.inventory.Cursor)
+		if crafting := c.windowState.crafting; crafting != nil {
+			crafting.Update()
+		}
+		if !thrown.IsEmpty() {
+			if err := c.throwItem(thrown); err != nil {
+				return err
+			}
+		}
+
+		expected := prot.Item{ID: packet.ItemID}
+		if packet.ItemID >= 0 {
+			expected.Count, expected.Damage = packet.Count, packet.Damage
+		}
+		accepted := ok && protItem(clicked) == expected
+		err := c.connection.WritePacket(&prot.PacketOutTransaction{
+			WindowId: window.Id,
+			Action:   packet.Action,
+			Accepted: accepted,
+		}, accepted)
+		if err != nil {
+			return err
+		}
+		if accepted {
+			// the client made the same changes on its own
+			window.Changes()
+			return nil
+		}
+		c.windowState.rejected = packet.Action
+		c.windowState.confirming = true
+		return c.resendWindow()
+	})
+	return nil
+}
+
+func (c *Client) OnTransaction(packet *prot.PacketInTransaction) error {
+	c.schedule(func() error {
+		state := &c.windowState
+		if state.confirming && packet.WindowId == state.window.Id && packet.Action == state.rejected {
+			state.confirming = false
+		}
+		return nil
+	})
+	return nil
+}
+
+func (c *Client) OnCloseWindow(packet *prot.PacketInCloseWindow) error {
+	c.schedule(func() error {
+		if c.world == nil || packet.WindowId != c.windowState.window.Id {
+			return nil
+		}
+		return c.closeWindow()
+	})
+	return nil
+}
+
+// closeWindow throws away the items left on the cursor and in the crafting grid, the player's own window
+// stays open in the background
+func (c *Client) closeWindow() error {
+	if err := c.throwItem(c.inventory.Cursor); err != nil {
+		return err
+	}
+	c.inventory.Cursor = inventory.EmptyStack()
+	if crafting := c.windowState.crafting; crafting != nil {
+		for i := 0; i < crafting.Items.Size(); i++ {
+			if err := c.throwItem(crafting.Items.Slot(i)); err != nil {
+				return err
+			}
+		}
+		crafting.Items.Clear()
+		crafting.Update()
+	}
+	c.windowState = c.playerWindow()
+	return nil
+}
+
+// playerWindow returns the state of the player's own window
+func (c *Client) playerWindow() windowState {
+	return windowState{window: c.inventory.Window(), crafting: c.crafting}
+}
+
+// throwItem throws the stack from the player's eyes, empty stacks are ignored
+func (c *Client) throwItem(stack inventory.ItemStack) error {
+	if stack.IsEmpty() {
+		return nil
+	}
+	_, err := c.world.ThrowItem(c.location.Add(0, eyeHeight, 0), stack)
+	return err
+}
+
+// syncInventories closes the windows of the blocks the players can not use anymore and sends the slots
+// changed during the tick to the players
+func (s *Server) syncInventories(int64) {
+	for _, client := range s.Clients() {
+		valid, err := client.windowValid()
+		if err == nil && !valid {
+			err = client.closeContainer()
+		}
+		if err == nil {
+			err = client.syncWindow()
+		}
+		if err != nil {
+			client.Disconnect(err)
+			continue
+		}
+		if err := client.connection.Flush(); err != nil {
+			client.Disconnect(err)
+		}
+	}
+}
+
+// syncWindow sends the changed slots and progress bars of the open window to the client. Nothing is sent
+// while a rejected click is not confirmed, the client gets all the items once again then.
+func (c *Client) syncWindow() error {
+	window := c.windowState.window
+	if c.windowState.confirming {
+		return nil
+	}
+	for _, slot := range window.Changes() {
+		err := c.connection.WritePacket(&prot.PacketOutSetSlot{
+			WindowId: int8(window.Id),
+			Slot:     int16(slot),
+			Item:     protItem(window.Slots[slot].Stack()),
+		}, false)
+		if err != nil {
+			return err
+		}
+	}
+	changed := window.ProgressBarChanges()
+	if len(changed) == 0 {
+		return nil
+	}
+	bars := window.ProgressBars()
+	for _, bar := range changed {
+		err := c.connection.WritePacket(&prot.PacketOutUpdateProgressBar{
+			WindowId: window.Id,
+			Bar:      int16(bar),
+			Value:    bars[bar],
+		}, false)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resendWindow sends all the items of the open window and the stack on the cursor to the client
+func (c *Client) resendWindow() error {
+	window := c.windowState.window
+	contents := window.Contents()
+	items := make([]prot.Item, len(contents))
+	for i, stack := range contents {
+		items[i] = protItem(stack)
+	}
+	err := c.connection.WritePacket(&prot.PacketOutWindowItems{WindowId: window.Id, Items: items}, false)
+	if err != nil {
+		return err
+	}
+	return c.connection.WritePacket(&prot.PacketOutSetSlot{
+		WindowId: -1,
+		Slot:     -1,
+		Item:     protItem(c.inventory.Cursor),
+	}, true)
+}
+
+// protItem converts the stack to the item sent in the packets
+func protItem(stack inventory.ItemStack) prot.Item {
+	if stack.IsEmpty() {
+		return prot.Item{ID: -1}
+	}
+	return prot.Item{ID: int16(stack.Material.Id()), Count: stack.Count, Damage: int16(stack.Data)}
+}
@@ -0,0 +1,84 @@
+package svr
+
+import (
+	"fmt"
+
+	"github.com/Pesekjak/173go/pkg/prot"
+	"github.com/Pesekjak/173go/pkg/world"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// maxPlaceDistanceSquared is the squared distance from the player to the blocks the player can click
+// to place blocks next to them
+const maxPlaceDistanceSquared = 64
+
+func (c *Client) OnPlayerBlockPlacement(packet *prot.PacketInPlayerBlockPlacement) error {
+	c.schedule(func() error {
+		if c.world == nil {
+			return fmt.Errorf("client %v placed a block before logging in", c)
+		}
+		// face -1 is sent when an item is used in the air
+		if packet.Face < 0 {
+			return nil
+		}
+		clicked := world.NewBlockPos(packet.X, int32(packet.Y), packet.Z)
+		if clicked.Y >= int32(world.ChunkHeight) {
+			return nil
+		}
+		if !c.canReach(clicked, maxPlaceDistanceSquared) {
+			return c.resendPlacement(clicked, packet.Face)
+		}
+		// the clicked block is used instead of placing the held item next to it, if it can be used
+		if used, err := c.useBlock(clicked); err != nil || used {
+			return err
+		}
+		if packet.ItemID < 0 {
+			return nil
+		}
+
+		// the item is taken from the server's inventory, the client is only told when they differ
+		stack := c.inventory.HeldStack()
+		if protItem(stack) != (prot.Item{ID: packet.ItemID, Count: packet.Count, Damage: packet.Damage}) {
+			if err := c.resendHeld(); err != nil {
+				return err
+			}
+		}
+		if stack.IsEmpty() {
+			return c.resendPlacement(clicked, packet.Face)
+		}
+		if stack.Material == material.FlintAndSteel {
+			pos, _ := clicked.Relative(packet.Face)
+			lit, err := c.world.PlaceFire(pos.X, pos.Y, pos.Z)
+			if err != nil || !lit {
+				return err
+			}
+			stack.Damage(1)
+			c.inventory.SetHeldStack(stack)
+			return nil
+		}
+
+		placed, err := c.world.PlaceItem(clicked, packet.Face, stack, c.location.Yaw)
+		if err != nil {
+			return err
+		}
+		if !placed {
+			return c.resendPlacement(clicked, packet.Face)
+		}
+		stack.Split(1)
+		c.inventory.SetHeldStack(stack)
+		return nil
+	})
+	return nil
+}
+
+// resendPlacement sends the clicked block and the block next to its clicked face to the client again,
+// after the client placed a block the server rejected
+func (c *Client) resendPlacement(clicked world.BlockPos, face int8) error {
+	if err := c.resendBlock(clicked); err != nil {
+		return err
+	}
+	if pos, ok := clicked.Relative(face); ok {
+		return c.resendBlock(pos)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package svr
+
+import (
+	"github.com/Pesekjak/173go/pkg/world"
+)
+
+const (
+	// portalStep is the progress of travelling made in a tick spent in a portal, the travel takes 80 ticks
+	portalStep = 0.0125
+	// portalDecay is the progress lost in a tick spent outside of portals
+	portalDecay = 0.05
+	// portalCooldown is the number of ticks after travelling during which portals are ignored,
+	// it starts again every tick the player stays in the exit portal
+	portalCooldown = 10
+)
+
+// portalState tracks how long a player has been standing in a portal
+type portalState struct {
+	progress float32
+	cooldown int
+}
+
+// tickPortals moves players who stood in a portal long enough to the world the portal leads to,
+// registered as a tick handler
+func (s *Server) tickPortals(int64) {
+	for _, client := range s.Clients() {
+		if err := client.tickPortal(); err != nil {
+			client.Disconnect(err)
+		}
+	}
+}
+
+// tickPortal updates the portal progress of the player, as the Notchian server does
+func (c *Client) tickPortal() error {
+	// the bounding box of the player, slightly shrunk so standing next to a portal does not count
+	const halfWidth, height, shrink = 0.3, 1.8, 0.001
+	inPortal, err := c.world.InPortal(
+		c.location.Add(-halfWidth+shrink, shrink, -halfWidth+shrink),
+		c.location.Add(halfWidth-shrink, height-shrink, halfWidth-shrink),
+	)
+	if err != nil {
+		return err
+	}
+
+	if inPortal && c.portal.cooldown > 0 {
+		c.portal.cooldown = portalCooldown
+		inPortal = false
+	}
+	if c.portal.cooldown > 0 {
+		c.portal.cooldown--
+	}
+	if !inPortal {
+		c.portal.progress = max(c.portal.progress-portalDecay, 0)
+		return nil
+	}
+
+	c.portal.progress += portalStep
+	if c.portal.progress < 1 {
+		return nil
+	}
+	c.portal.progress = 1
+	c.portal.cooldown = portalCooldown
+	return c.travelThroughPortal()
+}
+
+// travelThroughPortal moves the player to the portal of the world the current world's portals lead to.
+// Distances in the nether are scaled down, the exit portal is found or built around the scaled location.
+func (c *Client) travelThroughPortal() error {
+	target, ok := c.server.World(c.server.Config.WorldConfig(c.world.Name()).Portal)
+	if !ok {
+		return nil
+	}
+
+	location := c.location
+	if c.world.Dimension() != world.Hell && target.Dimension() == world.Hell {
+		location.X /= world.PortalScale
+		location.Z /= world.PortalScale
+	} else if c.world.Dimension() == world.Hell && target.Dimension() != world.Hell {
+		location.X *= world.PortalScale
+		location.Z *= world.PortalScale
+	}
+
+	exit, err := target.PortalExit(location)
+	if err != nil {
+		return err
+	}
+	c.logger.Info(c, " travelled through a portal to ", target.Name())
+	return c.Respawn(target, exit)
+}
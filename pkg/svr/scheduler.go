@@ -0,0 +1,51 @@
+package svr
+
+import "sync"
+
+// taskQueue is an unbounded queue of tasks waiting to be run on the server goroutine.
+// Pushing never blocks, so tasks can be scheduled even from the server goroutine itself.
+type taskQueue struct {
+	mu    sync.Mutex
+	tasks []func()
+
+	// notify receives a value whenever there are new tasks in the queue
+	notify chan struct{}
+}
+
+func newTaskQueue() *taskQueue {
+	return &taskQueue{notify: make(chan struct{}, 1)}
+}
+
+// push adds the task to the end of the queue
+func (q *taskQueue) push(task func()) {
+	q.mu.Lock()
+	q.tasks = append(q.tasks, task)
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default: // notification is already pending
+	}
+}
+
+// drain removes and returns all tasks in the queue
+func (q *taskQueue) drain() []func() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	tasks := q.tasks
+	q.tasks = nil
+	return tasks
+}
+
+// Schedule runs the task on the server goroutine, the only goroutine allowed to touch worlds and
+// the game state of clients. It is safe to call from any goroutine and never blocks.
+func (s *Server) Schedule(task func()) {
+	s.tasks.push(task)
+}
+
+// runTasks runs all scheduled tasks
+func (s *Server) runTasks() {
+	for _, task := range s.tasks.drain() {
+		task()
+	}
+}
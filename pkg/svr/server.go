@@ -1,8 +1,14 @@
 package svr
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/Pesekjak/173go/pkg/base"
 	"github.com/Pesekjak/173go/pkg/cmd"
 	"github.com/Pesekjak/173go/pkg/cons"
 	"github.com/Pesekjak/173go/pkg/log"
@@ -10,57 +16,108 @@ import (
 	"github.com/Pesekjak/173go/pkg/prot"
 	"github.com/Pesekjak/173go/pkg/system"
 	"github.com/Pesekjak/173go/pkg/world"
+	"github.com/Pesekjak/173go/pkg/world/recipe"
 )
 
+// Server is the game server. All game state, including the worlds and the game state of the clients,
+// is owned by the server goroutine running the tick loop. Other goroutines (network connections, console)
+// must hand the work over to it using Schedule.
 type Server struct {
 	message chan system.Message
+	tasks   *taskQueue
 
 	Config
 
 	*cons.Console
 	*cmd.CommandManager
 
+	// Recipes are the recipes the players can craft
+	Recipes *recipe.Registry
+
+	// worlds are the worlds of the server by their names, players join the default world
+	worlds       map[string]*world.World
 	defaultWorld *world.World
 
-	clients []Client
+	clients   []*Client
+	clientsMu sync.RWMutex
+
+	tick         int64
+	tickStats    tickStats
+	tickHandlers []TickHandler
 }
 
 func NewServer() (*Server, error) {
-	message := make(chan system.Message)
+	message := make(chan system.Message, 1)
 
 	config := NewDefaultConfig()
 	console := cons.NewConsole(os.Stdin, os.Stdout, log.BasicLevels...)
 
-	defaultWorld, err := world.NewWorld()
-	if err != nil {
-		return nil, err
-	}
-
 	commandManager := cmd.NewCommandManager(console.ChildLogger("cmd"))
 
 	server := &Server{
 		message: message,
+		tasks:   newTaskQueue(),
 
 		Config: config,
 
 		Console:        console,
 		CommandManager: commandManager,
 
-		defaultWorld: defaultWorld,
+		worlds: make(map[string]*world.World),
 
-		clients: make([]Client, 0, 8),
+		clients: make([]*Client, 0, 8),
 	}
 
+	if err := server.loadRecipes(); err != nil {
+		return nil, err
+	}
+	if err := server.loadWorlds(); err != nil {
+		return nil, err
+	}
 	return server, nil
 }
 
+// loadRecipes loads the recipes of the Notchian server and the recipes of the server's data file, if it exists
+func (s *Server) loadRecipes() error {
+	recipes, err := recipe.NewDefaultRegistry()
+	if err != nil {
+		return err
+	}
+	s.Recipes = recipes
+	if s.Config.RecipeFile == "" {
+		return nil
+	}
+	if _, err = os.Stat(s.Config.RecipeFile); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err = recipes.LoadFile(s.Config.RecipeFile); err != nil {
+		return err
+	}
+	s.Console.Info("loaded the recipes of ", s.Config.RecipeFile)
+	return nil
+}
+
 func (s *Server) Start() {
 	s.Console.Start(func(cmd string) {
-		s.CommandManager.ExecuteCommand(s.Console, cmd)
+		s.Schedule(func() {
+			s.CommandManager.ExecuteCommand(s.Console, cmd)
+		})
 	})
 	s.Console.Info("starting 173go server...")
 
 	registerCommands(s)
+	s.registerTickHandlers()
+
+	s.Console.Info("preparing spawn area...")
+	spawn := s.defaultWorld.SpawnPoint.ToChunkPos()
+	radius := int32(s.Config.ViewDistance)
+	for x := spawn.X - radius; x <= spawn.X+radius; x++ {
+		for z := spawn.Z - radius; z <= spawn.Z+radius; z++ {
+			if _, err := s.defaultWorld.LoadChunk(world.NewChunkPos(x, z)); err != nil {
+				s.Console.Severe("failed to load the chunk at ", x, ";", z, ": ", err)
+			}
+		}
+	}
 
 	network := net.NewNetwork(s.Config.Address, s.Config.Port, s.Console.ChildLogger("network"), s.message)
 	if err := network.Start(func(conn *net.Connection) (prot.PacketHandler, error) {
@@ -70,25 +127,37 @@ func (s *Server) Start() {
 		return
 	}
 
-	for x := int32(-4); x <= 4; x++ {
-		for z := int32(-4); z <= 4; z++ {
-			if _, err := s.defaultWorld.LoadChunk(world.NewChunkPos(x, z)); err != nil {
-				s.Console.Severe("failed to load the chunk at ", x, ";", z, ": ", err)
-			}
-		}
-	}
-
 	s.Console.Info("server is running")
 	s.wait()
 }
 
+// registerTickHandlers adds the tick handlers of the server's own game logic
+func (s *Server) registerTickHandlers() {
+	s.AddTickHandler(s.sendPendingChunks)
+	s.AddTickHandler(s.tickPortals)
+	s.AddTickHandler(s.tickDigging)
+	s.AddTickHandler(s.syncInventories)
+	s.AddTickHandler(s.unloadChunks)
+}
+
+// Stop stops the server. It is safe to call from any goroutine.
 func (s *Server) Stop() {
-	s.message <- system.Make(system.Stop, nil)
+	select {
+	case s.message <- system.Make(system.Stop, nil):
+	default: // the server is already stopping
+	}
 }
 
 func (s *Server) wait() {
+	ticker := time.NewTicker(TickInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
+		case <-ticker.C:
+			s.runTick()
+		case <-s.tasks.notify:
+			s.runTasks()
 		case command := <-s.message:
 			switch command.Command {
 			case system.Stop:
@@ -106,5 +175,81 @@ func (s *Server) wait() {
 }
 
 func (s *Server) terminate() {
+	for _, client := range s.Clients() {
+		client.Kick("Server closed")
+	}
+	s.Console.Info("saving the worlds...")
+	for _, w := range s.Worlds() {
+		if err := w.Close(); err != nil {
+			s.Console.Severe("failed to save the world ", w.Name(), ": ", err)
+		}
+	}
 	s.Console.Stop()
 }
+
+// Save writes all worlds to the disk.
+func (s *Server) Save() error {
+	for _, w := range s.Worlds() {
+		if err := w.Save(); err != nil {
+			return fmt.Errorf("failed to save the world %v: %v", w.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Clients returns a snapshot of all clients that are currently in game.
+// The list is only modified on the server goroutine but can be read from anywhere.
+func (s *Server) Clients() []*Client {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+	clients := make([]*Client, len(s.clients))
+	copy(clients, s.clients)
+	return clients
+}
+
+// Client returns the online client with given username.
+func (s *Server) Client(username string) (*Client, bool) {
+	for _, client := range s.Clients() {
+		if strings.EqualFold(client.username, username) {
+			return client, true
+		}
+	}
+	return nil, false
+}
+
+// Broadcast sends a chat message to every online client and logs it to the console.
+func (s *Server) Broadcast(message ...interface{}) {
+	msg := base.ConvertToString(message...)
+	s.Console.SendMessage(msg)
+	for _, client := range s.Clients() {
+		client.SendMessage(msg)
+	}
+}
+
+// IsOperator checks whether a player with given username is a server operator.
+func (s *Server) IsOperator(username string) bool {
+	for _, operator := range s.Config.Operators {
+		if strings.EqualFold(operator, username) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) addClient(client *Client) {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	s.clients = append(s.clients, client)
+}
+
+func (s *Server) removeClient(client *Client) bool {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	for i, c := range s.clients {
+		if c == client {
+			s.clients = append(s.clients[:i], s.clients[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,314 @@
+package svr
+
+import (
+	"fmt"
+	"io"
+	stdnet "net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Pesekjak/173go/pkg/cmd"
+	"github.com/Pesekjak/173go/pkg/cons"
+	"github.com/Pesekjak/173go/pkg/log"
+	"github.com/Pesekjak/173go/pkg/net"
+	"github.com/Pesekjak/173go/pkg/prot"
+	"github.com/Pesekjak/173go/pkg/system"
+	"github.com/Pesekjak/173go/pkg/world"
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// newTestServer starts a server with a single flat world in a temporary directory, without the network
+// and the console input. The server goroutine is stopped when the test ends.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	t.Chdir(t.TempDir())
+
+	console := cons.NewConsole(strings.NewReader(""), io.Discard, log.BasicLevels...)
+	s := &Server{
+		message: make(chan system.Message, 1),
+		tasks:   newTaskQueue(),
+
+		Config: NewDefaultConfig(),
+
+		Console:        console,
+		CommandManager: cmd.NewCommandManager(console.ChildLogger("cmd")),
+
+		worlds: make(map[string]*world.World),
+	}
+	s.Config.ViewDistance = 2
+	s.Config.LevelName = "flat"
+	s.Config.Worlds = []WorldConfig{{Name: "flat", Dimension: "overworld", Generator: "flat"}}
+	if err := s.loadRecipes(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.loadWorlds(); err != nil {
+		t.Fatal(err)
+	}
+	s.registerTickHandlers()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.wait()
+		close(stopped)
+	}()
+	t.Cleanup(func() {
+		s.Stop()
+		<-stopped
+	})
+	return s
+}
+
+// run runs the task on the server goroutine and waits for it
+func run(s *Server, task func()) {
+	done := make(chan struct{})
+	s.Schedule(func() {
+		task()
+		close(done)
+	})
+	<-done
+}
+
+// newTestConnection creates a connection over the loopback, everything written to it is discarded
+func newTestConnection(t *testing.T, s *Server) *net.Connection {
+	t.Helper()
+	listener, err := stdnet.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	remote, err := stdnet.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = remote.Close() })
+	go func() { _, _ = io.Copy(io.Discard, remote) }()
+
+	tcp, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return net.NewConnection(tcp.(*stdnet.TCPConn), s.Console.ChildLogger("network"), s.message)
+}
+
+func TestScheduleConcurrent(t *testing.T) {
+	s := newTestServer(t)
+	const goroutines, tasks = 16, 500
+
+	// the counters are touched by the tasks only, the race detector reports the tasks run outside the server goroutine
+	counter, nested := 0, 0
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < tasks; j++ {
+				s.Schedule(func() {
+					counter++
+					// tasks scheduled from the server goroutine run later as well
+					s.Schedule(func() { nested++ })
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the nested tasks are all scheduled once the first task waited for runs
+	run(s, func() {})
+	var got, gotNested int
+	run(s, func() { got, gotNested = counter, nested })
+	if want := goroutines * tasks; got != want || gotNested != want {
+		t.Errorf("ran %v tasks and %v nested tasks, want %v of both", got, gotNested, want)
+	}
+}
+
+func TestClientsConcurrent(t *testing.T) {
+	s := newTestServer(t)
+	const clients, rounds = 8, 50
+
+	// each client places and digs dirt on its own block next to the spawn, away from the other players
+	positions := make([]world.BlockPos, clients)
+	for i := range positions {
+		positions[i] = world.NewBlockPos(int32(i)-clients/2, 4, 2)
+	}
+	logged := make([]*Client, clients)
+
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		conn := newTestConnection(t, s)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// the handlers of a client run on the goroutine of its connection, like they do in the network
+			c := NewClient(s, conn)
+			logged[i] = c
+			name := fmt.Sprintf("player%v", i)
+			if err := c.OnHandShake(&prot.PacketInHandShake{Username: name}); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := c.OnLogin(&prot.PacketInLogin{Protocol: 14, Username: name}); err != nil {
+				t.Error(err)
+				return
+			}
+			s.Schedule(func() {
+				c.inventory.SetHeldStack(inventory.NewItemStack(material.Dirt, 64, 0))
+			})
+
+			clicked := positions[i]
+			placed := clicked.Up(1)
+			for j := 0; j < rounds; j++ {
+				for _, handle := range [...]func() error{
+					func() error {
+						return c.OnPlayerBlockPlacement(&prot.PacketInPlayerBlockPlacement{
+							X: clicked.X, Y: byte(clicked.Y), Z: clicked.Z, Face: 1,
+							ItemID: int16(material.Dirt.Id()), Count: 64,
+						})
+					},
+					func() error {
+						return c.OnPlayerDigging(&prot.PacketInPlayerDigging{
+							Status: digStart, X: placed.X, Y: byte(placed.Y), Z: placed.Z, Face: 1,
+						})
+					},
+					func() error {
+						return c.OnPlayerDigging(&prot.PacketInPlayerDigging{
+							Status: digFinish, X: placed.X, Y: byte(placed.Y), Z: placed.Z, Face: 1,
+						})
+					},
+					func() error {
+						return c.OnPlayerLook(&prot.PacketInPlayerLook{Yaw: float32(j), OnGround: true})
+					},
+				} {
+					if err := handle(); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+				_ = s.Clients()
+			}
+		}()
+	}
+	wg.Wait()
+
+	run(s, func() {
+		if online := len(s.Clients()); online != clients {
+			t.Errorf("%v clients online, want %v", online, clients)
+		}
+		for i, c := range logged {
+			if c.World() != s.DefaultWorld() || c.connection.IsClosed() {
+				t.Errorf("client %v is not in game, world %v", c, c.World())
+			}
+			block, err := s.DefaultWorld().GetBlock(positions[i].X, positions[i].Y+1, positions[i].Z)
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			if placed := block.Material(); placed != material.Dirt && placed != material.Air {
+				t.Errorf("block placed by %v is %v, want dirt or air", c, placed)
+			}
+		}
+	})
+}
+
+// newTestClient logs a new client in to the server
+func newTestClient(t *testing.T, s *Server, name string) *Client {
+	t.Helper()
+	c := NewClient(s, newTestConnection(t, s))
+	if err := c.OnHandShake(&prot.PacketInHandShake{Username: name}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.OnLogin(&prot.PacketInLogin{Protocol: 14, Username: name}); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestBreakBlockDamage(t *testing.T) {
+	s := newTestServer(t)
+	c := newTestClient(t, s, "player")
+	tests := []struct {
+		held  inventory.ItemStack
+		block *material.Block
+		want  inventory.ItemStack
+	}{
+		{inventory.NewItemStack(material.IronPickaxe, 1, 0), material.Stone,
+			inventory.NewItemStack(material.IronPickaxe, 1, 1)},
+		{inventory.NewItemStack(material.WoodenShovel, 1, 10), material.Dirt,
+			inventory.NewItemStack(material.WoodenShovel, 1, 11)},
+		// digging tools are damaged by any block, swords twice as much
+		{inventory.NewItemStack(material.DiamondAxe, 1, 0), material.Dirt,
+			inventory.NewItemStack(material.DiamondAxe, 1, 1)},
+		{inventory.NewItemStack(material.StoneSword, 1, 5), material.Leaves,
+			inventory.NewItemStack(material.StoneSword, 1, 7)},
+		// shears are only damaged by the blocks they are made for, hoes and other items not at all
+		{inventory.NewItemStack(material.Shears, 1, 0), material.Leaves,
+			inventory.NewItemStack(material.Shears, 1, 1)},
+		{inventory.NewItemStack(material.Shears, 1, 0), material.Dirt,
+			inventory.NewItemStack(material.Shears, 1, 0)},
+		{inventory.NewItemStack(material.GoldHoe, 1, 0), material.Dirt,
+			inventory.NewItemStack(material.GoldHoe, 1, 0)},
+		{inventory.NewItemStack(material.Dirt, 10, 0), material.Dirt,
+			inventory.NewItemStack(material.Dirt, 10, 0)},
+		// the tool breaks once it is worn out
+		{inventory.NewItemStack(material.GoldPickaxe, 1, material.GoldPickaxe.MaxDamage()), material.Stone,
+			inventory.EmptyStack()},
+	}
+	pos := world.NewBlockPos(0, 10, 0)
+	for _, test := range tests {
+		run(s, func() {
+			block, err := s.DefaultWorld().GetBlock(pos.X, pos.Y, pos.Z)
+			if err == nil {
+				err = block.Set(test.block, 0)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.inventory.SetHeldStack(test.held)
+			if err := c.breakBlock(pos); err != nil {
+				t.Fatal(err)
+			}
+			if got := c.inventory.HeldStack(); got != test.want {
+				t.Errorf("%v:%v breaking %v is %v:%v, want %v:%v", test.held, test.held.Data, test.block,
+					got, got.Data, test.want, test.want.Data)
+			}
+		})
+	}
+}
+
+func TestDisconnectClosesWindow(t *testing.T) {
+	s := newTestServer(t)
+	c := newTestClient(t, s, "player")
+	pos := world.NewBlockPos(0, 10, 0)
+	var grid *inventory.Inventory
+	run(s, func() {
+		block, err := s.DefaultWorld().GetBlock(pos.X, pos.Y, pos.Z)
+		if err == nil {
+			err = block.Set(material.CraftingTable, 0)
+		}
+		if err == nil {
+			_, err = c.useBlock(pos)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		grid = c.windowState.crafting.Items
+		grid.SetSlot(4, inventory.NewItemStack(material.WoodenPlanks, 3, 0))
+		c.inventory.Cursor = inventory.NewItemStack(material.Stick, 5, 0)
+	})
+
+	c.connection.Close(nil)
+	run(s, func() {
+		if !grid.Slot(4).IsEmpty() || !c.inventory.Cursor.IsEmpty() {
+			t.Errorf("the crafting grid has %v and the cursor %v after disconnecting, want them thrown out",
+				grid.Slot(4), c.inventory.Cursor)
+		}
+		if c.windowState.window != c.inventory.Window() {
+			t.Errorf("window %v is open after disconnecting", c.windowState.window.Id)
+		}
+		if len(s.Clients()) != 0 {
+			t.Errorf("%v clients online after disconnecting", len(s.Clients()))
+		}
+	})
+}
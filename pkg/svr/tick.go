@@ -0,0 +1,74 @@
+package svr
+
+import (
+	"time"
+)
+
+const (
+	// TicksPerSecond is the number of server ticks per second
+	TicksPerSecond = 20
+	// TickInterval is the expected duration between two ticks
+	TickInterval = time.Second / TicksPerSecond
+
+	// number of ticks used to compute the average tick duration
+	tickSamples = 100
+)
+
+// TickHandler is called once every server tick with the number of the current tick.
+type TickHandler func(tick int64)
+
+// tickStats collects durations of recent ticks
+type tickStats struct {
+	samples [tickSamples]time.Duration
+	last    time.Duration
+}
+
+// AddTickHandler registers a handler that is called every server tick after the worlds are ticked.
+// Handlers run on the server goroutine.
+func (s *Server) AddTickHandler(handler TickHandler) {
+	s.tickHandlers = append(s.tickHandlers, handler)
+}
+
+// CurrentTick returns the number of ticks since the server started.
+func (s *Server) CurrentTick() int64 {
+	return s.tick
+}
+
+// TickDuration returns how long the last tick took.
+func (s *Server) TickDuration() time.Duration {
+	return s.tickStats.last
+}
+
+// AverageTickDuration returns the average duration of the recent ticks.
+func (s *Server) AverageTickDuration() time.Duration {
+	var total time.Duration
+	count := min(s.tick, tickSamples)
+	if count == 0 {
+		return 0
+	}
+	for i := int64(0); i < count; i++ {
+		total += s.tickStats.samples[i]
+	}
+	return total / time.Duration(count)
+}
+
+// runTick runs a single server tick
+func (s *Server) runTick() {
+	start := time.Now()
+	s.tick++
+
+	for _, w := range s.worlds {
+		w.Tick()
+	}
+
+	for _, handler := range s.tickHandlers {
+		handler(s.tick)
+	}
+
+	duration := time.Since(start)
+	s.tickStats.last = duration
+	s.tickStats.samples[s.tick%tickSamples] = duration
+	if duration > TickInterval {
+		s.Console.WarnF("can't keep up! tick %d took %v (limit is %v)", s.tick, duration, TickInterval)
+	}
+}
@@ -0,0 +1,139 @@
+package svr
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/Pesekjak/173go/pkg/world"
+	"github.com/Pesekjak/173go/pkg/world/gen"
+)
+
+// loadWorlds loads the default world and the other worlds listed in the config
+func (s *Server) loadWorlds() error {
+	defaultWorld, err := s.LoadWorld(s.Config.WorldConfig(s.Config.LevelName))
+	if err != nil {
+		return err
+	}
+	s.defaultWorld = defaultWorld
+
+	for _, config := range s.Config.Worlds {
+		if config.Name == s.Config.LevelName {
+			continue
+		}
+		if _, err = s.LoadWorld(config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadWorld loads the world stored in the directory of its name, the world is created if it does not exist yet.
+// It fails if a world with the same name is already loaded.
+func (s *Server) LoadWorld(config WorldConfig) (*world.World, error) {
+	if config.Name == "" || config.Name != filepath.Base(config.Name) || config.Name == ".." {
+		return nil, fmt.Errorf("invalid world name: '%v'", config.Name)
+	}
+	if _, ok := s.worlds[config.Name]; ok {
+		return nil, fmt.Errorf("world %v is already loaded", config.Name)
+	}
+
+	dimension, err := world.ParseDimension(config.Dimension)
+	if err != nil {
+		return nil, err
+	}
+	factory, err := gen.Factory(config.Generator)
+	if err != nil {
+		return nil, err
+	}
+	// worlds share the seed of the default world unless configured otherwise, like the nether does
+	seed := world.ParseSeed(config.Seed)
+	if config.Seed == "" && s.defaultWorld != nil {
+		seed = s.defaultWorld.Seed()
+	}
+
+	w, err := world.NewWorld(config.Name, dimension, seed, factory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load world %v: %v", config.Name, err)
+	}
+	s.worlds[config.Name] = w
+	return w, nil
+}
+
+// CreateWorld creates a new world and loads it. It fails if the world already exists.
+func (s *Server) CreateWorld(config WorldConfig) (*world.World, error) {
+	if exists, err := world.Exists(config.Name); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, fmt.Errorf("world %v already exists", config.Name)
+	}
+	w, err := s.LoadWorld(config)
+	if err != nil {
+		return nil, err
+	}
+	// the world is written right away, so it is not created again
+	if err = w.Save(); err != nil {
+		return nil, fmt.Errorf("failed to save world %v: %v", config.Name, err)
+	}
+	return w, nil
+}
+
+// UnloadWorld saves and unloads the world with given name. Players in the world are moved
+// to the spawn of the default world, which can not be unloaded.
+func (s *Server) UnloadWorld(name string) error {
+	w, ok := s.worlds[name]
+	if !ok {
+		return fmt.Errorf("world %v is not loaded", name)
+	}
+	if w == s.defaultWorld {
+		return fmt.Errorf("the default world can not be unloaded")
+	}
+
+	spawn, err := s.defaultWorld.SpawnLocation()
+	if err != nil {
+		return err
+	}
+	for _, client := range s.Clients() {
+		if client.world != w {
+			continue
+		}
+		if err = client.Respawn(s.defaultWorld, spawn); err != nil {
+			client.Disconnect(err)
+		}
+	}
+
+	delete(s.worlds, name)
+	return w.Close()
+}
+
+// DefaultWorld returns the world players join the game in.
+func (s *Server) DefaultWorld() *world.World {
+	return s.defaultWorld
+}
+
+// World returns the loaded world with given name.
+func (s *Server) World(name string) (*world.World, bool) {
+	w, ok := s.worlds[name]
+	return w, ok
+}
+
+// Worlds returns all loaded worlds sorted by their names.
+func (s *Server) Worlds() []*world.World {
+	worlds := make([]*world.World, 0, len(s.worlds))
+	for _, w := range s.worlds {
+		worlds = append(worlds, w)
+	}
+	sort.Slice(worlds, func(i, j int) bool {
+		return worlds[i].Name() < worlds[j].Name()
+	})
+	return worlds
+}
+
+// unloadChunks saves and unloads the chunks of the worlds no player needs, registered as a tick handler
+func (s *Server) unloadChunks(int64) {
+	for _, w := range s.worlds {
+		if err := w.UnloadChunks(); err != nil {
+			s.Console.Severe("failed to unload the chunks of the world ", w.Name(), ": ", err)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package world
+
+import "github.com/Pesekjak/173go/pkg/world/material"
+
+// Biome is a region of the world with its own climate, surface and vegetation
+type Biome struct {
+	name string
+	// TopBlock covers the surface of the biome
+	TopBlock *material.Block
+	// FillerBlock is below the top block
+	FillerBlock *material.Block
+	snow        bool
+	rain        bool
+}
+
+func newBiome(name string) *Biome {
+	return &Biome{
+		name:        name,
+		TopBlock:    material.GrassBlock,
+		FillerBlock: material.Dirt,
+		snow:        false,
+		rain:        true,
+	}
+}
+
+func (b *Biome) withSurface(top, filler *material.Block) *Biome {
+	b.TopBlock = top
+	b.FillerBlock = filler
+	return b
+}
+
+func (b *Biome) withSnow() *Biome {
+	b.snow = true
+	return b
+}
+
+func (b *Biome) withoutRain() *Biome {
+	b.rain = false
+	return b
+}
+
+func (b *Biome) String() string {
+	return b.name
+}
+
+// HasSnow reports whether it snows instead of raining in the biome.
+func (b *Biome) HasSnow() bool {
+	return b.snow
+}
+
+// HasRain reports whether there is any precipitation in the biome.
+func (b *Biome) HasRain() bool {
+	return b.rain
+}
+
+var (
+	Rainforest     = newBiome("Rainforest")
+	Swampland      = newBiome("Swampland")
+	SeasonalForest = newBiome("Seasonal Forest")
+	Forest         = newBiome("Forest")
+	Savanna        = newBiome("Savanna")
+	Shrubland      = newBiome("Shrubland")
+	Taiga          = newBiome("Taiga").withSnow()
+	Desert         = newBiome("Desert").withSurface(material.Sand, material.Sand).withoutRain()
+	Plains         = newBiome("Plains")
+	IceDesert      = newBiome("Ice Desert").withSurface(material.Sand, material.Sand).withSnow().withoutRain()
+	Tundra         = newBiome("Tundra").withSnow()
+	HellBiome      = newBiome("Hell").withoutRain()
+)
+
+// biomeLookup maps the climate to biomes, the temperature and rainfall are quantized to 64 steps each
+var biomeLookup [64 * 64]*Biome
+
+func init() {
+	for t := 0; t < 64; t++ {
+		for r := 0; r < 64; r++ {
+			biomeLookup[t+r*64] = biomeForClimate(float32(t)/63, float32(r)/63)
+		}
+	}
+}
+
+// biomeForClimate picks biome for temperature and rainfall the same way as the Notchian server
+func biomeForClimate(temperature, rainfall float32) *Biome {
+	rainfall *= temperature
+	switch {
+	case temperature < 0.1:
+		return Tundra
+	case rainfall < 0.2:
+		if temperature < 0.5 {
+			return Tundra
+		} else if temperature < 0.95 {
+			return Savanna
+		}
+		return Desert
+	case rainfall > 0.5 && temperature < 0.7:
+		return Swampland
+	case temperature < 0.5:
+		return Taiga
+	case temperature < 0.97:
+		if rainfall < 0.35 {
+			return Shrubland
+		}
+		return Forest
+	case rainfall < 0.45:
+		return Plains
+	case rainfall < 0.9:
+		return SeasonalForest
+	default:
+		return Rainforest
+	}
+}
+
+// BiomeForClimate returns biome of a column with given temperature and rainfall, both in range [0, 1].
+func BiomeForClimate(temperature, rainfall float64) *Biome {
+	return biomeLookup[int(temperature*63)+int(rainfall*63)*64]
+}
+
+// BiomeSource provides the climate and biomes of columns of a world
+type BiomeSource interface {
+	// Biome returns biome of the column at given coordinates
+	Biome(x, z int32) *Biome
+	// Climate returns temperature and rainfall of the column at given coordinates, both in range [0, 1]
+	Climate(x, z int32) (temperature, rainfall float64)
+}
+
+// fixedBiomeSource is used by worlds whose generator has no biomes
+type fixedBiomeSource struct {
+	biome *Biome
+}
+
+func newFixedBiomeSource(dimension Dimension) *fixedBiomeSource {
+	if dimension == Hell {
+		return &fixedBiomeSource{biome: HellBiome}
+	}
+	return &fixedBiomeSource{biome: Plains}
+}
+
+func (s *fixedBiomeSource) Biome(int32, int32) *Biome {
+	return s.biome
+}
+
+func (s *fixedBiomeSource) Climate(int32, int32) (temperature, rainfall float64) {
+	if s.biome == HellBiome {
+		return 1, 0
+	}
+	return 0.5, 0.5
+}
+
+// Biome returns biome of the column at given coordinates.
+func (w *World) Biome(x, z int32) *Biome {
+	return w.biomes.Biome(x, z)
+}
+
+// Climate returns temperature and rainfall of the column at given coordinates, both in range [0, 1].
+func (w *World) Climate(x, z int32) (temperature, rainfall float64) {
+	return w.biomes.Climate(x, z)
+}
+
+// RainingAt checks whether rain falls on the block at given coordinates. It does not rain in biomes
+// without precipitation, snowy biomes get snow instead and blocks below other blocks stay dry.
+func (w *World) RainingAt(x, y, z int32) (bool, error) {
+	if !w.weather.raining {
+		return false, nil
+	}
+	biome := w.Biome(x, z)
+	if biome.HasSnow() || !biome.HasRain() {
+		return false, nil
+	}
+	highest, err := w.HighestBlockY(x, z)
+	if err != nil {
+		return false, err
+	}
+	return y > highest, nil
+}
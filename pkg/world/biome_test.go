@@ -0,0 +1,33 @@
+package world
+
+import "testing"
+
+func TestBiomeForClimate(t *testing.T) {
+	tests := []struct {
+		temperature, rainfall float64
+		want                  *Biome
+	}{
+		{0.05, 0.9, Tundra},
+		{0, 0, Tundra},
+		// the rainfall is multiplied by the temperature before the biome is picked
+		{0.3, 0.1, Tundra},
+		{0.8, 0.1, Savanna},
+		{1, 0.1, Desert},
+		{0.6, 1, Swampland},
+		{0.4, 0.6, Taiga},
+		{0.8, 0.3, Shrubland},
+		{0.8, 0.6, Forest},
+		{1, 0.3, Plains},
+		{1, 0.6, SeasonalForest},
+		{1, 1, Rainforest},
+		// the climate is rounded down to 64 steps, 0.5 falls to the step below the temperature of Shrubland
+		{0.5, 0.5, Taiga},
+		{0.52, 0.5, Shrubland},
+	}
+	for _, test := range tests {
+		if got := BiomeForClimate(test.temperature, test.rainfall); got != test.want {
+			t.Errorf("biome of temperature %v and rainfall %v is %v, want %v", test.temperature, test.rainfall,
+				got, test.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package world
+
+import "github.com/Pesekjak/173go/pkg/prot"
+
+// maxMultiBlockChange is the number of blocks changed in a chunk during a tick up to which
+// the blocks are sent one by one, more changed blocks are sent with the whole chunk
+const maxMultiBlockChange = 64
+
+// queueBlockChange remembers the changed block, so it is sent to the players at the end of the tick.
+// Changes of chunks no player has loaded are not kept, the chunks are sent as they are when needed.
+func (w *World) queueBlockChange(block Block) error {
+	pos, cx, cy, cz := WorldToChunkLocal(block.Position().X, block.Position().Y, block.Position().Z)
+	chunk, ok := w.chunks[pos]
+	if !ok || len(chunk.viewers) == 0 {
+		return nil
+	}
+	if chunk.changed == nil {
+		chunk.changed = make(map[uint32]struct{})
+	}
+	chunk.changed[blockIndex(cx, cy, cz)] = struct{}{}
+	w.changedChunks[pos] = struct{}{}
+	return nil
+}
+
+// sendBlockChanges sends the blocks changed since the last tick to the players who have their chunks loaded.
+// A single block is sent alone, a few blocks of a chunk together and many blocks with the whole chunk.
+// The updated tile entities are sent after the blocks.
+func (w *World) sendBlockChanges() {
+	if len(w.changedChunks) == 0 {
+		return
+	}
+	receivers := make(map[int32]PlayerEntity)
+	for pos := range w.changedChunks {
+		chunk := w.chunks[pos]
+		packets, err := chunk.changesPackets()
+		clear(chunk.changed)
+		clear(chunk.changedTileEntities)
+		for id, player := range chunk.viewers {
+			if err != nil {
+				player.Disconnect(err)
+				continue
+			}
+			if err := writePackets(player, packets); err != nil {
+				player.Disconnect(err)
+				continue
+			}
+			receivers[id] = player
+		}
+	}
+	clear(w.changedChunks)
+
+	for _, player := range receivers {
+		if err := player.Connection().Flush(); err != nil {
+			player.Disconnect(err)
+		}
+	}
+}
+
+// writePackets writes the packets to the player's connection, the connection is not flushed
+func writePackets(player PlayerEntity, packets []prot.PacketOut) error {
+	for _, packet := range packets {
+		if err := player.Connection().WritePacket(packet, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changesPackets creates the packets with the changed blocks and tile entities of the chunk
+func (c *Chunk) changesPackets() ([]prot.PacketOut, error) {
+	var packets []prot.PacketOut
+	if len(c.changed) > 0 {
+		packet, err := c.changesPacket()
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, packet)
+		if _, whole := packet.(*prot.PacketOutMapChunk); whole {
+			// the client forgets the tile entities of the chunk sent again
+			return append(packets, c.tileEntityPackets()...), nil
+		}
+	}
+	for pos := range c.changedTileEntities {
+		if updating, ok := c.tileEntities[pos].(updatingTileEntity); ok {
+			packets = append(packets, updating.updatePacket(pos))
+		}
+	}
+	return packets, nil
+}
+
+// changesPacket creates the packet with the changed blocks of the chunk
+func (c *Chunk) changesPacket() (prot.PacketOut, error) {
+	if len(c.changed) > maxMultiBlockChange {
+		return c.dataPacket()
+	}
+
+	packet := &prot.PacketOutMultiBlockChange{
+		ChunkX:      c.pos.X,
+		ChunkZ:      c.pos.Z,
+		Coordinates: make([]int16, 0, len(c.changed)),
+		Types:       make([]byte, 0, len(c.changed)),
+		Metadata:    make([]byte, 0, len(c.changed)),
+	}
+	for index := range c.changed {
+		x, y, z := blockPos(index)
+		block, err := c.GetBlock(x, y, z)
+		if err != nil {
+			return nil, err
+		}
+		if len(c.changed) == 1 {
+			return &prot.PacketOutBlockChange{
+				X:        block.Position().X,
+				Y:        byte(y),
+				Z:        block.Position().Z,
+				Type:     c.blockTypes[index],
+				Metadata: block.Data(),
+			}, nil
+		}
+		packet.Coordinates = append(packet.Coordinates, int16(x<<12|z<<8|y))
+		packet.Types = append(packet.Types, c.blockTypes[index])
+		packet.Metadata = append(packet.Metadata, block.Data())
+	}
+	return packet, nil
+}
@@ -5,6 +5,8 @@ import (
 	"compress/zlib"
 	"fmt"
 
+	"github.com/Pesekjak/173go/pkg/nbt"
+	"github.com/Pesekjak/173go/pkg/prot"
 	"github.com/Pesekjak/173go/pkg/world/material"
 )
 
@@ -19,84 +21,102 @@ type Chunk struct {
 
 	valid bool
 
-	blocks []Block
-
 	blockTypes    []byte
 	blockMetadata []byte
 	blockLight    *light
 	skyLight      *light
+	// heights are the lowest Y of each column above which the sky light is not blocked, indexed by z<<4 | x
+	heights []byte
 
 	cache     []byte
 	updater   func(block Block) error
 	generated bool
+	populated bool
+	// dirty is set when the chunk was modified since it was last saved
+	dirty bool
+
+	// viewers are the players the chunk has been sent to, by their entity ids
+	viewers map[int32]PlayerEntity
+	// changed are indices of the blocks changed since the changes were last sent to the viewers
+	changed map[uint32]struct{}
+	// items are the dropped items lying in the chunk
+	items map[int32]*ItemEntity
+	// tileEntities are the states of the blocks in the chunk their type and data can not hold
+	tileEntities map[BlockPos]TileEntity
+	// changedTileEntities are the tile entities to be sent to the viewers with the changed blocks
+	changedTileEntities map[BlockPos]struct{}
+
+	// entities and unknown tile entities loaded from the disk, kept until they are supported
+	entitiesNBT     nbt.List
+	tileEntitiesNBT nbt.List
 }
 
-func newChunk(world *World, pos ChunkPos, updater func(block Block) error) (*Chunk, error) {
+func newChunk(world *World, pos ChunkPos, updater func(block Block) error) *Chunk {
 	bCount := ChunkSize * ChunkHeight * ChunkSize
-	c := &Chunk{
+	return &Chunk{
 		world: world,
 		pos:   pos,
 
 		valid: true,
 
-		blocks: make([]Block, bCount),
-
 		blockTypes:    make([]byte, bCount),
 		blockMetadata: make([]byte, bCount/2),
 		blockLight:    newLight(),
 		skyLight:      newLight(),
+		heights:       make([]byte, ChunkSize*ChunkSize),
 
 		cache:     nil,
 		updater:   updater,
 		generated: false,
-	}
 
-	for i := uint32(0); i < bCount; i++ {
-		x, y, z := blockPos(i)
-		mat, err := material.FromID(uint16(c.blockTypes[i]))
-		if err != nil || mat.(*material.Block) == nil {
-			return nil, fmt.Errorf("failed to create block from ID %v", c.blockTypes[i])
-		}
-		data := c.blockMetadata[i/2]
-		if i%2 == 0 {
-			data = data & 0x0F
-		} else {
-			data = (data & 0xF0) >> 4
-		}
-		block, err := c.GetBlock(x, y, z)
-		if err != nil {
-			return nil, err
-		}
-		err = block.Set(mat.(*material.Block), data)
-		if err != nil {
-			return nil, err
-		}
+		viewers: make(map[int32]PlayerEntity),
 	}
-
-	return c, nil
 }
 
 func (c *Chunk) Pos() ChunkPos {
 	return c.pos
 }
 
+// BlockIDs returns the block IDs of the chunk, indexed by x<<11 | z<<7 | y. It is meant for generators
+// filling whole chunks at once, changes made through it bypass lighting and block updates.
+func (c *Chunk) BlockIDs() []byte {
+	return c.blockTypes
+}
+
 func (c *Chunk) GetBlock(x, y, z uint32) (Block, error) {
 	if _, err := inChunkBounds(x, y, z); err != nil {
 		return nil, err
 	}
-	i := blockIndex(x, y, z)
-	if block := c.blocks[i]; block != nil {
-		return block, nil
-	} else {
-		block = &chunkBlock{
-			owner:    c,
-			pos:      NewBlockPos(c.Pos().X*16+int32(x), int32(y), c.Pos().Z*16+int32(z)),
-			material: material.Air,
-			data:     0,
-		}
-		c.blocks[i] = block
-		return block, nil
+	return &chunkBlock{
+		owner: c,
+		pos:   NewBlockPos(c.Pos().X*16+int32(x), int32(y), c.Pos().Z*16+int32(z)),
+		index: blockIndex(x, y, z),
+	}, nil
+}
+
+// blockMaterial returns the block at given chunk coordinates, which must be within the chunk
+func (c *Chunk) blockMaterial(x, y, z uint32) *material.Block {
+	if block, ok := material.BlockFromID(c.blockTypes[blockIndex(x, y, z)]); ok {
+		return block
 	}
+	return material.Air
+}
+
+// dataPacket creates the packet with the whole chunk
+func (c *Chunk) dataPacket() (*prot.PacketOutMapChunk, error) {
+	data, err := c.data()
+	if err != nil {
+		return nil, err
+	}
+	return &prot.PacketOutMapChunk{
+		X:     c.pos.X * 16,
+		Y:     0,
+		Z:     c.pos.Z * 16,
+		SizeX: byte(ChunkSize) - 1,
+		SizeY: byte(ChunkHeight) - 1,
+		SizeZ: byte(ChunkSize) - 1,
+		Data:  data,
+	}, nil
 }
 
 func (c *Chunk) data() ([]byte, error) {
@@ -122,11 +142,11 @@ func (c *Chunk) data() ([]byte, error) {
 	return c.cache, nil
 }
 
+// chunkBlock is a view of a single block in a chunk, its state is read from the chunk data
 type chunkBlock struct {
-	owner    *Chunk
-	pos      BlockPos
-	material *material.Block
-	data     byte
+	owner *Chunk
+	pos   BlockPos
+	index uint32
 }
 
 func (b *chunkBlock) Position() BlockPos {
@@ -134,23 +154,26 @@ func (b *chunkBlock) Position() BlockPos {
 }
 
 func (b *chunkBlock) Material() *material.Block {
-	return b.material
+	if block, ok := material.BlockFromID(b.owner.blockTypes[b.index]); ok {
+		return block
+	}
+	return material.Air
 }
 
 func (b *chunkBlock) Data() byte {
-	return b.data
+	data := b.owner.blockMetadata[b.index/2]
+	if b.index%2 == 0 {
+		return data & 0x0F
+	}
+	return data >> 4
 }
 
 func (b *chunkBlock) Set(block *material.Block, data byte) error {
-	b.material = block
-	b.data = data
 	b.owner.cache = nil // invalidate cached chunk data
+	b.owner.dirty = true
+	b.owner.replaceTileEntity(b.pos, b.Material(), block)
 
-	chunkX := uint32(b.pos.X) % ChunkSize
-	chunkY := uint32(b.pos.Y)
-	chunkZ := uint32(b.pos.Z) % ChunkSize
-	index := blockIndex(chunkX, chunkY, chunkZ)
-
+	index := b.index
 	b.owner.blockTypes[index] = byte(block.Id())
 
 	metaIndex := index / 2
@@ -160,16 +183,37 @@ func (b *chunkBlock) Set(block *material.Block, data byte) error {
 		b.owner.blockMetadata[metaIndex] = (b.owner.blockMetadata[metaIndex] & 0x0F) | ((data & 0x0F) << 4)
 	}
 
-	if emission := block.LightEmission(); emission != 0 {
-		if err := propagateLight(b.owner.world, b.pos.X, b.pos.Y, b.pos.Z, emission); err != nil {
-			return err
+	if !b.owner.generated {
+		return nil // chunk is not yet generated, it is lit and sent when it is
+	}
+	b.owner.world.light.markChanged(b.pos)
+	return b.owner.updater(b)
+}
+
+// height returns the lowest Y of the column above which the sky light is not blocked
+func (c *Chunk) height(x, z uint32) uint32 {
+	return uint32(c.heights[z<<4|x])
+}
+
+// computeHeight finds the lowest Y of the column above which all blocks let the sky light through
+func (c *Chunk) computeHeight(x, z uint32) uint32 {
+	y := ChunkHeight - 1
+	for ; y > 0; y-- {
+		block, ok := material.BlockFromID(c.blockTypes[blockIndex(x, y-1, z)])
+		if ok && block.LightOpacity() != 0 {
+			break
 		}
 	}
+	return y
+}
 
-	if !b.owner.generated {
-		return nil // chunk is not yet generated, no need to send block updates
+// updateHeightMap computes the heights of all columns, used after the blocks were set without lighting
+func (c *Chunk) updateHeightMap() {
+	for x := uint32(0); x < ChunkSize; x++ {
+		for z := uint32(0); z < ChunkSize; z++ {
+			c.heights[z<<4|x] = byte(c.computeHeight(x, z))
+		}
 	}
-	return b.owner.updater(b)
 }
 
 func blockIndex(x, y, z uint32) uint32 {
@@ -0,0 +1,156 @@
+package world
+
+import (
+	"fmt"
+
+	"github.com/Pesekjak/173go/pkg/nbt"
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// ContainerSize returns the number of the slots of the container block, 0 for the blocks without an inventory.
+func ContainerSize(block *material.Block) int {
+	switch block {
+	case material.Chest:
+		return 27
+	case material.Dispenser:
+		return 9
+	case material.Furnace, material.FurnaceLit:
+		return 3
+	}
+	return 0
+}
+
+// container is the part of the tile entities holding items, its items are dropped when the block is broken
+type container struct {
+	Inventory *inventory.Inventory
+}
+
+func newContainer(size int) container {
+	return container{Inventory: inventory.NewInventory(size)}
+}
+
+func (c *container) ReadNBT(data nbt.Compound) {
+	if items, ok := data.List("Items"); ok {
+		readItemsNBT(items, c.Inventory)
+	}
+}
+
+func (c *container) WriteNBT(data nbt.Compound) {
+	data["Items"] = writeItemsNBT(c.Inventory)
+}
+
+func (c *container) drops() []inventory.ItemStack {
+	var items []inventory.ItemStack
+	for i := 0; i < c.Inventory.Size(); i++ {
+		if stack := c.Inventory.Slot(i); !stack.IsEmpty() {
+			items = append(items, stack)
+		}
+	}
+	return items
+}
+
+// Chest is the tile entity of a chest, each half of a large chest holds its own items.
+type Chest struct {
+	container
+}
+
+func newChest() *Chest {
+	return &Chest{container: newContainer(ContainerSize(material.Chest))}
+}
+
+// Dispenser is the tile entity of a dispenser.
+type Dispenser struct {
+	container
+}
+
+func newDispenser() *Dispenser {
+	return &Dispenser{container: newContainer(ContainerSize(material.Dispenser))}
+}
+
+// Container returns the inventory of the container block at the position, like a chest, a furnace
+// or a dispenser. The inventory is shared by all players.
+func (w *World) Container(pos BlockPos) (*inventory.Inventory, error) {
+	tileEntity, err := w.TileEntity(pos)
+	if err != nil {
+		return nil, err
+	}
+	switch tileEntity := tileEntity.(type) {
+	case *Chest:
+		return tileEntity.Inventory, nil
+	case *Dispenser:
+		return tileEntity.Inventory, nil
+	case *Furnace:
+		return tileEntity.Inventory, nil
+	}
+	return nil, fmt.Errorf("the block at %v is not a container", pos)
+}
+
+// ChestHalves returns the positions of the chest and of the chest next to it, if there is one, in the order
+// their slots are shown in the window. It returns no positions if the chest can not be opened,
+// because there is a solid block above one of its halves.
+func (w *World) ChestHalves(pos BlockPos) ([]BlockPos, error) {
+	halves := []BlockPos{pos}
+	for _, side := range [...]BlockPos{pos.West(1), pos.East(1), pos.North(1), pos.South(1)} {
+		block, _, err := w.blockAt(side)
+		if err != nil {
+			return nil, err
+		}
+		if block != material.Chest {
+			continue
+		}
+		// the western and the northern half come first
+		if side.X < pos.X || side.Z < pos.Z {
+			halves = []BlockPos{side, pos}
+		} else {
+			halves = []BlockPos{pos, side}
+		}
+		break
+	}
+
+	for _, half := range halves {
+		blocked, err := w.isNormalCube(half.Up(1))
+		if err != nil || blocked {
+			return nil, err
+		}
+	}
+	return halves, nil
+}
+
+// readItemsNBT puts the items of a tile entity into the inventory, the items of unknown materials
+// or in invalid slots are skipped
+func readItemsNBT(items nbt.List, inv *inventory.Inventory) {
+	for _, value := range items.Values {
+		item, ok := value.(nbt.Compound)
+		if !ok {
+			continue
+		}
+		slot, _ := item.Byte("Slot")
+		id, _ := item.Short("id")
+		count, _ := item.Byte("Count")
+		damage, _ := item.Short("Damage")
+		m, err := material.FromID(uint16(id))
+		if err != nil || slot < 0 || int(slot) >= inv.Size() {
+			continue
+		}
+		inv.SetSlot(int(slot), inventory.NewItemStack(m, byte(count), uint16(damage)))
+	}
+}
+
+// writeItemsNBT creates the list of the items of a tile entity, the empty slots are left out
+func writeItemsNBT(inv *inventory.Inventory) nbt.List {
+	items := nbt.NewList(nbt.TagCompound)
+	for slot := 0; slot < inv.Size(); slot++ {
+		stack := inv.Slot(slot)
+		if stack.IsEmpty() {
+			continue
+		}
+		items.Values = append(items.Values, nbt.Compound{
+			"Slot":   int8(slot),
+			"id":     int16(stack.Material.Id()),
+			"Count":  int8(stack.Count),
+			"Damage": int16(stack.Data),
+		})
+	}
+	return items
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/Pesekjak/173go/pkg/net"
 	"github.com/Pesekjak/173go/pkg/world/entity_data"
+	"github.com/Pesekjak/173go/pkg/world/inventory"
 )
 
 type EntityType byte
@@ -118,6 +119,7 @@ type PlayerEntity interface {
 	MobEntity
 	Username() string
 	IsOnline() bool
+	Inventory() *inventory.PlayerInventory
 	Connection() *net.Connection
 	Disconnect(error)
 	Kick(string)
@@ -1,8 +1,38 @@
 package world
 
+import "fmt"
+
 type Dimension byte
 
 const (
 	Overworld Dimension = 0
 	Hell      Dimension = 255
 )
+
+// ParseDimension returns the dimension with given name, "overworld" or "nether".
+func ParseDimension(name string) (Dimension, error) {
+	switch name {
+	case "overworld":
+		return Overworld, nil
+	case "nether":
+		return Hell, nil
+	default:
+		return 0, fmt.Errorf("unknown dimension: '%v'", name)
+	}
+}
+
+func (d Dimension) String() string {
+	switch d {
+	case Overworld:
+		return "overworld"
+	case Hell:
+		return "nether"
+	default:
+		return fmt.Sprintf("Dimension(%d)", byte(d))
+	}
+}
+
+// HasSkyLight reports whether the sky lights the dimension, the sky of the nether gives no light.
+func (d Dimension) HasSkyLight() bool {
+	return d != Hell
+}
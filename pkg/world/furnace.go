@@ -0,0 +1,158 @@
+package world
+
+import (
+	"fmt"
+
+	"github.com/Pesekjak/173go/pkg/nbt"
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+	"github.com/Pesekjak/173go/pkg/world/recipe"
+)
+
+// smeltTime is the number of ticks it takes to smelt an item
+const smeltTime = 200
+
+// Furnace is the tile entity of a furnace, its items and the progress of smelting.
+type Furnace struct {
+	container
+	// burnTime is the number of ticks the current fuel burns for yet, fuelTime is the burn time of the whole fuel
+	burnTime int
+	fuelTime int
+	// cookTime is the number of ticks the ingredient has been smelted for
+	cookTime int
+}
+
+func newFurnace() *Furnace {
+	return &Furnace{container: newContainer(ContainerSize(material.Furnace))}
+}
+
+// IsBurning returns true if the furnace burns fuel.
+func (f *Furnace) IsBurning() bool {
+	return f.burnTime > 0
+}
+
+// ProgressBars returns the values shown in the window of the furnace: the progress of smelting,
+// the remaining burn time and the burn time of the whole fuel.
+func (f *Furnace) ProgressBars() []int16 {
+	return []int16{int16(f.cookTime), int16(f.burnTime), int16(f.fuelTime)}
+}
+
+// tick burns the fuel and smelts the ingredient, the furnace block is lit while it burns fuel
+func (f *Furnace) tick(w *World, pos BlockPos) bool {
+	burning := f.IsBurning()
+	changed := f.burn()
+	if burning == f.IsBurning() {
+		return changed
+	}
+	// the furnace is in a loaded chunk, switching its block can not fail
+	_ = w.setFurnaceLit(pos, f.IsBurning())
+	return true
+}
+
+// burn burns the fuel and smelts the ingredient, as the Notchian furnace does. It reports whether
+// the items of the furnace changed.
+func (f *Furnace) burn() bool {
+	changed := false
+	if f.burnTime > 0 {
+		f.burnTime--
+	}
+
+	if f.burnTime == 0 && f.canSmelt() {
+		fuel := f.Inventory.Slot(inventory.FurnaceFuelSlot)
+		f.fuelTime = recipe.FuelTime(fuel.Material)
+		f.burnTime = f.fuelTime
+		if f.burnTime > 0 {
+			// the whole fuel is used up, even the buckets of lava
+			fuel.Split(1)
+			f.Inventory.SetSlot(inventory.FurnaceFuelSlot, fuel)
+			changed = true
+		}
+	}
+
+	if f.IsBurning() && f.canSmelt() {
+		f.cookTime++
+		if f.cookTime == smeltTime {
+			f.cookTime = 0
+			f.smelt()
+			changed = true
+		}
+	} else {
+		f.cookTime = 0
+	}
+	return changed
+}
+
+// canSmelt checks whether the ingredient can be smelted and its result fits to the result slot
+func (f *Furnace) canSmelt() bool {
+	ingredient := f.Inventory.Slot(inventory.FurnaceIngredientSlot)
+	if ingredient.IsEmpty() {
+		return false
+	}
+	result, ok := recipe.Smelt(ingredient.Material)
+	if !ok {
+		return false
+	}
+	current := f.Inventory.Slot(inventory.FurnaceResultSlot)
+	if current.IsEmpty() {
+		return true
+	}
+	return current.StacksWith(result) && current.Count < current.MaxStackSize()
+}
+
+// smelt turns an item of the ingredient into its result
+func (f *Furnace) smelt() {
+	ingredient := f.Inventory.Slot(inventory.FurnaceIngredientSlot)
+	result, _ := recipe.Smelt(ingredient.Material)
+	current := f.Inventory.Slot(inventory.FurnaceResultSlot)
+	if current.IsEmpty() {
+		current = result
+	} else {
+		current.Count += result.Count
+	}
+	f.Inventory.SetSlot(inventory.FurnaceResultSlot, current)
+	ingredient.Split(1)
+	f.Inventory.SetSlot(inventory.FurnaceIngredientSlot, ingredient)
+}
+
+// Furnace returns the state of the furnace block at the position.
+func (w *World) Furnace(pos BlockPos) (*Furnace, error) {
+	tileEntity, err := w.TileEntity(pos)
+	if err != nil {
+		return nil, err
+	}
+	if furnace, ok := tileEntity.(*Furnace); ok {
+		return furnace, nil
+	}
+	return nil, fmt.Errorf("the block at %v is not a furnace", pos)
+}
+
+// setFurnaceLit switches the furnace block between lit and unlit, it keeps facing the same direction
+func (w *World) setFurnaceLit(pos BlockPos, lit bool) error {
+	block, err := w.GetBlock(pos.X, pos.Y, pos.Z)
+	if err != nil {
+		return err
+	}
+	if block.Material() != material.Furnace && block.Material() != material.FurnaceLit {
+		return nil
+	}
+	if lit {
+		return block.Set(material.FurnaceLit, block.Data())
+	}
+	return block.Set(material.Furnace, block.Data())
+}
+
+func (f *Furnace) ReadNBT(data nbt.Compound) {
+	f.container.ReadNBT(data)
+	burnTime, _ := data.Short("BurnTime")
+	cookTime, _ := data.Short("CookTime")
+	f.burnTime = int(burnTime)
+	f.cookTime = int(cookTime)
+	// the burn time of the whole fuel is not stored, the Notchian server takes the fuel that is left
+	f.fuelTime = recipe.FuelTime(f.Inventory.Slot(inventory.FurnaceFuelSlot).Material)
+}
+
+func (f *Furnace) WriteNBT(data nbt.Compound) {
+	f.container.WriteNBT(data)
+	data["BurnTime"] = int16(f.burnTime)
+	data["CookTime"] = int16(f.cookTime)
+}
@@ -0,0 +1,99 @@
+package world
+
+import (
+	"testing"
+
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+func TestFurnaceTick(t *testing.T) {
+	w := newTestWorld(t)
+	pos := NewBlockPos(3, 5, 3)
+	if err := w.setBlock(pos.X, pos.Y, pos.Z, material.Furnace, 4); err != nil {
+		t.Fatal(err)
+	}
+	furnace, err := w.Furnace(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the planks burn for 300 ticks, enough to smelt one of the cobblestones
+	furnace.Inventory.SetSlot(inventory.FurnaceIngredientSlot, inventory.NewItemStack(material.Cobblestone, 3, 0))
+	furnace.Inventory.SetSlot(inventory.FurnaceFuelSlot, inventory.NewItemStack(material.WoodenPlanks, 1, 0))
+
+	steps := []struct {
+		ticks              int
+		block              *material.Block
+		ingredient, result byte
+		bars               []int16
+	}{
+		// the fuel is used up in the first tick, the furnace block is lit
+		{1, material.FurnaceLit, 3, 0, []int16{1, 300, 300}},
+		{199, material.FurnaceLit, 3, 0, []int16{199, 102, 300}},
+		// the first cobblestone is smelted after 200 ticks
+		{200, material.FurnaceLit, 2, 1, []int16{0, 101, 300}},
+		{300, material.FurnaceLit, 2, 1, []int16{100, 1, 300}},
+		// the fuel burns out, the progress of smelting is lost and the block goes out
+		{301, material.Furnace, 2, 1, []int16{0, 0, 0}},
+		{600, material.Furnace, 2, 1, []int16{0, 0, 0}},
+	}
+	ticked := 0
+	for _, step := range steps {
+		for ; ticked < step.ticks; ticked++ {
+			w.Tick()
+		}
+		block, data, err := w.blockAt(pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if block != step.block || data != 4 {
+			t.Errorf("after %v ticks the block is %v:%v, want %v:4", step.ticks, block, data, step.block)
+		}
+		if current, err := w.Furnace(pos); err != nil || current != furnace {
+			t.Fatalf("after %v ticks the furnace is %p, error %v, want the same furnace", step.ticks, current, err)
+		}
+		ingredient := furnace.Inventory.Slot(inventory.FurnaceIngredientSlot)
+		result := furnace.Inventory.Slot(inventory.FurnaceResultSlot)
+		fuel := furnace.Inventory.Slot(inventory.FurnaceFuelSlot)
+		if ingredient.Count != step.ingredient || result.Count != step.result || !fuel.IsEmpty() {
+			t.Errorf("after %v ticks the furnace has %v, %v and %v, want %v cobblestone and %v stone", step.ticks,
+				ingredient, fuel, result, step.ingredient, step.result)
+		}
+		if result.Count > 0 && result.Material != material.Stone {
+			t.Errorf("after %v ticks the result is %v, want stone", step.ticks, result)
+		}
+		bars := furnace.ProgressBars()
+		for i := range bars {
+			if bars[i] != step.bars[i] {
+				t.Errorf("after %v ticks the progress bars are %v, want %v", step.ticks, bars, step.bars)
+				break
+			}
+		}
+	}
+}
+
+func TestFurnaceFull(t *testing.T) {
+	w := newTestWorld(t)
+	pos := NewBlockPos(3, 5, 3)
+	if err := w.setBlock(pos.X, pos.Y, pos.Z, material.Furnace, 2); err != nil {
+		t.Fatal(err)
+	}
+	furnace, err := w.Furnace(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the result slot is full, the fuel is not used
+	furnace.Inventory.SetSlot(inventory.FurnaceIngredientSlot, inventory.NewItemStack(material.IronOre, 1, 0))
+	furnace.Inventory.SetSlot(inventory.FurnaceFuelSlot, inventory.NewItemStack(material.Coal, 1, 0))
+	furnace.Inventory.SetSlot(inventory.FurnaceResultSlot, inventory.NewItemStack(material.IronIngot, 64, 0))
+	for range 10 {
+		w.Tick()
+	}
+	if furnace.IsBurning() || furnace.Inventory.Slot(inventory.FurnaceFuelSlot).Count != 1 {
+		t.Errorf("furnace with a full result burns %v and has %v", furnace.IsBurning(),
+			furnace.Inventory.Slot(inventory.FurnaceFuelSlot))
+	}
+	if block, _, _ := w.blockAt(pos); block != material.Furnace {
+		t.Errorf("furnace with a full result is %v, want %v", block, material.Furnace)
+	}
+}
@@ -10,6 +10,23 @@ type Generator interface {
 	GenerateBlocks(chunk *Chunk) error
 }
 
+// GeneratorFactory creates a generator for a world with given seed
+type GeneratorFactory func(seed int64) (Generator, error)
+
+// SpawnChecker is implemented by generators that choose where the spawn of a new world can be
+type SpawnChecker interface {
+	// CanSpawnAt checks whether the spawn can be in the column at given coordinates
+	CanSpawnAt(world *World, x, z int32) (bool, error)
+}
+
+// Populator is implemented by generators that decorate the terrain once the chunks around it exist.
+// Chunk at given position is populated when the chunks at +1 in both axes are loaded as well,
+// as the population spans from the middle of the chunk into them.
+type Populator interface {
+	// Populate decorates the terrain of the chunk at given position and its +1/+1 neighbours
+	Populate(world *World, pos ChunkPos) error
+}
+
 type FlatGenerator struct {
 	Layers []struct {
 		Material *material.Block
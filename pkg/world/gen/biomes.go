@@ -0,0 +1,73 @@
+package gen
+
+import "github.com/Pesekjak/173go/pkg/world"
+
+// biomeSource computes the temperature and rainfall of columns and picks their biomes
+// the same way as the Notchian server
+type biomeSource struct {
+	temperatureNoise *octaveSimplexNoise
+	rainfallNoise    *octaveSimplexNoise
+	variationNoise   *octaveSimplexNoise
+
+	variation []float64
+}
+
+func newBiomeSource(seed int64) *biomeSource {
+	return &biomeSource{
+		temperatureNoise: newOctaveSimplexNoise(NewRandom(seed*9871), 4),
+		rainfallNoise:    newOctaveSimplexNoise(NewRandom(seed*39811), 4),
+		variationNoise:   newOctaveSimplexNoise(NewRandom(seed*543321), 2),
+	}
+}
+
+// generate fills biomes, temperature and rainfall of a region of columns, indexed by x*sizeZ + z.
+// The slices are reused if they have enough capacity.
+func (s *biomeSource) generate(biomes []*world.Biome, temperature, rainfall []float64, x, z int32, sizeX, sizeZ int) (
+	[]*world.Biome, []float64, []float64) {
+	// the scales are float constants in the Notchian implementation
+	temperature = s.temperatureNoise.generate(temperature, x, z, sizeX, sizeZ,
+		float64(float32(0.025)), float64(float32(0.025)), 0.25, 0.5)
+	rainfall = s.rainfallNoise.generate(rainfall, x, z, sizeX, sizeZ,
+		float64(float32(0.05)), float64(float32(0.05)), 1.0/3.0, 0.5)
+	s.variation = s.variationNoise.generate(s.variation, x, z, sizeX, sizeZ, 0.25, 0.25, 0.5882352941176471, 0.5)
+
+	// the weights are computed at runtime in the Notchian implementation, 1-0.01 is not exactly 0.99
+	temperatureWeight, rainfallWeight := 0.01, 0.002
+	for i := range temperature {
+		variation := float64(s.variation[i]*1.1) + 0.5
+		t := float64((float64(temperature[i]*0.15)+0.7)*(1-temperatureWeight)) + float64(variation*temperatureWeight)
+		r := float64((float64(rainfall[i]*0.15)+0.5)*(1-rainfallWeight)) + float64(variation*rainfallWeight)
+		t = 1 - float64((1-t)*(1-t))
+		temperature[i] = clamp(t, 0, 1)
+		rainfall[i] = clamp(r, 0, 1)
+	}
+
+	if cap(biomes) < len(temperature) {
+		biomes = make([]*world.Biome, len(temperature))
+	}
+	biomes = biomes[:len(temperature)]
+	for i := range biomes {
+		biomes[i] = world.BiomeForClimate(temperature[i], rainfall[i])
+	}
+	return biomes, temperature, rainfall
+}
+
+func (s *biomeSource) Biome(x, z int32) *world.Biome {
+	biomes, _, _ := s.generate(nil, nil, nil, x, z, 1, 1)
+	return biomes[0]
+}
+
+func (s *biomeSource) Climate(x, z int32) (temperature, rainfall float64) {
+	_, temperatures, rainfalls := s.generate(nil, nil, nil, x, z, 1, 1)
+	return temperatures[0], rainfalls[0]
+}
+
+func clamp(v, min, max float64) float64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,254 @@
+package gen
+
+import (
+	"math"
+
+	"github.com/Pesekjak/173go/pkg/world"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// carverRange is the distance in chunks from which a cave system can reach a chunk
+const carverRange = 8
+
+// forEachCarverOrigin calls the function for each chunk within the carver range, with the random
+// seeded for that chunk. Cave systems starting in the neighbouring chunks are traced again for every
+// chunk they can reach, so carving never depends on other chunks being generated.
+func forEachCarverOrigin(seed int64, random *Random, pos world.ChunkPos, carve func(originX, originZ int32)) {
+	random.SetSeed(seed)
+	xFactor := random.NextLong()/2*2 + 1
+	zFactor := random.NextLong()/2*2 + 1
+	for x := pos.X - carverRange; x <= pos.X+carverRange; x++ {
+		for z := pos.Z - carverRange; z <= pos.Z+carverRange; z++ {
+			random.SetSeed((int64(x)*xFactor + int64(z)*zFactor) ^ seed)
+			carve(x, z)
+		}
+	}
+}
+
+// CaveCarver carves the tunnels and rooms of the Notchian overworld or nether caves.
+type CaveCarver struct {
+	seed   int64
+	random *Random
+	// nether makes the caves wider and flatter, carve netherrack and avoid lava instead of water
+	nether bool
+}
+
+// NewCaveCarver creates cave carver for an overworld with given seed.
+func NewCaveCarver(seed int64) *CaveCarver {
+	return &CaveCarver{seed: seed, random: NewRandom(0)}
+}
+
+// NewNetherCaveCarver creates cave carver for a nether with given seed.
+func NewNetherCaveCarver(seed int64) *CaveCarver {
+	return &CaveCarver{seed: seed, random: NewRandom(0), nether: true}
+}
+
+func (c *CaveCarver) Generate(pos world.ChunkPos, blocks []byte) error {
+	forEachCarverOrigin(c.seed, c.random, pos, func(originX, originZ int32) {
+		c.carveFrom(originX, originZ, pos, blocks)
+	})
+	return nil
+}
+
+// carveFrom carves the cave systems starting in the origin chunk into the chunk
+func (c *CaveCarver) carveFrom(originX, originZ int32, pos world.ChunkPos, blocks []byte) {
+	r := c.random
+	var count int32
+	if c.nether {
+		count = r.NextIntn(r.NextIntn(r.NextIntn(10)+1) + 1)
+		if r.NextIntn(5) != 0 {
+			count = 0
+		}
+	} else {
+		count = r.NextIntn(r.NextIntn(r.NextIntn(40)+1) + 1)
+		if r.NextIntn(15) != 0 {
+			count = 0
+		}
+	}
+
+	for i := int32(0); i < count; i++ {
+		x := float64(originX*16 + r.NextIntn(16))
+		var y float64
+		if c.nether {
+			y = float64(r.NextIntn(128))
+		} else {
+			y = float64(r.NextIntn(r.NextIntn(120) + 8))
+		}
+		z := float64(originZ*16 + r.NextIntn(16))
+
+		tunnels := int32(1)
+		if r.NextIntn(4) == 0 {
+			c.carveRoom(pos, blocks, x, y, z)
+			tunnels += r.NextIntn(4)
+		}
+
+		for j := int32(0); j < tunnels; j++ {
+			yaw := float32(r.NextFloat()*math.Pi) * 2
+			pitch := float32((r.NextFloat()-0.5)*2) / 8
+			width := float32(r.NextFloat()*2) + r.NextFloat()
+			heightRatio := 1.0
+			// nether tunnels are twice as wide and half as high
+			if c.nether {
+				width *= 2
+				heightRatio = 0.5
+			}
+			c.carveTunnel(pos, blocks, x, y, z, width, yaw, pitch, 0, 0, heightRatio)
+		}
+	}
+}
+
+// carveRoom carves a large round room, a tunnel that does not move
+func (c *CaveCarver) carveRoom(pos world.ChunkPos, blocks []byte, x, y, z float64) {
+	c.carveTunnel(pos, blocks, x, y, z, 1+float32(c.random.NextFloat()*6), 0, 0, -1, -1, 0.5)
+}
+
+// carveTunnel carves a winding tunnel into the chunk. Tunnels can split in two, the step of -1 makes it a room.
+func (c *CaveCarver) carveTunnel(pos world.ChunkPos, blocks []byte, x, y, z float64, width, yaw, pitch float32,
+	step, length int32, heightRatio float64) {
+	centerX := float64(pos.X*16 + 8)
+	centerZ := float64(pos.Z*16 + 8)
+	var yawChange, pitchChange float32
+	random := NewRandom(c.random.NextLong())
+
+	if length <= 0 {
+		maxLength := int32(carverRange*16 - 16)
+		length = maxLength - random.NextIntn(maxLength/4)
+	}
+	room := false
+	if step == -1 {
+		step = length / 2
+		room = true
+	}
+
+	splitStep := random.NextIntn(length/2) + length/4
+	steep := random.NextIntn(6) == 0
+	for ; step < length; step++ {
+		radius := 1.5 + float64(sin(float32(step)*math.Pi/float32(length))*width*1)
+		radiusY := float64(radius * heightRatio)
+
+		cosPitch := cos(pitch)
+		sinPitch := sin(pitch)
+		x += float64(cos(yaw) * cosPitch)
+		y += float64(sinPitch)
+		z += float64(sin(yaw) * cosPitch)
+
+		if steep {
+			pitch = float32(pitch * 0.92)
+		} else {
+			pitch = float32(pitch * 0.7)
+		}
+		pitch += float32(pitchChange * 0.1)
+		yaw += float32(yawChange * 0.1)
+		pitchChange = float32(pitchChange * 0.9)
+		yawChange = float32(yawChange * 0.75)
+		pitchChange += float32(float32((random.NextFloat()-random.NextFloat())*random.NextFloat()) * 2)
+		yawChange += float32(float32((random.NextFloat()-random.NextFloat())*random.NextFloat()) * 4)
+
+		if !room && step == splitStep && width > 1 {
+			c.carveTunnel(pos, blocks, x, y, z, float32(random.NextFloat()*0.5)+0.5, yaw-math.Pi/2, pitch/3, step, length, 1)
+			c.carveTunnel(pos, blocks, x, y, z, float32(random.NextFloat()*0.5)+0.5, yaw+math.Pi/2, pitch/3, step, length, 1)
+			return
+		}
+
+		if !room && random.NextIntn(4) == 0 {
+			continue
+		}
+
+		// stop when the rest of the tunnel can not reach the chunk anymore
+		dx := x - centerX
+		dz := z - centerZ
+		remaining := float64(length - step)
+		maxDistance := float64(width + 2 + 16)
+		if float64(dx*dx)+float64(dz*dz)-float64(remaining*remaining) > maxDistance*maxDistance {
+			return
+		}
+
+		// the tunnel is too far from the chunk for now
+		margin := float64(radius * 2)
+		if x < centerX-16-margin || z < centerZ-16-margin || x > centerX+16+margin || z > centerZ+16+margin {
+			continue
+		}
+
+		if c.carveSphere(pos, blocks, x, y, z, radius, radiusY) && room {
+			break
+		}
+	}
+}
+
+// carveSphere carves an ellipsoid into the chunk unless it would reach water, or lava in the nether.
+// It reports whether the ellipsoid was carved.
+func (c *CaveCarver) carveSphere(pos world.ChunkPos, blocks []byte, x, y, z, radius, radiusY float64) bool {
+	minX := max(floor(x-radius)-int(pos.X)*16-1, 0)
+	maxX := min(floor(x+radius)-int(pos.X)*16+1, 16)
+	minY := max(floor(y-radiusY)-1, 1)
+	maxY := min(floor(y+radiusY)+1, 120)
+	minZ := max(floor(z-radius)-int(pos.Z)*16-1, 0)
+	maxZ := min(floor(z+radius)-int(pos.Z)*16+1, 16)
+
+	liquid := byte(material.WaterFlowing.Id())
+	stillLiquid := byte(material.WaterStill.Id())
+	if c.nether {
+		liquid = byte(material.LavaFlowing.Id())
+		stillLiquid = byte(material.LavaStill.Id())
+	}
+
+	// only the shell of the box is checked for liquids
+	for bx := minX; bx < maxX; bx++ {
+		for bz := minZ; bz < maxZ; bz++ {
+			for by := maxY + 1; by >= minY-1; by-- {
+				if by < 0 || by >= 128 {
+					continue
+				}
+				block := blocks[(bx*16+bz)*128+by]
+				if block == liquid || block == stillLiquid {
+					return false
+				}
+				if by != minY-1 && bx != minX && bx != maxX-1 && bz != minZ && bz != maxZ-1 {
+					by = minY
+				}
+			}
+		}
+	}
+
+	stone := byte(material.Stone.Id())
+	dirt := byte(material.Dirt.Id())
+	grass := byte(material.GrassBlock.Id())
+	lava := byte(material.LavaFlowing.Id())
+	if c.nether {
+		stone = byte(material.Netherrack.Id())
+	}
+
+	for bx := minX; bx < maxX; bx++ {
+		nx := (float64(bx+int(pos.X)*16) + 0.5 - x) / radius
+		for bz := minZ; bz < maxZ; bz++ {
+			nz := (float64(bz+int(pos.Z)*16) + 0.5 - z) / radius
+			if float64(nx*nx)+float64(nz*nz) >= 1 {
+				continue
+			}
+			index := (bx*16+bz)*128 + maxY
+			hitGrass := false
+			for by := maxY - 1; by >= minY; by-- {
+				ny := (float64(by) + 0.5 - y) / radiusY
+				if ny > -0.7 && float64(nx*nx)+float64(ny*ny)+float64(nz*nz) < 1 {
+					block := blocks[index]
+					if block == grass {
+						hitGrass = true
+					}
+					if block == stone || block == dirt || block == grass {
+						if by < 10 && !c.nether {
+							blocks[index] = lava
+						} else {
+							blocks[index] = 0
+							// keep the surface green when the cave opens below grass
+							if hitGrass && !c.nether && blocks[index-1] == dirt {
+								blocks[index-1] = grass
+							}
+						}
+					}
+				}
+				index--
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,206 @@
+package gen
+
+import (
+	"github.com/Pesekjak/173go/pkg/nbt"
+	"github.com/Pesekjak/173go/pkg/world"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// Area is the part of a world decorated during population of a chunk. It spans the populated chunk
+// and its neighbours at +1 in both axes, features placed by decorators are centered in the middle of it.
+//
+// Blocks outside the area can be accessed as well, loading their chunks like the Notchian server does.
+// Failures are remembered and reported once the decorator finishes.
+type Area struct {
+	// X and Z are the block coordinates of the corner of the populated chunk
+	X, Z int32
+	// Random is the random shared by all decorators of the population
+	Random *Random
+	// Biome is the biome in the middle of the area
+	Biome *world.Biome
+
+	world  *world.World
+	chunks [2][2]*world.Chunk
+	err    error
+}
+
+func newArea(w *world.World, pos world.ChunkPos, random *Random, biome *world.Biome) *Area {
+	a := &Area{
+		X:      pos.X * 16,
+		Z:      pos.Z * 16,
+		Random: random,
+		Biome:  biome,
+		world:  w,
+	}
+	for x := int32(0); x < 2; x++ {
+		for z := int32(0); z < 2; z++ {
+			a.chunks[x][z], _ = w.Chunk(world.NewChunkPos(pos.X+x, pos.Z+z))
+		}
+	}
+	return a
+}
+
+// World returns the world being populated.
+func (a *Area) World() *world.World {
+	return a.world
+}
+
+// chunk returns the chunk of the area containing the column, or nil if the column is outside the area
+func (a *Area) chunk(x, z int32) *world.Chunk {
+	cx := (x >> 4) - (a.X >> 4)
+	cz := (z >> 4) - (a.Z >> 4)
+	if cx < 0 || cx > 1 || cz < 0 || cz > 1 {
+		return nil
+	}
+	return a.chunks[cx][cz]
+}
+
+func (a *Area) fail(err error) {
+	if a.err == nil {
+		a.err = err
+	}
+}
+
+// BlockID returns ID of the block at given coordinates. Blocks above and below the world are air.
+func (a *Area) BlockID(x, y, z int32) byte {
+	if y < 0 || y >= int32(world.ChunkHeight) {
+		return 0
+	}
+	if chunk := a.chunk(x, z); chunk != nil {
+		return chunk.BlockIDs()[(x&15)<<11|(z&15)<<7|y]
+	}
+	block, err := a.world.GetBlock(x, y, z)
+	if err != nil {
+		a.fail(err)
+		return 0
+	}
+	return byte(block.Material().Id())
+}
+
+// Block returns the block at given coordinates.
+func (a *Area) Block(x, y, z int32) *material.Block {
+	if block, ok := material.BlockFromID(a.BlockID(x, y, z)); ok {
+		return block
+	}
+	return material.Air
+}
+
+// IsEmpty checks whether there is air at given coordinates.
+func (a *Area) IsEmpty(x, y, z int32) bool {
+	return a.BlockID(x, y, z) == 0
+}
+
+// SetBlock sets the block at given coordinates. Blocks above and below the world are ignored.
+func (a *Area) SetBlock(x, y, z int32, block *material.Block, data byte) {
+	if y < 0 || y >= int32(world.ChunkHeight) {
+		return
+	}
+	var target world.Block
+	var err error
+	if chunk := a.chunk(x, z); chunk != nil {
+		target, err = chunk.GetBlock(uint32(x&15), uint32(y), uint32(z&15))
+	} else {
+		target, err = a.world.GetBlock(x, y, z)
+	}
+	if err == nil {
+		err = target.Set(block, data)
+	}
+	if err != nil {
+		a.fail(err)
+	}
+}
+
+// SetTileEntityNBT stores tile entity data of the block at given coordinates.
+func (a *Area) SetTileEntityNBT(x, y, z int32, data nbt.Compound) {
+	chunk := a.chunk(x, z)
+	if chunk == nil {
+		var err error
+		if chunk, err = a.world.LoadChunk(world.NewChunkPos(x>>4, z>>4)); err != nil {
+			a.fail(err)
+			return
+		}
+	}
+	chunk.SetTileEntityNBT(world.NewBlockPos(x, y, z), data)
+}
+
+// Height returns the Y coordinate above the highest block of the column that blocks any light.
+func (a *Area) Height(x, z int32) int32 {
+	for y := int32(world.ChunkHeight) - 1; y >= 0; y-- {
+		if a.Block(x, y, z).LightOpacity() != 0 {
+			return y + 1
+		}
+	}
+	return 0
+}
+
+// TopSolidOrLiquid returns the Y coordinate above the highest solid or liquid block of the column,
+// or -1 if there is none.
+func (a *Area) TopSolidOrLiquid(x, z int32) int32 {
+	for y := int32(world.ChunkHeight) - 1; y > 0; y-- {
+		group := a.Block(x, y, z).Group
+		if group.IsSolid() || group.IsFluid() {
+			return y + 1
+		}
+	}
+	return -1
+}
+
+// CanSeeSky checks whether there is no block blocking the light above given coordinates.
+func (a *Area) CanSeeSky(x, y, z int32) bool {
+	return y >= a.Height(x, z)
+}
+
+// Decorator adds features to the terrain during population, like ores or trees.
+type Decorator interface {
+	Decorate(area *Area) error
+}
+
+// DecoratorFunc is a function used as a Decorator
+type DecoratorFunc func(area *Area) error
+
+func (f DecoratorFunc) Decorate(area *Area) error {
+	return f(area)
+}
+
+// Decoration is a populator running several decorators in order. The decorators share the random
+// of the area, seeded from the world seed and the chunk position the same way as the Notchian server does.
+type Decoration struct {
+	seed       int64
+	biomes     world.BiomeSource
+	decorators []Decorator
+}
+
+// NewDecoration creates populator of a world with given seed, running given decorators in order.
+// The biome of each area is picked by the biome source.
+func NewDecoration(seed int64, biomes world.BiomeSource, decorators ...Decorator) *Decoration {
+	return &Decoration{seed: seed, biomes: biomes, decorators: decorators}
+}
+
+// AddDecorator adds a decorator to the end of the decoration.
+func (d *Decoration) AddDecorator(decorator Decorator) {
+	d.decorators = append(d.decorators, decorator)
+}
+
+func (d *Decoration) Populate(w *world.World, pos world.ChunkPos) error {
+	random := NewRandom(d.seed)
+	xFactor := random.NextLong()/2*2 + 1
+	zFactor := random.NextLong()/2*2 + 1
+	random.SetSeed((int64(pos.X)*xFactor + int64(pos.Z)*zFactor) ^ d.seed)
+
+	area := newArea(w, pos, random, d.biomes.Biome(pos.X*16+16, pos.Z*16+16))
+	for _, decorator := range d.decorators {
+		if err := decorator.Decorate(area); err != nil {
+			return err
+		}
+		if area.err != nil {
+			return area.err
+		}
+	}
+	return nil
+}
+
+// feature is a single structure placed during population, like a tree or an ore vein
+type feature interface {
+	// place places the feature at given coordinates, it reports whether the feature was placed
+	place(area *Area, x, y, z int32) bool
+}
@@ -0,0 +1,174 @@
+package gen
+
+import (
+	"github.com/Pesekjak/173go/pkg/nbt"
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+const (
+	// dungeonHeight is the inner height of a dungeon
+	dungeonHeight = 3
+	// chestSize is the number of slots of a single chest
+	chestSize = 27
+)
+
+// dungeon is a room of cobblestone and moss stone with a monster spawner and up to two chests
+type dungeon struct{}
+
+func (dungeon) place(a *Area, x, y, z int32) bool {
+	r := a.Random
+	radiusX := r.NextIntn(2) + 2
+	radiusZ := r.NextIntn(2) + 2
+
+	// the room needs a solid floor and ceiling, and a few openings in its walls
+	openings := 0
+	for bx := x - radiusX - 1; bx <= x+radiusX+1; bx++ {
+		for by := y - 1; by <= y+dungeonHeight+1; by++ {
+			for bz := z - radiusZ - 1; bz <= z+radiusZ+1; bz++ {
+				solid := a.Block(bx, by, bz).Group.IsSolid()
+				if (by == y-1 || by == y+dungeonHeight+1) && !solid {
+					return false
+				}
+				wall := bx == x-radiusX-1 || bx == x+radiusX+1 || bz == z-radiusZ-1 || bz == z+radiusZ+1
+				if wall && by == y && a.IsEmpty(bx, by, bz) && a.IsEmpty(bx, by+1, bz) {
+					openings++
+				}
+			}
+		}
+	}
+	if openings < 1 || openings > 5 {
+		return false
+	}
+
+	for bx := x - radiusX - 1; bx <= x+radiusX+1; bx++ {
+		for by := y + dungeonHeight; by >= y-1; by-- {
+			for bz := z - radiusZ - 1; bz <= z+radiusZ+1; bz++ {
+				inside := bx != x-radiusX-1 && by != y-1 && bz != z-radiusZ-1 &&
+					bx != x+radiusX+1 && by != y+dungeonHeight+1 && bz != z+radiusZ+1
+				switch {
+				case inside:
+					a.SetBlock(bx, by, bz, material.Air, 0)
+				case by >= 0 && !a.Block(bx, by-1, bz).Group.IsSolid():
+					a.SetBlock(bx, by, bz, material.Air, 0)
+				case a.Block(bx, by, bz).Group.IsSolid():
+					if by == y-1 && r.NextIntn(4) != 0 {
+						a.SetBlock(bx, by, bz, material.MossStone, 0)
+					} else {
+						a.SetBlock(bx, by, bz, material.Cobblestone, 0)
+					}
+				}
+			}
+		}
+	}
+
+	// chests are placed next to a single wall, each of them gets three attempts
+	for chest := 0; chest < 2; chest++ {
+		for attempt := 0; attempt < 3; attempt++ {
+			cx := x + r.NextIntn(radiusX*2+1) - radiusX
+			cz := z + r.NextIntn(radiusZ*2+1) - radiusZ
+			if !a.IsEmpty(cx, y, cz) {
+				continue
+			}
+			walls := 0
+			for _, side := range [4][2]int32{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+				if a.Block(cx+side[0], y, cz+side[1]).Group.IsSolid() {
+					walls++
+				}
+			}
+			if walls != 1 {
+				continue
+			}
+			a.SetBlock(cx, y, cz, material.Chest, 0)
+			a.SetTileEntityNBT(cx, y, cz, chestNBT(dungeonLoot(r)))
+			break
+		}
+	}
+
+	a.SetBlock(x, y, z, material.MobSpawner, 0)
+	a.SetTileEntityNBT(x, y, z, nbt.Compound{
+		"id":       "MobSpawner",
+		"EntityId": dungeonMonster(r),
+		"Delay":    int16(20),
+	})
+	return true
+}
+
+// dungeonLoot fills slots of a dungeon chest, slots can be picked more than once
+func dungeonLoot(r *Random) map[int32]inventory.ItemStack {
+	items := make(map[int32]inventory.ItemStack)
+	for i := 0; i < 8; i++ {
+		if item, ok := dungeonItem(r); ok {
+			items[r.NextIntn(chestSize)] = item
+		}
+	}
+	return items
+}
+
+// dungeonItem picks a random item of a dungeon chest
+func dungeonItem(r *Random) (inventory.ItemStack, bool) {
+	switch r.NextIntn(11) {
+	case 0:
+		return inventory.NewItemStack(material.Saddle, 1, 0), true
+	case 1:
+		return inventory.NewItemStack(material.IronIngot, byte(r.NextIntn(4)+1), 0), true
+	case 2:
+		return inventory.NewItemStack(material.Bread, 1, 0), true
+	case 3:
+		return inventory.NewItemStack(material.Wheat, byte(r.NextIntn(4)+1), 0), true
+	case 4:
+		return inventory.NewItemStack(material.Gunpowder, byte(r.NextIntn(4)+1), 0), true
+	case 5:
+		return inventory.NewItemStack(material.StringItem, byte(r.NextIntn(4)+1), 0), true
+	case 6:
+		return inventory.NewItemStack(material.Bucket, 1, 0), true
+	case 7:
+		if r.NextIntn(100) == 0 {
+			return inventory.NewItemStack(material.GoldenApple, 1, 0), true
+		}
+	case 8:
+		if r.NextIntn(2) == 0 {
+			return inventory.NewItemStack(material.RedstoneDust, byte(r.NextIntn(4)+1), 0), true
+		}
+	case 9:
+		if r.NextIntn(10) == 0 {
+			if r.NextIntn(2) == 0 {
+				return inventory.NewItemStack(material.Record13, 1, 0), true
+			}
+			return inventory.NewItemStack(material.RecordCat, 1, 0), true
+		}
+	case 10:
+		return inventory.NewItemStack(material.Dye, 1, 3), true // cocoa beans
+	}
+	return inventory.ItemStack{}, false
+}
+
+// dungeonMonster picks the monster spawned in a dungeon
+func dungeonMonster(r *Random) string {
+	switch r.NextIntn(4) {
+	case 0:
+		return "Skeleton"
+	case 3:
+		return "Spider"
+	default:
+		return "Zombie"
+	}
+}
+
+// chestNBT creates tile entity data of a chest with given items in its slots
+func chestNBT(items map[int32]inventory.ItemStack) nbt.Compound {
+	list := nbt.NewList(nbt.TagCompound)
+	for slot := int32(0); slot < chestSize; slot++ {
+		item, ok := items[slot]
+		if !ok {
+			continue
+		}
+		list.Values = append(list.Values, nbt.Compound{
+			"Slot":   int8(slot),
+			"id":     int16(item.Material.Id()),
+			"Count":  int8(item.Count),
+			"Damage": int16(item.Data),
+		})
+	}
+	return nbt.Compound{"id": "Chest", "Items": list}
+}
@@ -0,0 +1,33 @@
+// Package gen implements world generators reproducing the terrain of the Notchian Beta 1.7.3 server.
+//
+// Parity with the Notchian server depends on performing the floating point operations in the
+// same order and rounding each of them the way Java does. Go allows the compiler to fuse a multiplication
+// and an addition into a single fused multiply-add, which it does on arm64 and on amd64 with GOAMD64=v3,
+// so the noise and the density code round the products they add with an explicit float64 conversion.
+package gen
+
+import (
+	"fmt"
+
+	"github.com/Pesekjak/173go/pkg/world"
+)
+
+// Factory returns factory of the generator with given name.
+func Factory(name string) (world.GeneratorFactory, error) {
+	switch name {
+	case "default":
+		return func(seed int64) (world.Generator, error) {
+			return NewOverworldGenerator(seed), nil
+		}, nil
+	case "nether":
+		return func(seed int64) (world.Generator, error) {
+			return NewNetherGenerator(seed), nil
+		}, nil
+	case "flat":
+		return func(int64) (world.Generator, error) {
+			return world.MakeStandardFlatGenerator()
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown generator: '%v'", name)
+	}
+}
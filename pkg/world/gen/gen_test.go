@@ -0,0 +1,74 @@
+package gen
+
+import (
+	"testing"
+
+	"github.com/Pesekjak/173go/pkg/world"
+)
+
+// viewRadius is the distance of the chunks the Notchian client views around a player
+const viewRadius = 8
+
+// loadChunks loads the chunks within the radius around the origin in a new world created by the factory
+func loadChunks(b *testing.B, factory world.GeneratorFactory, radius int32) *world.World {
+	w, err := world.NewWorld(b.TempDir(), world.Overworld, 42, factory)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for x := -radius; x <= radius; x++ {
+		for z := -radius; z <= radius; z++ {
+			if _, err := w.LoadChunk(world.NewChunkPos(x, z)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	// the light of the blocks placed by the last population is updated with the tick
+	w.Tick()
+	return w
+}
+
+// BenchmarkGenerate17x17 generates, populates and lights the square of 17x17 chunks the Notchian client views
+// around a player, in a new world each time
+func BenchmarkGenerate17x17(b *testing.B) {
+	factory, _ := Factory("default")
+	for b.Loop() {
+		loadChunks(b, factory, viewRadius)
+	}
+}
+
+// BenchmarkGenerateFlat17x17 generates and lights 17x17 chunks of the flat world, which are not populated
+func BenchmarkGenerateFlat17x17(b *testing.B) {
+	factory, _ := Factory("flat")
+	for b.Loop() {
+		loadChunks(b, factory, viewRadius)
+	}
+}
+
+// copiedGenerator generates the blocks copied from the chunks of another world, without populating them
+type copiedGenerator map[world.ChunkPos][]byte
+
+func (g copiedGenerator) GenerateBlocks(chunk *world.Chunk) error {
+	copy(chunk.BlockIDs(), g[chunk.Pos()])
+	return nil
+}
+
+// BenchmarkLight17x17 lights 17x17 chunks of the populated terrain, the blocks are generated once and only
+// copied to the chunks of each new world
+func BenchmarkLight17x17(b *testing.B) {
+	factory, _ := Factory("default")
+	// the chunks around the copied ones are loaded too, so the copied chunks are populated
+	generated := loadChunks(b, factory, viewRadius+1)
+	blocks := make(copiedGenerator)
+	for x := int32(-viewRadius); x <= viewRadius; x++ {
+		for z := int32(-viewRadius); z <= viewRadius; z++ {
+			pos := world.NewChunkPos(x, z)
+			chunk, _ := generated.Chunk(pos)
+			blocks[pos] = append([]byte(nil), chunk.BlockIDs()...)
+		}
+	}
+	copied := func(int64) (world.Generator, error) { return blocks, nil }
+
+	for b.Loop() {
+		loadChunks(b, copied, viewRadius)
+	}
+}
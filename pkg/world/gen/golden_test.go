@@ -0,0 +1,237 @@
+package gen
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Pesekjak/173go/pkg/world"
+)
+
+// goldenDir holds the worlds saved by the Notchian Beta 1.7.3 server, see its README
+const goldenDir = "testdata/golden"
+
+// goldenDirEnv names the variable with another directory of the golden worlds, the test fails instead of
+// being skipped when it is set and there are no worlds in it
+const goldenDirEnv = "GOLDEN_WORLDS"
+
+const (
+	// spawnArea is the distance in blocks from the spawn the Notchian server prepares the chunks in,
+	// when a new world is created
+	spawnArea = 192
+	// goldenRadius is the radius of the square of chunks around the spawn compared with the golden worlds,
+	// well inside the spawn area so all these chunks are populated
+	goldenRadius = 4
+)
+
+// TestGoldenChunks generates the worlds in the golden directory with their seeds and compares the blocks
+// of the chunks around their spawn with the saved ones, byte for byte.
+func TestGoldenChunks(t *testing.T) {
+	dir, required := os.LookupEnv(goldenDirEnv)
+	if !required {
+		dir = goldenDir
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var worlds []string
+	for _, entry := range entries {
+		if _, err := os.Stat(filepath.Join(dir, entry.Name(), "level.dat")); err == nil {
+			worlds = append(worlds, entry.Name())
+		}
+	}
+	if len(worlds) == 0 {
+		if required {
+			t.Fatal("no golden worlds in ", dir)
+		}
+		t.Skip("no golden worlds in ", dir)
+	}
+
+	for _, name := range worlds {
+		t.Run(name, func(t *testing.T) {
+			// the golden world is read with a generator that does not populate, so only the saved chunks
+			// are compared and the missing ones do not match
+			golden, err := world.NewWorld(copyWorld(t, filepath.Join(dir, name)), world.Overworld, 0,
+				func(int64) (world.Generator, error) { return world.MakeStandardFlatGenerator() })
+			if err != nil {
+				t.Fatal(err)
+			}
+			factory, _ := Factory("default")
+			generated, err := world.NewWorld(t.TempDir(), world.Overworld, golden.Seed(), factory)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the population depends on the order of the chunks, they are loaded in the order
+			// the Notchian server prepares the spawn area in
+			spawn := golden.SpawnPoint
+			for x := -spawnArea; x <= spawnArea; x += 16 {
+				for z := -spawnArea; z <= spawnArea; z += 16 {
+					pos := world.NewChunkPos((spawn.X+int32(x))>>4, (spawn.Z+int32(z))>>4)
+					if _, err := generated.LoadChunk(pos); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			center := spawn.ToChunkPos()
+			for x := center.X - goldenRadius; x <= center.X+goldenRadius; x++ {
+				for z := center.Z - goldenRadius; z <= center.Z+goldenRadius; z++ {
+					compareChunks(t, golden, generated, world.NewChunkPos(x, z))
+				}
+			}
+		})
+	}
+}
+
+// compareChunks reports the first block of the chunk that differs between the worlds
+func compareChunks(t *testing.T, want, got *world.World, pos world.ChunkPos) {
+	t.Helper()
+	for x := pos.X * 16; x < pos.X*16+16; x++ {
+		for z := pos.Z * 16; z < pos.Z*16+16; z++ {
+			for y := int32(0); y < int32(world.ChunkHeight); y++ {
+				wantBlock, err := want.GetBlock(x, y, z)
+				if err != nil {
+					t.Fatal(err)
+				}
+				gotBlock, err := got.GetBlock(x, y, z)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if wantBlock.Material() != gotBlock.Material() || wantBlock.Data() != gotBlock.Data() {
+					t.Errorf("chunk %v;%v: block at %v %v %v is %v:%v, want %v:%v", pos.X, pos.Z, x, y, z,
+						gotBlock.Material(), gotBlock.Data(), wantBlock.Material(), wantBlock.Data())
+					return
+				}
+			}
+		}
+	}
+}
+
+// copyWorld copies the level and the region files of the world to a temporary directory,
+// so the golden world is not changed by loading it
+func copyWorld(t *testing.T, dir string) string {
+	t.Helper()
+	target := t.TempDir()
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return os.MkdirAll(filepath.Join(target, rel), 0755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(target, rel), data, 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return target
+}
+
+// terrainChecksums are the SHA-256 sums of the terrain generated before carving the caves, with the densities
+// and the climate it was generated from. They were computed by this implementation, not by the Notchian server,
+// and they catch any change of the floating point evaluation, like fused multiply-add, on the architectures
+// the tests run on.
+var terrainChecksums = []struct {
+	name string
+	seed int64
+	pos  world.ChunkPos
+	want string
+}{
+	{"overworld", 0, world.NewChunkPos(0, 0),
+		"eb3e80320ba307f684e49b0760cb76b6bca8f8bcb4d081f8dd687ff40dc9b714"},
+	{"overworld", 42, world.NewChunkPos(-3, 7),
+		"10b7f243e220b4e53e9e58a2c4bc15f096f7484f4080c7217bbf4f9ec878bf81"},
+	{"overworld", -8737612371621, world.NewChunkPos(25, -14),
+		"f85c4884dd143c3296938b20d3bcfd2315381e79df3f8641446931280e9faaf0"},
+	{"nether", 0, world.NewChunkPos(0, 0),
+		"35dd26c96ecbeed274af7881adb18145f9d689cb8c458fcebe163cd1a6b1a74c"},
+	{"nether", 42, world.NewChunkPos(-3, 7),
+		"219ec94db70f49e5a9696bc436a3528adfdb93e4f694b47a7169aec49781ee03"},
+}
+
+func TestTerrainChecksums(t *testing.T) {
+	for _, test := range terrainChecksums {
+		blocks := make([]byte, chunkBlocks)
+		hash := sha256.New()
+		if test.name == "nether" {
+			g := NewNetherGenerator(test.seed)
+			if err := g.generate(test.pos, blocks); err != nil {
+				t.Fatal(err)
+			}
+			writeFloats(hash, g.density)
+		} else {
+			g := NewOverworldGenerator(test.seed)
+			if err := g.generate(test.pos, blocks); err != nil {
+				t.Fatal(err)
+			}
+			writeFloats(hash, g.density, g.temperature, g.rainfall)
+		}
+		hash.Write(blocks)
+		if got := hex.EncodeToString(hash.Sum(nil)); got != test.want {
+			t.Errorf("%v of seed %v, chunk %v;%v: checksum %v, want %v", test.name, test.seed, test.pos.X, test.pos.Z,
+				got, test.want)
+		}
+	}
+}
+
+// writeFloats writes the exact bits of the values
+func writeFloats(w io.Writer, values ...[]float64) {
+	for _, v := range values {
+		_ = binary.Write(w, binary.BigEndian, v)
+	}
+}
+
+// caveChecksums are the SHA-256 sums of the chunks after carving the caves, computed by this implementation
+// like the terrain checksums. The chunks are the ones of the most carved blocks around the origin.
+var caveChecksums = []struct {
+	name string
+	seed int64
+	pos  world.ChunkPos
+	want string
+}{
+	{"overworld", 0, world.NewChunkPos(2, 5),
+		"d38bad9cf83d72bd638b4b0568eba2fb632e5d0166b13dbf4730802a4ac4c501"},
+	{"overworld", 42, world.NewChunkPos(-6, -1),
+		"1094479fe8af82aa94ad9624418c456cd7237c0f0af8d717a3b9c8bf16bdc57a"},
+	{"overworld", -8737612371621, world.NewChunkPos(0, -2),
+		"4b436daf55969068593635f01d12a86e18507980004828ff2081d748f6d30d3f"},
+	{"nether", 0, world.NewChunkPos(5, 0),
+		"9f731b9680474f43b6ba0ec955382355840a2378b9e5b994bb9a4e749fe965f8"},
+	{"nether", 42, world.NewChunkPos(1, 2),
+		"ef8c6e133c43db20ac1e097e2bd6c56cd59229cf6e31bbdc72e4f1370865c452"},
+}
+
+func TestCaveChecksums(t *testing.T) {
+	for _, test := range caveChecksums {
+		var pipeline *Pipeline
+		if test.name == "nether" {
+			pipeline = NewNetherGenerator(test.seed).Pipeline
+		} else {
+			pipeline = NewOverworldGenerator(test.seed).Pipeline
+		}
+		blocks := make([]byte, chunkBlocks)
+		for _, stage := range pipeline.stages {
+			if err := stage.Generate(test.pos, blocks); err != nil {
+				t.Fatal(err)
+			}
+		}
+		sum := sha256.Sum256(blocks)
+		if got := hex.EncodeToString(sum[:]); got != test.want {
+			t.Errorf("%v of seed %v, chunk %v;%v: checksum %v, want %v", test.name, test.seed, test.pos.X, test.pos.Z,
+				got, test.want)
+		}
+	}
+}
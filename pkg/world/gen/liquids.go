@@ -0,0 +1,160 @@
+package gen
+
+import "github.com/Pesekjak/173go/pkg/world/material"
+
+// lake is a pool of still liquid, lava lakes are surrounded by stone
+type lake struct {
+	liquid *material.Block
+}
+
+var (
+	waterLake = &lake{liquid: material.WaterStill}
+	lavaLake  = &lake{liquid: material.LavaStill}
+)
+
+// lakeIndex returns index of a cell of the 16x8x16 lake shape
+func lakeIndex(x, y, z int32) int32 {
+	return (x*16+z)*8 + y
+}
+
+func (l *lake) place(a *Area, x, y, z int32) bool {
+	r := a.Random
+	x -= 8
+	z -= 8
+	for y > 0 && a.IsEmpty(x, y, z) {
+		y--
+	}
+	y -= 4
+
+	// the shape is made of several overlapping ellipsoids
+	var shape [2048]bool
+	blobs := r.NextIntn(4) + 4
+	for i := int32(0); i < blobs; i++ {
+		sizeX := float64(r.NextDouble()*6) + 3
+		sizeY := float64(r.NextDouble()*4) + 2
+		sizeZ := float64(r.NextDouble()*6) + 3
+		centerX := float64(r.NextDouble()*(16-sizeX-2)) + 1 + sizeX/2
+		centerY := float64(r.NextDouble()*(8-sizeY-4)) + 2 + sizeY/2
+		centerZ := float64(r.NextDouble()*(16-sizeZ-2)) + 1 + sizeZ/2
+		for bx := int32(1); bx < 15; bx++ {
+			for bz := int32(1); bz < 15; bz++ {
+				for by := int32(1); by < 7; by++ {
+					dx := (float64(bx) - centerX) / (sizeX / 2)
+					dy := (float64(by) - centerY) / (sizeY / 2)
+					dz := (float64(bz) - centerZ) / (sizeZ / 2)
+					if float64(dx*dx)+float64(dy*dy)+float64(dz*dz) < 1 {
+						shape[lakeIndex(bx, by, bz)] = true
+					}
+				}
+			}
+		}
+	}
+
+	// border reports whether the cell is just outside of the shape
+	border := func(bx, by, bz int32) bool {
+		return !shape[lakeIndex(bx, by, bz)] &&
+			(bx < 15 && shape[lakeIndex(bx+1, by, bz)] || bx > 0 && shape[lakeIndex(bx-1, by, bz)] ||
+				bz < 15 && shape[lakeIndex(bx, by, bz+1)] || bz > 0 && shape[lakeIndex(bx, by, bz-1)] ||
+				by < 7 && shape[lakeIndex(bx, by+1, bz)] || by > 0 && shape[lakeIndex(bx, by-1, bz)])
+	}
+
+	// the lake must not touch other liquids above its surface and must be enclosed below it
+	for bx := int32(0); bx < 16; bx++ {
+		for bz := int32(0); bz < 16; bz++ {
+			for by := int32(0); by < 8; by++ {
+				if !border(bx, by, bz) {
+					continue
+				}
+				block := a.Block(x+bx, y+by, z+bz)
+				if by >= 4 && block.Group.IsFluid() {
+					return false
+				}
+				if by < 4 && !block.Group.IsSolid() && block != l.liquid {
+					return false
+				}
+			}
+		}
+	}
+
+	for bx := int32(0); bx < 16; bx++ {
+		for bz := int32(0); bz < 16; bz++ {
+			for by := int32(0); by < 8; by++ {
+				if !shape[lakeIndex(bx, by, bz)] {
+					continue
+				}
+				if by >= 4 {
+					a.SetBlock(x+bx, y+by, z+bz, material.Air, 0)
+				} else {
+					a.SetBlock(x+bx, y+by, z+bz, l.liquid, 0)
+				}
+			}
+		}
+	}
+
+	// dirt uncovered by the lake turns into grass
+	for bx := int32(0); bx < 16; bx++ {
+		for bz := int32(0); bz < 16; bz++ {
+			for by := int32(4); by < 8; by++ {
+				if shape[lakeIndex(bx, by, bz)] && a.Block(x+bx, y+by-1, z+bz) == material.Dirt &&
+					a.CanSeeSky(x+bx, y+by, z+bz) {
+					a.SetBlock(x+bx, y+by-1, z+bz, material.GrassBlock, 0)
+				}
+			}
+		}
+	}
+
+	if l.liquid.Group == material.GroupLava {
+		for bx := int32(0); bx < 16; bx++ {
+			for bz := int32(0); bz < 16; bz++ {
+				for by := int32(0); by < 8; by++ {
+					if border(bx, by, bz) && (by < 4 || r.NextIntn(2) != 0) && a.Block(x+bx, y+by, z+bz).Group.IsSolid() {
+						a.SetBlock(x+bx, y+by, z+bz, material.Stone, 0)
+					}
+				}
+			}
+		}
+	}
+	return true
+}
+
+// spring is a single liquid source in a wall of rock
+type spring struct {
+	liquid *material.Block
+	rock   *material.Block
+	// openBelow counts the block below among the sides, instead of requiring rock there
+	openBelow bool
+}
+
+var (
+	waterSpring  = &spring{liquid: material.WaterFlowing, rock: material.Stone}
+	lavaSpring   = &spring{liquid: material.LavaFlowing, rock: material.Stone}
+	netherSpring = &spring{liquid: material.LavaFlowing, rock: material.Netherrack, openBelow: true}
+)
+
+func (s *spring) place(a *Area, x, y, z int32) bool {
+	rock := byte(s.rock.Id())
+	if a.BlockID(x, y+1, z) != rock || !s.openBelow && a.BlockID(x, y-1, z) != rock {
+		return false
+	}
+	if id := a.BlockID(x, y, z); id != 0 && id != rock {
+		return false
+	}
+
+	sides := [][3]int32{{-1, 0, 0}, {1, 0, 0}, {0, 0, -1}, {0, 0, 1}}
+	if s.openBelow {
+		sides = append(sides, [3]int32{0, -1, 0})
+	}
+	walls, openings := 0, 0
+	for _, side := range sides {
+		switch a.BlockID(x+side[0], y+side[1], z+side[2]) {
+		case rock:
+			walls++
+		case 0:
+			openings++
+		}
+	}
+	if walls == len(sides)-1 && openings == 1 {
+		a.SetBlock(x, y, z, s.liquid, 0)
+	}
+	return true
+}
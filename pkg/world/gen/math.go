@@ -0,0 +1,20 @@
+package gen
+
+import "math"
+
+// sinTable is the lookup table of the Notchian sine, used instead of the exact functions by the carvers
+var sinTable [65536]float32
+
+func init() {
+	for i := range sinTable {
+		sinTable[i] = float32(math.Sin(float64(float64(i)*math.Pi) * 2 / 65536))
+	}
+}
+
+func sin(f float32) float32 {
+	return sinTable[int32(f*10430.378)&0xFFFF]
+}
+
+func cos(f float32) float32 {
+	return sinTable[int32(float32(f*10430.378)+16384)&0xFFFF]
+}
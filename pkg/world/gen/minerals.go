@@ -0,0 +1,123 @@
+package gen
+
+import (
+	"math"
+
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// oreVein is a vein of blocks replacing stone, like ores or patches of dirt and gravel
+type oreVein struct {
+	block *material.Block
+	// size is the number of steps of the vein, roughly the number of placed blocks
+	size int32
+	// replaces is the block replaced by the vein
+	replaces *material.Block
+}
+
+func newOreVein(block *material.Block, size int32) *oreVein {
+	return &oreVein{block: block, size: size, replaces: material.Stone}
+}
+
+func (v *oreVein) place(a *Area, x, y, z int32) bool {
+	r := a.Random
+	size := float32(v.size)
+	angle := r.NextFloat() * math.Pi
+	startX := float64(float32(x+8) + float32(sin(angle)*size/8))
+	endX := float64(float32(x+8) - float32(sin(angle)*size/8))
+	startZ := float64(float32(z+8) + float32(cos(angle)*size/8))
+	endZ := float64(float32(z+8) - float32(cos(angle)*size/8))
+	startY := float64(y + r.NextIntn(3) + 2)
+	endY := float64(y + r.NextIntn(3) + 2)
+
+	replaces := byte(v.replaces.Id())
+	for i := int32(0); i <= v.size; i++ {
+		progress := float64(i) / float64(v.size)
+		centerX := startX + float64((endX-startX)*progress)
+		centerY := startY + float64((endY-startY)*progress)
+		centerZ := startZ + float64((endZ-startZ)*progress)
+		scale := r.NextDouble() * float64(v.size) / 16
+		width := float64(float64(sin(float32(i)*math.Pi/size)+1)*scale) + 1
+		height := width
+
+		for bx := floor(centerX - width/2); bx <= floor(centerX+width/2); bx++ {
+			dx := (float64(bx) + 0.5 - centerX) / (width / 2)
+			if dx*dx >= 1 {
+				continue
+			}
+			for by := floor(centerY - height/2); by <= floor(centerY+height/2); by++ {
+				dy := (float64(by) + 0.5 - centerY) / (height / 2)
+				if float64(dx*dx)+float64(dy*dy) >= 1 {
+					continue
+				}
+				for bz := floor(centerZ - width/2); bz <= floor(centerZ+width/2); bz++ {
+					dz := (float64(bz) + 0.5 - centerZ) / (width / 2)
+					if float64(dx*dx)+float64(dy*dy)+float64(dz*dz) < 1 && a.BlockID(int32(bx), int32(by), int32(bz)) == replaces {
+						a.SetBlock(int32(bx), int32(by), int32(bz), v.block, 0)
+					}
+				}
+			}
+		}
+	}
+	return true
+}
+
+// clayPatch is a vein of clay replacing sand under water
+type clayPatch struct {
+	oreVein
+}
+
+func newClayPatch(size int32) *clayPatch {
+	return &clayPatch{oreVein{block: material.ClayBlock, size: size, replaces: material.Sand}}
+}
+
+func (c *clayPatch) place(a *Area, x, y, z int32) bool {
+	if a.Block(x, y, z).Group != material.GroupWater {
+		return false
+	}
+	return c.oreVein.place(a, x, y, z)
+}
+
+// mineral is a kind of vein scattered through the terrain
+type mineral struct {
+	vein  feature
+	count int
+	// height picks the Y coordinate of a vein
+	height func(r *Random) int32
+}
+
+// uniformHeight spreads veins uniformly up to given height
+func uniformHeight(maxY int32) func(r *Random) int32 {
+	return func(r *Random) int32 {
+		return r.NextIntn(maxY)
+	}
+}
+
+// overworldMinerals are the minerals of the overworld, in the order they are placed
+var overworldMinerals = []mineral{
+	{vein: newClayPatch(32), count: 10, height: uniformHeight(128)},
+	{vein: newOreVein(material.Dirt, 32), count: 20, height: uniformHeight(128)},
+	{vein: newOreVein(material.Gravel, 32), count: 10, height: uniformHeight(128)},
+	{vein: newOreVein(material.CoalOre, 16), count: 20, height: uniformHeight(128)},
+	{vein: newOreVein(material.IronOre, 8), count: 20, height: uniformHeight(64)},
+	{vein: newOreVein(material.GoldOre, 8), count: 2, height: uniformHeight(32)},
+	{vein: newOreVein(material.RedstoneOre, 7), count: 8, height: uniformHeight(16)},
+	{vein: newOreVein(material.DiamondOre, 7), count: 1, height: uniformHeight(16)},
+	{vein: newOreVein(material.LapisLazuliOre, 6), count: 1, height: func(r *Random) int32 {
+		return r.NextIntn(16) + r.NextIntn(16) // lapis is the most common around Y 16
+	}},
+}
+
+// decorateMinerals places the veins of the overworld minerals
+func decorateMinerals(a *Area) error {
+	r := a.Random
+	for _, m := range overworldMinerals {
+		for i := 0; i < m.count; i++ {
+			x := a.X + r.NextIntn(16)
+			y := m.height(r)
+			z := a.Z + r.NextIntn(16)
+			m.vein.place(a, x, y, z)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package gen
+
+import (
+	"testing"
+
+	"github.com/Pesekjak/173go/pkg/world"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// oreArea is the radius in chunks of the square loaded around the origin, the ores are counted in the chunks
+// populated together with all their -1 neighbours, whose veins can reach into them
+const oreArea = 4
+
+// oreStats are the number of the blocks of an ore in a world and the highest Y they are found at
+type oreStats struct {
+	count int
+	maxY  int32
+}
+
+// generateOres generates the square of chunks around the origin and counts the blocks of the ores in it
+func generateOres(t *testing.T, seed int64) map[material.Material]oreStats {
+	t.Helper()
+	factory, _ := Factory("default")
+	w, err := world.NewWorld(t.TempDir(), world.Overworld, seed, factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := int32(-oreArea); x < oreArea; x++ {
+		for z := int32(-oreArea); z < oreArea; z++ {
+			if _, err := w.LoadChunk(world.NewChunkPos(x, z)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	ores := make(map[material.Material]oreStats)
+	for x := int32(-oreArea+1) * 16; x < (oreArea-1)*16; x++ {
+		for z := int32(-oreArea+1) * 16; z < (oreArea-1)*16; z++ {
+			for y := int32(0); y < int32(world.ChunkHeight); y++ {
+				block, err := w.GetBlock(x, y, z)
+				if err != nil {
+					t.Fatal(err)
+				}
+				counts := ores[block.Material()]
+				counts.count++
+				counts.maxY = max(counts.maxY, y)
+				ores[block.Material()] = counts
+			}
+		}
+	}
+	return ores
+}
+
+func TestOreDistribution(t *testing.T) {
+	// chunks is the number of chunks the ores are counted in
+	const chunks = (2*oreArea - 2) * (2*oreArea - 2)
+	tests := []struct {
+		ore material.Material
+		// maxY is the highest Y the veins reach, the veins start up to 4 blocks above the height they are
+		// placed at and spread by half of their width
+		maxY int32
+		// minCount and maxCount are the bounds of the blocks of the ore in a chunk on average
+		minCount, maxCount float64
+	}{
+		{material.CoalOre, 127, 50, 250},
+		{material.IronOre, 68, 25, 120},
+		{material.GoldOre, 36, 1, 20},
+		{material.RedstoneOre, 19, 10, 60},
+		{material.DiamondOre, 19, 0.5, 10},
+		{material.LapisLazuliOre, 34, 0.5, 10},
+	}
+	for _, seed := range []int64{0, 42, -8737612371621} {
+		ores := generateOres(t, seed)
+		for _, test := range tests {
+			got := ores[test.ore]
+			if got.maxY > test.maxY {
+				t.Errorf("seed %v: %v found at Y %v, want at most %v", seed, test.ore, got.maxY, test.maxY)
+			}
+			if perChunk := float64(got.count) / chunks; perChunk < test.minCount || perChunk > test.maxCount {
+				t.Errorf("seed %v: %.1f blocks of %v per chunk, want between %v and %v", seed, perChunk, test.ore,
+					test.minCount, test.maxCount)
+			}
+		}
+	}
+}
+
+// oreCounts are the numbers of the blocks of the ores generated around the origin, counted by this implementation
+// like the terrain checksums. They catch the changes of the order the veins consume the random in.
+var oreCounts = []struct {
+	seed int64
+	want map[material.Material]int
+}{
+	{42, map[material.Material]int{
+		material.CoalOre: 6492, material.IronOre: 3424, material.GoldOre: 326, material.RedstoneOre: 1225,
+		material.DiamondOre: 152, material.LapisLazuliOre: 151, material.Dirt: 57307, material.Gravel: 16433,
+	}},
+}
+
+func TestOreCounts(t *testing.T) {
+	for _, test := range oreCounts {
+		ores := generateOres(t, test.seed)
+		for ore, want := range test.want {
+			if got := ores[ore].count; got != want {
+				t.Errorf("seed %v: %v blocks of %v, want %v", test.seed, got, ore, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package gen
+
+import (
+	"math"
+
+	"github.com/Pesekjak/173go/pkg/world"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+const (
+	// lavaLevel is the height of the lava oceans of the nether
+	lavaLevel = 32
+	// netherSurfaceLevel is the height around which soul sand and gravel are placed
+	netherSurfaceLevel = 64
+)
+
+// NetherGenerator generates terrain of the Notchian Beta 1.7.3 nether.
+// The same seed produces the same terrain as the Notchian server.
+//
+// The generator keeps buffers between chunks and is not safe for concurrent use.
+type NetherGenerator struct {
+	*Pipeline
+	*Decoration
+
+	seed   int64
+	random *Random
+
+	minLimitNoise       *octaveNoise
+	maxLimitNoise       *octaveNoise
+	mainNoise           *octaveNoise
+	soulSandGravelNoise *octaveNoise
+	netherrackNoise     *octaveNoise
+
+	// offsets lower the density towards the floor and the ceiling
+	offsets [noiseSizeY]float64
+
+	density                                        []float64
+	mainValues, minValues, maxValues               []float64
+	soulSandValues, gravelValues, netherrackValues []float64
+}
+
+// NewNetherGenerator creates generator of the nether with given seed.
+func NewNetherGenerator(seed int64) *NetherGenerator {
+	random := NewRandom(seed)
+	g := &NetherGenerator{
+		seed:   seed,
+		random: random,
+
+		// the order matters, all noises are seeded from the same random. The Notchian server
+		// creates two more noises afterwards, but their values do not affect the terrain.
+		minLimitNoise:       newOctaveNoise(random, 16),
+		maxLimitNoise:       newOctaveNoise(random, 16),
+		mainNoise:           newOctaveNoise(random, 8),
+		soulSandGravelNoise: newOctaveNoise(random, 4),
+		netherrackNoise:     newOctaveNoise(random, 4),
+	}
+	for iy := 0; iy < noiseSizeY; iy++ {
+		g.offsets[iy] = float64(math.Cos(float64(iy)*math.Pi*6/float64(noiseSizeY)) * 2)
+		distance := float64(iy)
+		if iy > noiseSizeY/2 {
+			distance = float64(noiseSizeY - 1 - iy)
+		}
+		if distance < 4 {
+			distance = 4 - distance
+			g.offsets[iy] -= float64(distance * distance * distance * 10)
+		}
+	}
+	g.Pipeline = NewPipeline(StageFunc(g.generate), NewNetherCaveCarver(seed))
+	g.Decoration = g.newNetherDecoration()
+	return g
+}
+
+// generate generates the terrain and surface of the chunk
+func (g *NetherGenerator) generate(pos world.ChunkPos, blocks []byte) error {
+	g.random.SetSeed(int64(pos.X)*341873128712 + int64(pos.Z)*132897987541)
+	g.generateTerrain(pos, blocks)
+	g.generateSurface(pos, blocks)
+	return nil
+}
+
+// generateTerrain fills the chunk with netherrack where the density is positive and with lava below the lava level
+func (g *NetherGenerator) generateTerrain(pos world.ChunkPos, blocks []byte) {
+	netherrack := byte(material.Netherrack.Id())
+	lava := byte(material.LavaStill.Id())
+
+	g.generateDensity(int(pos.X)*cellsX, 0, int(pos.Z)*cellsX)
+	d := g.density
+
+	for cx := 0; cx < cellsX; cx++ {
+		for cz := 0; cz < cellsX; cz++ {
+			for cy := 0; cy < cellsY; cy++ {
+				// trilinear interpolation of the densities at the corners of the cell
+				d000 := d[((cx+0)*noiseSizeZ+cz+0)*noiseSizeY+cy]
+				d010 := d[((cx+0)*noiseSizeZ+cz+1)*noiseSizeY+cy]
+				d100 := d[((cx+1)*noiseSizeZ+cz+0)*noiseSizeY+cy]
+				d110 := d[((cx+1)*noiseSizeZ+cz+1)*noiseSizeY+cy]
+				step000 := float64((d[((cx+0)*noiseSizeZ+cz+0)*noiseSizeY+cy+1] - d000) * 0.125)
+				step010 := float64((d[((cx+0)*noiseSizeZ+cz+1)*noiseSizeY+cy+1] - d010) * 0.125)
+				step100 := float64((d[((cx+1)*noiseSizeZ+cz+0)*noiseSizeY+cy+1] - d100) * 0.125)
+				step110 := float64((d[((cx+1)*noiseSizeZ+cz+1)*noiseSizeY+cy+1] - d110) * 0.125)
+
+				for ly := 0; ly < cellHeight; ly++ {
+					y := cy*cellHeight + ly
+					dx0 := d000
+					dx1 := d010
+					stepX0 := float64((d100 - d000) * 0.25)
+					stepX1 := float64((d110 - d010) * 0.25)
+
+					for lx := 0; lx < cellWidth; lx++ {
+						x := cx*cellWidth + lx
+						index := x<<11 | (cz*cellWidth)<<7 | y
+						density := dx0
+						stepZ := float64((dx1 - dx0) * 0.25)
+
+						for lz := 0; lz < cellWidth; lz++ {
+							var block byte
+							if y < lavaLevel {
+								block = lava
+							}
+							if density > 0 {
+								block = netherrack
+							}
+							blocks[index] = block
+							index += 128
+							density += stepZ
+						}
+						dx0 += stepX0
+						dx1 += stepX1
+					}
+					d000 += step000
+					d010 += step010
+					d100 += step100
+					d110 += step110
+				}
+			}
+		}
+	}
+}
+
+// generateDensity computes the terrain density in the corners of the cells of a chunk
+func (g *NetherGenerator) generateDensity(x, y, z int) {
+	const horizontalScale, verticalScale = 684.412, 2053.236
+
+	g.mainValues = g.mainNoise.generate(g.mainValues, float64(x), float64(y), float64(z),
+		noiseSizeX, noiseSizeY, noiseSizeZ, horizontalScale/80, verticalScale/60, horizontalScale/80)
+	g.minValues = g.minLimitNoise.generate(g.minValues, float64(x), float64(y), float64(z),
+		noiseSizeX, noiseSizeY, noiseSizeZ, horizontalScale, verticalScale, horizontalScale)
+	g.maxValues = g.maxLimitNoise.generate(g.maxValues, float64(x), float64(y), float64(z),
+		noiseSizeX, noiseSizeY, noiseSizeZ, horizontalScale, verticalScale, horizontalScale)
+	g.density = resize(g.density, noiseSizeX*noiseSizeY*noiseSizeZ)
+
+	for index := range g.density {
+		iy := index % noiseSizeY
+		minLimit := g.minValues[index] / 512
+		maxLimit := g.maxValues[index] / 512
+		main := (g.mainValues[index]/10 + 1) / 2
+
+		var density float64
+		if main < 0 {
+			density = minLimit
+		} else if main > 1 {
+			density = maxLimit
+		} else {
+			density = minLimit + float64((maxLimit-minLimit)*main)
+		}
+		density -= g.offsets[iy]
+
+		// close the terrain at the top of the world
+		if iy > noiseSizeY-4 {
+			factor := float64(float32(iy-(noiseSizeY-4)) / 3)
+			density = float64(density*(1-factor)) + float64(-10*factor)
+		}
+		g.density[index] = density
+	}
+}
+
+// generateSurface covers the shores of the lava oceans with soul sand and gravel and places the bedrock
+func (g *NetherGenerator) generateSurface(pos world.ChunkPos, blocks []byte) {
+	const scale = 0.03125
+
+	air := byte(material.Air.Id())
+	netherrack := byte(material.Netherrack.Id())
+	soulSand := byte(material.SoulSand.Id())
+	gravel := byte(material.Gravel.Id())
+	lava := byte(material.LavaStill.Id())
+	bedrock := byte(material.Bedrock.Id())
+
+	x, z := float64(pos.X*16), float64(pos.Z*16)
+	g.soulSandValues = g.soulSandGravelNoise.generate(g.soulSandValues, x, z, 0, 16, 16, 1, scale, scale, 1)
+	g.gravelValues = g.soulSandGravelNoise.generate(g.gravelValues, x, 109.0134, z, 16, 1, 16, scale, 1, scale)
+	g.netherrackValues = g.netherrackNoise.generate(g.netherrackValues, x, z, 0, 16, 16, 1,
+		scale*2, scale*2, scale*2)
+
+	for lz := 0; lz < 16; lz++ {
+		for lx := 0; lx < 16; lx++ {
+			column := lz + lx*16
+			soulSandy := g.soulSandValues[column]+float64(g.random.NextDouble()*0.2) > 0
+			gravelly := g.gravelValues[column]+float64(g.random.NextDouble()*0.2) > 0
+			depth := int(g.netherrackValues[column]/3 + 3 + float64(g.random.NextDouble()*0.25))
+
+			topBlock := netherrack
+			fillerBlock := netherrack
+			remaining := -1
+
+			for y := 127; y >= 0; y-- {
+				index := (lx*16+lz)*128 + y
+				if y >= 127-int(g.random.NextIntn(5)) || y <= int(g.random.NextIntn(5)) {
+					blocks[index] = bedrock
+					continue
+				}
+
+				block := blocks[index]
+				if block == air {
+					remaining = -1
+					continue
+				}
+				if block != netherrack {
+					continue
+				}
+
+				if remaining == -1 {
+					if depth <= 0 {
+						topBlock = air
+						fillerBlock = netherrack
+					} else if y >= netherSurfaceLevel-4 && y <= netherSurfaceLevel+1 {
+						topBlock = netherrack
+						fillerBlock = netherrack
+						if gravelly {
+							topBlock = gravel
+						}
+						if soulSandy {
+							topBlock = soulSand
+							fillerBlock = soulSand
+						}
+					}
+					if y < netherSurfaceLevel && topBlock == air {
+						topBlock = lava
+					}
+
+					remaining = depth
+					if y >= netherSurfaceLevel-1 {
+						blocks[index] = topBlock
+					} else {
+						blocks[index] = fillerBlock
+					}
+				} else if remaining > 0 {
+					remaining--
+					blocks[index] = fillerBlock
+				}
+			}
+		}
+	}
+}
+
+// Biome returns the biome of the nether, which is the same everywhere.
+func (g *NetherGenerator) Biome(int32, int32) *world.Biome {
+	return world.HellBiome
+}
+
+// Climate returns the climate of the nether, which is hot and dry everywhere.
+func (g *NetherGenerator) Climate(int32, int32) (temperature, rainfall float64) {
+	return 1, 0
+}
+
+// CanSpawnAt accepts columns where the first uncovered block above the lava oceans is an opaque cube.
+func (g *NetherGenerator) CanSpawnAt(w *world.World, x, z int32) (bool, error) {
+	block, err := w.FirstUncoveredBlock(x, z)
+	if err != nil {
+		return false, err
+	}
+	m := block.Material()
+	return m != material.Air && m != material.Bedrock && m.IsOpaqueCube(), nil
+}
@@ -0,0 +1,108 @@
+package gen
+
+import "github.com/Pesekjak/173go/pkg/world/material"
+
+// newNetherDecoration creates the population of the nether, the decorators are in the order
+// the Notchian server places the features in, so they consume the random the same way.
+//
+// The Notchian server keeps using the random of the last generated chunk to populate the nether,
+// here the random is seeded for each chunk instead, so the result does not depend on the order
+// the chunks are generated in.
+func (g *NetherGenerator) newNetherDecoration() *Decoration {
+	return NewDecoration(g.seed, g,
+		DecoratorFunc(decorateNetherSprings),
+		DecoratorFunc(decorateFire),
+		DecoratorFunc(decorateGlowstone),
+		DecoratorFunc(decorateNetherMushrooms),
+	)
+}
+
+// scatterBelowCeiling places the feature count times at random points of the area,
+// away from the bedrock of the floor and the ceiling
+func scatterBelowCeiling(a *Area, f feature, count int) {
+	r := a.Random
+	for i := 0; i < count; i++ {
+		x := a.X + r.NextIntn(16) + 8
+		y := r.NextIntn(120) + 4
+		z := a.Z + r.NextIntn(16) + 8
+		f.place(a, x, y, z)
+	}
+}
+
+// decorateNetherSprings places lava sources in the netherrack
+func decorateNetherSprings(a *Area) error {
+	scatterBelowCeiling(a, netherSpring, 8)
+	return nil
+}
+
+// decorateFire sets a few patches of netherrack on fire
+func decorateFire(a *Area) error {
+	r := a.Random
+	scatterBelowCeiling(a, fire{}, int(r.NextIntn(r.NextIntn(10)+1)+1))
+	return nil
+}
+
+// decorateGlowstone hangs glowstone clusters from the ceilings, the second kind is placed
+// by a separate generator of the Notchian server that behaves the same
+func decorateGlowstone(a *Area) error {
+	r := a.Random
+	scatterBelowCeiling(a, glowstone{}, int(r.NextIntn(r.NextIntn(10)+1)))
+	scatterBelowCeiling(a, glowstone{}, 10)
+	return nil
+}
+
+// decorateNetherMushrooms places patches of both mushrooms, the Notchian server rolls for them with a chance of one in one
+func decorateNetherMushrooms(a *Area) error {
+	scatterRarely(a, brownMushrooms, 1)
+	scatterRarely(a, redMushrooms, 1)
+	return nil
+}
+
+// fire is a patch of fire burning on netherrack
+type fire struct{}
+
+func (fire) place(a *Area, x, y, z int32) bool {
+	r := a.Random
+	netherrack := byte(material.Netherrack.Id())
+	for i := 0; i < 64; i++ {
+		px := x + r.NextIntn(8) - r.NextIntn(8)
+		py := y + r.NextIntn(4) - r.NextIntn(4)
+		pz := z + r.NextIntn(8) - r.NextIntn(8)
+		if a.IsEmpty(px, py, pz) && a.BlockID(px, py-1, pz) == netherrack {
+			a.SetBlock(px, py, pz, material.Fire, 0)
+		}
+	}
+	return true
+}
+
+// glowstone is a cluster of glowstone growing down from netherrack
+type glowstone struct{}
+
+func (glowstone) place(a *Area, x, y, z int32) bool {
+	r := a.Random
+	if !a.IsEmpty(x, y, z) || a.BlockID(x, y+1, z) != byte(material.Netherrack.Id()) {
+		return false
+	}
+	glowstoneID := byte(material.GlowstoneBlock.Id())
+	a.SetBlock(x, y, z, material.GlowstoneBlock, 0)
+
+	// the cluster grows into empty blocks touching exactly one glowstone
+	for i := 0; i < 1500; i++ {
+		px := x + r.NextIntn(8) - r.NextIntn(8)
+		py := y - r.NextIntn(12)
+		pz := z + r.NextIntn(8) - r.NextIntn(8)
+		if a.BlockID(px, py, pz) != 0 {
+			continue
+		}
+		touching := 0
+		for _, side := range [6][3]int32{{-1, 0, 0}, {1, 0, 0}, {0, -1, 0}, {0, 1, 0}, {0, 0, -1}, {0, 0, 1}} {
+			if a.BlockID(px+side[0], py+side[1], pz+side[2]) == glowstoneID {
+				touching++
+			}
+		}
+		if touching == 1 {
+			a.SetBlock(px, py, pz, material.GlowstoneBlock, 0)
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package gen
+
+// octaveNoise sums several octaves of Perlin noise, each with half the frequency and double the
+// amplitude of the previous one
+type octaveNoise struct {
+	octaves []*perlinNoise
+}
+
+func newOctaveNoise(random *Random, count int) *octaveNoise {
+	o := &octaveNoise{octaves: make([]*perlinNoise, count)}
+	for i := range o.octaves {
+		o.octaves[i] = newPerlinNoise(random)
+	}
+	return o
+}
+
+// sample returns the 2D noise value at given coordinates
+func (o *octaveNoise) sample(x, z float64) float64 {
+	value := 0.0
+	frequency := 1.0
+	for _, octave := range o.octaves {
+		value += octave.sample(float64(x*frequency), float64(z*frequency), 0) / frequency
+		frequency /= 2
+	}
+	return value
+}
+
+// generate fills the values with noise of a region, indexed by (x*sizeZ + z)*sizeY + y.
+// The values are reused if they have enough capacity.
+func (o *octaveNoise) generate(values []float64, x, y, z float64, sizeX, sizeY, sizeZ int,
+	scaleX, scaleY, scaleZ float64) []float64 {
+	values = resize(values, sizeX*sizeY*sizeZ)
+	frequency := 1.0
+	for _, octave := range o.octaves {
+		octave.add(values, x, y, z, sizeX, sizeY, sizeZ, scaleX*frequency, scaleY*frequency, scaleZ*frequency, frequency)
+		frequency /= 2
+	}
+	return values
+}
+
+// generate2D fills the values with 2D noise of a region, indexed by x*sizeZ + z
+func (o *octaveNoise) generate2D(values []float64, x, z int32, sizeX, sizeZ int, scaleX, scaleZ float64) []float64 {
+	return o.generate(values, float64(x), 10, float64(z), sizeX, 1, sizeZ, scaleX, 1, scaleZ)
+}
+
+// resize returns zeroed slice of given length, reusing the values if possible
+func resize(values []float64, length int) []float64 {
+	if cap(values) < length {
+		return make([]float64, length)
+	}
+	values = values[:length]
+	for i := range values {
+		values[i] = 0
+	}
+	return values
+}
@@ -0,0 +1,349 @@
+package gen
+
+import (
+	"github.com/Pesekjak/173go/pkg/world"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+const (
+	seaLevel = 64
+
+	// the density is sampled every 4 blocks horizontally and every 8 blocks vertically
+	cellWidth   = 4
+	cellHeight  = 8
+	cellsX      = 16 / cellWidth
+	cellsY      = 128 / cellHeight
+	noiseSizeX  = cellsX + 1
+	noiseSizeY  = cellsY + 1
+	noiseSizeZ  = cellsX + 1
+	chunkBlocks = 16 * 16 * 128
+)
+
+// OverworldGenerator generates terrain of the Notchian Beta 1.7.3 overworld.
+// The same seed produces the same terrain as the Notchian server.
+//
+// The generator keeps buffers between chunks and is not safe for concurrent use.
+type OverworldGenerator struct {
+	*Pipeline
+	*Decoration
+
+	seed   int64
+	random *Random
+
+	minLimitNoise   *octaveNoise
+	maxLimitNoise   *octaveNoise
+	mainNoise       *octaveNoise
+	sandGravelNoise *octaveNoise
+	stoneNoise      *octaveNoise
+	scaleNoise      *octaveNoise
+	depthNoise      *octaveNoise
+	treeNoise       *octaveNoise
+
+	biomeSource *biomeSource
+
+	density                               []float64
+	mainValues, minValues, maxValues      []float64
+	scaleValues, depthValues              []float64
+	sandValues, gravelValues, stoneValues []float64
+	biomes                                []*world.Biome
+	temperature, rainfall                 []float64
+
+	snowBiomes                    []*world.Biome
+	snowTemperature, snowRainfall []float64
+}
+
+// NewOverworldGenerator creates generator of the overworld with given seed.
+func NewOverworldGenerator(seed int64) *OverworldGenerator {
+	random := NewRandom(seed)
+	g := &OverworldGenerator{
+		seed:   seed,
+		random: random,
+
+		// the order matters, all noises are seeded from the same random
+		minLimitNoise:   newOctaveNoise(random, 16),
+		maxLimitNoise:   newOctaveNoise(random, 16),
+		mainNoise:       newOctaveNoise(random, 8),
+		sandGravelNoise: newOctaveNoise(random, 4),
+		stoneNoise:      newOctaveNoise(random, 4),
+		scaleNoise:      newOctaveNoise(random, 10),
+		depthNoise:      newOctaveNoise(random, 16),
+		treeNoise:       newOctaveNoise(random, 8),
+
+		biomeSource: newBiomeSource(seed),
+	}
+	g.Pipeline = NewPipeline(StageFunc(g.generate), NewCaveCarver(seed))
+	g.Decoration = g.newOverworldDecoration()
+	return g
+}
+
+// generate generates the terrain and surface of the chunk
+func (g *OverworldGenerator) generate(pos world.ChunkPos, blocks []byte) error {
+	g.random.SetSeed(int64(pos.X)*341873128712 + int64(pos.Z)*132897987541)
+
+	g.biomes, g.temperature, g.rainfall = g.biomeSource.generate(g.biomes, g.temperature, g.rainfall,
+		pos.X*16, pos.Z*16, 16, 16)
+	g.generateTerrain(pos, blocks)
+	g.generateSurface(pos, blocks)
+	return nil
+}
+
+// generateTerrain fills the chunk with stone where the density is positive and with water below the sea level
+func (g *OverworldGenerator) generateTerrain(pos world.ChunkPos, blocks []byte) {
+	stone := byte(material.Stone.Id())
+	water := byte(material.WaterStill.Id())
+	ice := byte(material.Ice.Id())
+
+	g.generateDensity(int(pos.X)*cellsX, 0, int(pos.Z)*cellsX)
+	d := g.density
+
+	for cx := 0; cx < cellsX; cx++ {
+		for cz := 0; cz < cellsX; cz++ {
+			for cy := 0; cy < cellsY; cy++ {
+				// trilinear interpolation of the densities at the corners of the cell
+				d000 := d[((cx+0)*noiseSizeZ+cz+0)*noiseSizeY+cy]
+				d010 := d[((cx+0)*noiseSizeZ+cz+1)*noiseSizeY+cy]
+				d100 := d[((cx+1)*noiseSizeZ+cz+0)*noiseSizeY+cy]
+				d110 := d[((cx+1)*noiseSizeZ+cz+1)*noiseSizeY+cy]
+				step000 := float64((d[((cx+0)*noiseSizeZ+cz+0)*noiseSizeY+cy+1] - d000) * 0.125)
+				step010 := float64((d[((cx+0)*noiseSizeZ+cz+1)*noiseSizeY+cy+1] - d010) * 0.125)
+				step100 := float64((d[((cx+1)*noiseSizeZ+cz+0)*noiseSizeY+cy+1] - d100) * 0.125)
+				step110 := float64((d[((cx+1)*noiseSizeZ+cz+1)*noiseSizeY+cy+1] - d110) * 0.125)
+
+				for ly := 0; ly < cellHeight; ly++ {
+					y := cy*cellHeight + ly
+					dx0 := d000
+					dx1 := d010
+					stepX0 := float64((d100 - d000) * 0.25)
+					stepX1 := float64((d110 - d010) * 0.25)
+
+					for lx := 0; lx < cellWidth; lx++ {
+						x := cx*cellWidth + lx
+						index := x<<11 | (cz*cellWidth)<<7 | y
+						density := dx0
+						stepZ := float64((dx1 - dx0) * 0.25)
+
+						for lz := 0; lz < cellWidth; lz++ {
+							z := cz*cellWidth + lz
+							var block byte
+							if y < seaLevel {
+								if g.temperature[x*16+z] < 0.5 && y >= seaLevel-1 {
+									block = ice
+								} else {
+									block = water
+								}
+							}
+							if density > 0 {
+								block = stone
+							}
+							blocks[index] = block
+							index += 128
+							density += stepZ
+						}
+						dx0 += stepX0
+						dx1 += stepX1
+					}
+					d000 += step000
+					d010 += step010
+					d100 += step100
+					d110 += step110
+				}
+			}
+		}
+	}
+}
+
+// generateDensity computes the terrain density in the corners of the cells of a chunk
+func (g *OverworldGenerator) generateDensity(x, y, z int) {
+	const horizontalScale, verticalScale = 684.412, 684.412
+
+	g.scaleValues = g.scaleNoise.generate2D(g.scaleValues, int32(x), int32(z), noiseSizeX, noiseSizeZ, 1.121, 1.121)
+	g.depthValues = g.depthNoise.generate2D(g.depthValues, int32(x), int32(z), noiseSizeX, noiseSizeZ, 200, 200)
+	g.mainValues = g.mainNoise.generate(g.mainValues, float64(x), float64(y), float64(z),
+		noiseSizeX, noiseSizeY, noiseSizeZ, horizontalScale/80, verticalScale/160, horizontalScale/80)
+	g.minValues = g.minLimitNoise.generate(g.minValues, float64(x), float64(y), float64(z),
+		noiseSizeX, noiseSizeY, noiseSizeZ, horizontalScale, verticalScale, horizontalScale)
+	g.maxValues = g.maxLimitNoise.generate(g.maxValues, float64(x), float64(y), float64(z),
+		noiseSizeX, noiseSizeY, noiseSizeZ, horizontalScale, verticalScale, horizontalScale)
+	g.density = resize(g.density, noiseSizeX*noiseSizeY*noiseSizeZ)
+
+	index := 0
+	columnIndex := 0
+	step := 16 / noiseSizeX
+	for ix := 0; ix < noiseSizeX; ix++ {
+		climateX := ix*step + step/2
+		for iz := 0; iz < noiseSizeZ; iz++ {
+			climateZ := iz*step + step/2
+			temperature := g.temperature[climateX*16+climateZ]
+			humidity := float64(g.rainfall[climateX*16+climateZ] * temperature)
+			humidity = 1 - humidity
+			humidity = float64(humidity * humidity)
+			humidity = float64(humidity * humidity)
+			humidity = 1 - humidity
+
+			scale := (g.scaleValues[columnIndex] + 256) / 512
+			scale = float64(scale * humidity)
+			if scale > 1 {
+				scale = 1
+			}
+
+			depth := g.depthValues[columnIndex] / 8000
+			if depth < 0 {
+				depth = -depth * 0.3
+			}
+			depth = float64(depth*3) - 2
+			if depth < 0 {
+				depth /= 2
+				if depth < -1 {
+					depth = -1
+				}
+				depth /= 1.4
+				depth /= 2
+				scale = 0
+			} else {
+				if depth > 1 {
+					depth = 1
+				}
+				depth /= 8
+			}
+			if scale < 0 {
+				scale = 0
+			}
+			scale += 0.5
+			depth = depth * float64(noiseSizeY) / 16
+			center := float64(noiseSizeY)/2 + float64(depth*4)
+			columnIndex++
+
+			for iy := 0; iy < noiseSizeY; iy++ {
+				offset := (float64(iy) - center) * 12 / scale
+				if offset < 0 {
+					offset = float64(offset * 4)
+				}
+				minLimit := g.minValues[index] / 512
+				maxLimit := g.maxValues[index] / 512
+				main := (g.mainValues[index]/10 + 1) / 2
+
+				var density float64
+				if main < 0 {
+					density = minLimit
+				} else if main > 1 {
+					density = maxLimit
+				} else {
+					density = minLimit + float64((maxLimit-minLimit)*main)
+				}
+				density -= offset
+
+				// close the terrain at the top of the world
+				if iy > noiseSizeY-4 {
+					factor := float64(float32(iy-(noiseSizeY-4)) / 3)
+					density = float64(density*(1-factor)) + float64(-10*factor)
+				}
+				g.density[index] = density
+				index++
+			}
+		}
+	}
+}
+
+// generateSurface replaces the top layers of stone with the surface blocks and places the bedrock
+func (g *OverworldGenerator) generateSurface(pos world.ChunkPos, blocks []byte) {
+	const scale = 0.03125
+
+	air := byte(material.Air.Id())
+	stone := byte(material.Stone.Id())
+	sand := byte(material.Sand.Id())
+	gravel := byte(material.Gravel.Id())
+	sandstone := byte(material.Sandstone.Id())
+	water := byte(material.WaterStill.Id())
+	bedrock := byte(material.Bedrock.Id())
+
+	x, z := float64(pos.X*16), float64(pos.Z*16)
+	g.sandValues = g.sandGravelNoise.generate(g.sandValues, x, z, 0, 16, 16, 1, scale, scale, 1)
+	g.gravelValues = g.sandGravelNoise.generate(g.gravelValues, x, 109.0134, z, 16, 1, 16, scale, 1, scale)
+	g.stoneValues = g.stoneNoise.generate(g.stoneValues, x, z, 0, 16, 16, 1, scale*2, scale*2, scale*2)
+
+	for lz := 0; lz < 16; lz++ {
+		for lx := 0; lx < 16; lx++ {
+			column := lz + lx*16
+			biome := g.biomes[column]
+			sandy := g.sandValues[column]+float64(g.random.NextDouble()*0.2) > 0
+			gravelly := g.gravelValues[column]+float64(g.random.NextDouble()*0.2) > 3
+			depth := int(g.stoneValues[column]/3 + 3 + float64(g.random.NextDouble()*0.25))
+
+			topBlock := byte(biome.TopBlock.Id())
+			fillerBlock := byte(biome.FillerBlock.Id())
+			remaining := -1
+
+			for y := 127; y >= 0; y-- {
+				index := (lx*16+lz)*128 + y
+				if y <= int(g.random.NextIntn(5)) {
+					blocks[index] = bedrock
+					continue
+				}
+
+				block := blocks[index]
+				if block == air {
+					remaining = -1
+					continue
+				}
+				if block != stone {
+					continue
+				}
+
+				if remaining == -1 {
+					if depth <= 0 {
+						topBlock = air
+						fillerBlock = stone
+					} else if y >= seaLevel-4 && y <= seaLevel+1 {
+						topBlock = byte(biome.TopBlock.Id())
+						fillerBlock = byte(biome.FillerBlock.Id())
+						if gravelly {
+							topBlock = air
+							fillerBlock = gravel
+						}
+						if sandy {
+							topBlock = sand
+							fillerBlock = sand
+						}
+					}
+					if y < seaLevel && topBlock == air {
+						topBlock = water
+					}
+
+					remaining = depth
+					if y >= seaLevel-1 {
+						blocks[index] = topBlock
+					} else {
+						blocks[index] = fillerBlock
+					}
+				} else if remaining > 0 {
+					remaining--
+					blocks[index] = fillerBlock
+					// sand is held up by sandstone
+					if remaining == 0 && fillerBlock == sand {
+						remaining = int(g.random.NextIntn(4))
+						fillerBlock = sandstone
+					}
+				}
+			}
+		}
+	}
+}
+
+// Biome returns biome of the column at given coordinates.
+func (g *OverworldGenerator) Biome(x, z int32) *world.Biome {
+	return g.biomeSource.Biome(x, z)
+}
+
+// Climate returns temperature and rainfall of the column at given coordinates.
+func (g *OverworldGenerator) Climate(x, z int32) (temperature, rainfall float64) {
+	return g.biomeSource.Climate(x, z)
+}
+
+// CanSpawnAt checks whether players can spawn in the column, the Notchian server spawns them on sand.
+func (g *OverworldGenerator) CanSpawnAt(w *world.World, x, z int32) (bool, error) {
+	block, err := w.FirstUncoveredBlock(x, z)
+	if err != nil {
+		return false, err
+	}
+	return block.Material() == material.Sand, nil
+}
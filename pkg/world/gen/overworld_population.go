@@ -0,0 +1,172 @@
+package gen
+
+import (
+	"github.com/Pesekjak/173go/pkg/world"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// newOverworldDecoration creates the population of the overworld, the decorators are in the order
+// the Notchian server places the features in, so they consume the random the same way.
+func (g *OverworldGenerator) newOverworldDecoration() *Decoration {
+	return NewDecoration(g.seed, g,
+		DecoratorFunc(decorateLakes),
+		DecoratorFunc(decorateDungeons),
+		DecoratorFunc(decorateMinerals),
+		DecoratorFunc(g.decorateTrees),
+		DecoratorFunc(decoratePlants),
+		DecoratorFunc(decorateSprings),
+		DecoratorFunc(g.decorateSnow),
+	)
+}
+
+// decorateLakes places the occasional water lake and the rarer lava lake, mostly deep underground
+func decorateLakes(a *Area) error {
+	r := a.Random
+	if r.NextIntn(4) == 0 {
+		x := a.X + r.NextIntn(16) + 8
+		y := r.NextIntn(128)
+		z := a.Z + r.NextIntn(16) + 8
+		waterLake.place(a, x, y, z)
+	}
+	if r.NextIntn(8) == 0 {
+		x := a.X + r.NextIntn(16) + 8
+		y := r.NextIntn(r.NextIntn(120) + 8)
+		z := a.Z + r.NextIntn(16) + 8
+		if y < seaLevel || r.NextIntn(10) == 0 {
+			lavaLake.place(a, x, y, z)
+		}
+	}
+	return nil
+}
+
+// decorateDungeons attempts to place dungeons
+func decorateDungeons(a *Area) error {
+	r := a.Random
+	for i := 0; i < 8; i++ {
+		x := a.X + r.NextIntn(16) + 8
+		y := r.NextIntn(128)
+		z := a.Z + r.NextIntn(16) + 8
+		dungeon{}.place(a, x, y, z)
+	}
+	return nil
+}
+
+// decorateTrees places trees, their number depends on the biome and the tree noise
+func (g *OverworldGenerator) decorateTrees(a *Area) error {
+	r := a.Random
+	density := int32((g.treeNoise.sample(float64(a.X)*0.5, float64(a.Z)*0.5)/8 + r.NextDouble()*4 + 4) / 3)
+	trees := int32(0)
+	if r.NextIntn(10) == 0 {
+		trees++
+	}
+	switch a.Biome {
+	case world.Forest, world.Rainforest, world.Taiga:
+		trees += density + 5
+	case world.SeasonalForest:
+		trees += density + 2
+	case world.Desert, world.Tundra, world.Plains:
+		trees -= 20
+	}
+
+	for i := int32(0); i < trees; i++ {
+		x := a.X + r.NextIntn(16) + 8
+		z := a.Z + r.NextIntn(16) + 8
+		tree := treeFor(a.Biome, r)
+		tree.place(a, x, a.Height(x, z), z)
+	}
+	return nil
+}
+
+// scatter places the feature count times at random points of the area
+func scatter(a *Area, f feature, count int) {
+	r := a.Random
+	for i := 0; i < count; i++ {
+		x := a.X + r.NextIntn(16) + 8
+		y := r.NextIntn(128)
+		z := a.Z + r.NextIntn(16) + 8
+		f.place(a, x, y, z)
+	}
+}
+
+// scatterRarely places the feature at a random point of the area with a chance of one in given number
+func scatterRarely(a *Area, f feature, chance int32) {
+	if a.Random.NextIntn(chance) == 0 {
+		scatter(a, f, 1)
+	}
+}
+
+// decoratePlants places flowers, grass, mushrooms, sugar canes, pumpkins and cacti
+func decoratePlants(a *Area) error {
+	r := a.Random
+	var flowers, grass, bushes, cactusPatches int
+	switch a.Biome {
+	case world.Forest:
+		flowers, grass = 2, 2
+	case world.Rainforest:
+		grass = 10
+	case world.SeasonalForest:
+		flowers, grass = 4, 2
+	case world.Taiga:
+		flowers, grass = 2, 1
+	case world.Plains:
+		flowers, grass = 3, 10
+	case world.Desert:
+		bushes, cactusPatches = 2, 10
+	}
+
+	scatter(a, dandelions, flowers)
+	for i := 0; i < grass; i++ {
+		patch := tallGrass
+		if a.Biome == world.Rainforest && r.NextIntn(3) != 0 {
+			patch = ferns
+		}
+		scatter(a, patch, 1)
+	}
+	scatter(a, deadBushes, bushes)
+	scatterRarely(a, roses, 2)
+	scatterRarely(a, brownMushrooms, 4)
+	scatterRarely(a, redMushrooms, 8)
+	scatter(a, reeds{}, 10)
+	scatterRarely(a, pumpkins{}, 32)
+	scatter(a, cacti{}, cactusPatches)
+	return nil
+}
+
+// decorateSprings places single water and lava sources in the walls of caves
+func decorateSprings(a *Area) error {
+	r := a.Random
+	for i := 0; i < 50; i++ {
+		x := a.X + r.NextIntn(16) + 8
+		y := r.NextIntn(r.NextIntn(120) + 8)
+		z := a.Z + r.NextIntn(16) + 8
+		waterSpring.place(a, x, y, z)
+	}
+	for i := 0; i < 20; i++ {
+		x := a.X + r.NextIntn(16) + 8
+		y := r.NextIntn(r.NextIntn(r.NextIntn(112)+8) + 8)
+		z := a.Z + r.NextIntn(16) + 8
+		lavaSpring.place(a, x, y, z)
+	}
+	return nil
+}
+
+// decorateSnow covers cold columns with snow, the temperature drops with the height of the column
+func (g *OverworldGenerator) decorateSnow(a *Area) error {
+	g.snowBiomes, g.snowTemperature, g.snowRainfall = g.biomeSource.generate(g.snowBiomes, g.snowTemperature,
+		g.snowRainfall, a.X+8, a.Z+8, 16, 16)
+	for dx := int32(0); dx < 16; dx++ {
+		for dz := int32(0); dz < 16; dz++ {
+			x, z := a.X+8+dx, a.Z+8+dz
+			y := a.TopSolidOrLiquid(x, z)
+			temperature := g.snowTemperature[dx*16+dz] - float64(float64(y-seaLevel)/64*0.3)
+			if temperature >= 0.5 || y <= 0 || y >= 128 || !a.IsEmpty(x, y, z) {
+				continue
+			}
+			below := a.Block(x, y-1, z)
+			if below.Group.IsSolid() && below.Group != material.GroupIce {
+				a.SetBlock(x, y, z, material.SnowLayer, 0)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package gen
+
+// perlinNoise is the improved Perlin noise as implemented by the Notchian server
+type perlinNoise struct {
+	offsetX, offsetY, offsetZ float64
+	permutations              [512]int
+}
+
+func newPerlinNoise(random *Random) *perlinNoise {
+	p := &perlinNoise{
+		offsetX: random.NextDouble() * 256,
+		offsetY: random.NextDouble() * 256,
+		offsetZ: random.NextDouble() * 256,
+	}
+	for i := 0; i < 256; i++ {
+		p.permutations[i] = i
+	}
+	for i := 0; i < 256; i++ {
+		j := int(random.NextIntn(int32(256-i))) + i
+		p.permutations[i], p.permutations[j] = p.permutations[j], p.permutations[i]
+		p.permutations[i+256] = p.permutations[i]
+	}
+	return p
+}
+
+// sample returns the noise value at given coordinates
+func (p *perlinNoise) sample(x, y, z float64) float64 {
+	x += p.offsetX
+	y += p.offsetY
+	z += p.offsetZ
+	xi, yi, zi := floor(x), floor(y), floor(z)
+	x -= float64(xi)
+	y -= float64(yi)
+	z -= float64(zi)
+	xi, yi, zi = xi&255, yi&255, zi&255
+	u, v, w := fade(x), fade(y), fade(z)
+
+	perm := &p.permutations
+	a := perm[xi] + yi
+	aa := perm[a] + zi
+	ab := perm[a+1] + zi
+	b := perm[xi+1] + yi
+	ba := perm[b] + zi
+	bb := perm[b+1] + zi
+
+	return lerp(w,
+		lerp(v,
+			lerp(u, grad(perm[aa], x, y, z), grad(perm[ba], x-1, y, z)),
+			lerp(u, grad(perm[ab], x, y-1, z), grad(perm[bb], x-1, y-1, z))),
+		lerp(v,
+			lerp(u, grad(perm[aa+1], x, y, z-1), grad(perm[ba+1], x-1, y, z-1)),
+			lerp(u, grad(perm[ab+1], x, y-1, z-1), grad(perm[bb+1], x-1, y-1, z-1))))
+}
+
+// add adds the noise of a region to the values, scaled by 1/amplitude. The values are indexed by
+// (x*sizeZ + z)*sizeY + y. A region with height 1 is sampled as 2D noise ignoring the Y coordinate.
+func (p *perlinNoise) add(values []float64, x, y, z float64, sizeX, sizeY, sizeZ int,
+	scaleX, scaleY, scaleZ, amplitude float64) {
+	perm := &p.permutations
+	scale := 1 / amplitude
+
+	if sizeY == 1 {
+		i := 0
+		for ix := 0; ix < sizeX; ix++ {
+			nx := float64((x+float64(ix))*scaleX) + p.offsetX
+			xi := floor(nx)
+			nx -= float64(xi)
+			xi &= 255
+			u := fade(nx)
+			for iz := 0; iz < sizeZ; iz++ {
+				nz := float64((z+float64(iz))*scaleZ) + p.offsetZ
+				zi := floor(nz)
+				nz -= float64(zi)
+				zi &= 255
+				w := fade(nz)
+
+				a := perm[perm[xi]] + zi
+				b := perm[perm[xi+1]] + zi
+				x1 := lerp(u, grad2(perm[a], nx, nz), grad(perm[b], nx-1, 0, nz))
+				x2 := lerp(u, grad(perm[a+1], nx, 0, nz-1), grad(perm[b+1], nx-1, 0, nz-1))
+				values[i] += float64(lerp(w, x1, x2) * scale)
+				i++
+			}
+		}
+		return
+	}
+
+	i := 0
+	lastYi := -1
+	var x1, x2, x3, x4 float64
+	for ix := 0; ix < sizeX; ix++ {
+		nx := float64((x+float64(ix))*scaleX) + p.offsetX
+		xi := floor(nx)
+		nx -= float64(xi)
+		xi &= 255
+		u := fade(nx)
+		for iz := 0; iz < sizeZ; iz++ {
+			nz := float64((z+float64(iz))*scaleZ) + p.offsetZ
+			zi := floor(nz)
+			nz -= float64(zi)
+			zi &= 255
+			w := fade(nz)
+			for iy := 0; iy < sizeY; iy++ {
+				ny := float64((y+float64(iy))*scaleY) + p.offsetY
+				yi := floor(ny)
+				ny -= float64(yi)
+				yi &= 255
+				v := fade(ny)
+
+				// the gradients only change with the Y cell, the Notchian implementation caches them
+				// across columns as well, which has to be preserved
+				if iy == 0 || yi != lastYi {
+					lastYi = yi
+					a := perm[xi] + yi
+					aa := perm[a] + zi
+					ab := perm[a+1] + zi
+					b := perm[xi+1] + yi
+					ba := perm[b] + zi
+					bb := perm[b+1] + zi
+					x1 = lerp(u, grad(perm[aa], nx, ny, nz), grad(perm[ba], nx-1, ny, nz))
+					x2 = lerp(u, grad(perm[ab], nx, ny-1, nz), grad(perm[bb], nx-1, ny-1, nz))
+					x3 = lerp(u, grad(perm[aa+1], nx, ny, nz-1), grad(perm[ba+1], nx-1, ny, nz-1))
+					x4 = lerp(u, grad(perm[ab+1], nx, ny-1, nz-1), grad(perm[bb+1], nx-1, ny-1, nz-1))
+				}
+				values[i] += float64(lerp(w, lerp(v, x1, x2), lerp(v, x3, x4)) * scale)
+				i++
+			}
+		}
+	}
+}
+
+// floor rounds down to int the way the Notchian noise does
+func floor(v float64) int {
+	i := int(v)
+	if v < float64(i) {
+		i--
+	}
+	return i
+}
+
+func fade(t float64) float64 {
+	return t * t * t * (float64(t*(float64(t*6)-15)) + 10)
+}
+
+func lerp(t, a, b float64) float64 {
+	return a + float64(t*(b-a))
+}
+
+func grad(hash int, x, y, z float64) float64 {
+	h := hash & 15
+	u := y
+	if h < 8 {
+		u = x
+	}
+	v := z
+	if h < 4 {
+		v = y
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
+
+func grad2(hash int, x, z float64) float64 {
+	h := hash & 15
+	u := float64(float64(1-((h&8)>>3)) * x)
+	v := z
+	if h < 4 {
+		v = 0
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
@@ -0,0 +1,150 @@
+package gen
+
+import "github.com/Pesekjak/173go/pkg/world/material"
+
+// plantSoil checks whether flowers and grass can grow on the block
+func plantSoil(id byte) bool {
+	return id == byte(material.GrassBlock.Id()) || id == byte(material.Dirt.Id()) || id == byte(material.Farmland.Id())
+}
+
+// plantCanStay checks whether a plant can be at given coordinates
+func plantCanStay(a *Area, plant *material.Block, x, y, z int32) bool {
+	below := a.BlockID(x, y-1, z)
+	switch plant {
+	case material.BrownMushroom, material.RedMushroom:
+		// mushrooms need an opaque block and darkness
+		block, ok := material.BlockFromID(below)
+		return y >= 0 && y < 128 && !a.CanSeeSky(x, y, z) && ok && block.IsOpaqueCube()
+	case material.DeadBush:
+		return below == byte(material.Sand.Id())
+	default:
+		return a.CanSeeSky(x, y, z) && plantSoil(below)
+	}
+}
+
+// plantPatch is a patch of plants scattered around a point
+type plantPatch struct {
+	plant *material.Block
+	data  byte
+	// tries is the number of plants attempted to place
+	tries int
+	// grounded makes the patch start on the highest block below the point that is not air or leaves
+	grounded bool
+}
+
+var (
+	dandelions     = &plantPatch{plant: material.Dandelion, tries: 64}
+	roses          = &plantPatch{plant: material.Rose, tries: 64}
+	brownMushrooms = &plantPatch{plant: material.BrownMushroom, tries: 64}
+	redMushrooms   = &plantPatch{plant: material.RedMushroom, tries: 64}
+	tallGrass      = &plantPatch{plant: material.TallGrass, data: 1, tries: 128, grounded: true}
+	ferns          = &plantPatch{plant: material.TallGrass, data: 2, tries: 128, grounded: true}
+	deadBushes     = &plantPatch{plant: material.DeadBush, tries: 4, grounded: true}
+)
+
+func (p *plantPatch) place(a *Area, x, y, z int32) bool {
+	r := a.Random
+	if p.grounded {
+		for y > 0 && canGrowThrough(a.BlockID(x, y, z)) {
+			y--
+		}
+	}
+	for i := 0; i < p.tries; i++ {
+		px := x + r.NextIntn(8) - r.NextIntn(8)
+		py := y + r.NextIntn(4) - r.NextIntn(4)
+		pz := z + r.NextIntn(8) - r.NextIntn(8)
+		if a.IsEmpty(px, py, pz) && plantCanStay(a, p.plant, px, py, pz) {
+			a.SetBlock(px, py, pz, p.plant, p.data)
+		}
+	}
+	return true
+}
+
+// waterNextTo checks whether there is water next to the block
+func waterNextTo(a *Area, x, y, z int32) bool {
+	for _, side := range [4][2]int32{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+		if a.Block(x+side[0], y, z+side[1]).Group == material.GroupWater {
+			return true
+		}
+	}
+	return false
+}
+
+// reedCanStay checks whether sugar cane can be at given coordinates
+func reedCanStay(a *Area, x, y, z int32) bool {
+	below := a.BlockID(x, y-1, z)
+	if below == byte(material.SugarCaneBlock.Id()) {
+		return true
+	}
+	return (below == byte(material.GrassBlock.Id()) || below == byte(material.Dirt.Id())) && waterNextTo(a, x, y-1, z)
+}
+
+// reeds are sugar canes growing by water
+type reeds struct{}
+
+func (reeds) place(a *Area, x, y, z int32) bool {
+	r := a.Random
+	for i := 0; i < 20; i++ {
+		px := x + r.NextIntn(4) - r.NextIntn(4)
+		pz := z + r.NextIntn(4) - r.NextIntn(4)
+		if !a.IsEmpty(px, y, pz) || !waterNextTo(a, px, y-1, pz) {
+			continue
+		}
+		height := 2 + r.NextIntn(r.NextIntn(3)+1)
+		for dy := int32(0); dy < height; dy++ {
+			if reedCanStay(a, px, y+dy, pz) {
+				a.SetBlock(px, y+dy, pz, material.SugarCaneBlock, 0)
+			}
+		}
+	}
+	return true
+}
+
+// pumpkins are a patch of pumpkins facing random directions
+type pumpkins struct{}
+
+func (pumpkins) place(a *Area, x, y, z int32) bool {
+	r := a.Random
+	for i := 0; i < 64; i++ {
+		px := x + r.NextIntn(8) - r.NextIntn(8)
+		py := y + r.NextIntn(4) - r.NextIntn(4)
+		pz := z + r.NextIntn(8) - r.NextIntn(8)
+		if a.IsEmpty(px, py, pz) && a.Block(px, py-1, pz) == material.GrassBlock {
+			a.SetBlock(px, py, pz, material.Pumpkin, byte(r.NextIntn(4)))
+		}
+	}
+	return true
+}
+
+// cactusCanStay checks whether a cactus can be at given coordinates, it needs sand and free sides
+func cactusCanStay(a *Area, x, y, z int32) bool {
+	for _, side := range [4][2]int32{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+		if a.Block(x+side[0], y, z+side[1]).Group.IsSolid() {
+			return false
+		}
+	}
+	below := a.BlockID(x, y-1, z)
+	return below == byte(material.Cactus.Id()) || below == byte(material.Sand.Id())
+}
+
+// cacti are a few cacti scattered around a point
+type cacti struct{}
+
+func (cacti) place(a *Area, x, y, z int32) bool {
+	r := a.Random
+	for i := 0; i < 10; i++ {
+		px := x + r.NextIntn(8) - r.NextIntn(8)
+		py := y + r.NextIntn(4) - r.NextIntn(4)
+		pz := z + r.NextIntn(8) - r.NextIntn(8)
+		if !a.IsEmpty(px, py, pz) {
+			continue
+		}
+		height := 1 + r.NextIntn(r.NextIntn(3)+1)
+		for dy := int32(0); dy < height; dy++ {
+			if cactusCanStay(a, px, py+dy, pz) {
+				a.SetBlock(px, py+dy, pz, material.Cactus, 0)
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package gen
+
+const (
+	randomMultiplier = 0x5DEECE66D
+	randomAddend     = 0xB
+	randomMask       = (1 << 48) - 1
+)
+
+// Random is a port of java.util.Random. The Notchian generator draws all of its decisions
+// from it, so the exact sequence of numbers is needed to generate the same worlds.
+type Random struct {
+	seed int64
+}
+
+// NewRandom creates a random seeded with given seed.
+func NewRandom(seed int64) *Random {
+	r := &Random{}
+	r.SetSeed(seed)
+	return r
+}
+
+// SetSeed resets the random to given seed.
+func (r *Random) SetSeed(seed int64) {
+	r.seed = (seed ^ randomMultiplier) & randomMask
+}
+
+// next returns the given number of random bits
+func (r *Random) next(bits uint) int32 {
+	r.seed = (r.seed*randomMultiplier + randomAddend) & randomMask
+	return int32(uint64(r.seed) >> (48 - bits))
+}
+
+// NextInt returns a random int32.
+func (r *Random) NextInt() int32 {
+	return r.next(32)
+}
+
+// NextIntn returns a random int32 in range [0, n). It panics if n is not positive.
+func (r *Random) NextIntn(n int32) int32 {
+	if n <= 0 {
+		panic("bound must be positive")
+	}
+	if n&-n == n { // power of two
+		return int32((int64(n) * int64(r.next(31))) >> 31)
+	}
+	for {
+		bits := r.next(31)
+		value := bits % n
+		if bits-value+(n-1) >= 0 { // int32 overflow rejects the biased values
+			return value
+		}
+	}
+}
+
+// NextLong returns a random int64.
+func (r *Random) NextLong() int64 {
+	return int64(r.next(32))<<32 + int64(r.next(32))
+}
+
+// NextBoolean returns a random bool.
+func (r *Random) NextBoolean() bool {
+	return r.next(1) != 0
+}
+
+// NextFloat returns a random float32 in range [0, 1).
+func (r *Random) NextFloat() float32 {
+	return float32(r.next(24)) / float32(1<<24)
+}
+
+// NextDouble returns a random float64 in range [0, 1).
+func (r *Random) NextDouble() float64 {
+	return float64(int64(r.next(26))<<27+int64(r.next(27))) * (1.0 / (1 << 53))
+}
@@ -0,0 +1,57 @@
+package gen
+
+import "testing"
+
+// the expected values are printed by java.util.Random of the same seeds
+
+func TestRandomNextInt(t *testing.T) {
+	tests := []struct {
+		seed int64
+		want []int32
+	}{
+		{0, []int32{-1155484576, -723955400}},
+		{42, []int32{-1170105035, 234785527, -1360544799, 205897768}},
+	}
+	for _, test := range tests {
+		r := NewRandom(test.seed)
+		for i, want := range test.want {
+			if got := r.NextInt(); got != want {
+				t.Errorf("Random(%v) int %v is %v, want %v", test.seed, i, got, want)
+			}
+		}
+	}
+}
+
+func TestRandomNextIntn(t *testing.T) {
+	tests := []struct {
+		seed  int64
+		bound int32
+		want  []int32
+	}{
+		{42, 10, []int32{0, 3, 8, 4, 0, 5, 5, 8}},
+		{0, 100, []int32{60, 48, 29, 47, 15, 53, 91, 61}},
+	}
+	for _, test := range tests {
+		r := NewRandom(test.seed)
+		for i, want := range test.want {
+			if got := r.NextIntn(test.bound); got != want {
+				t.Errorf("Random(%v) int %v below %v is %v, want %v", test.seed, i, test.bound, got, want)
+			}
+		}
+	}
+}
+
+func TestRandomNextValues(t *testing.T) {
+	if got := NewRandom(0).NextLong(); got != -4962768465676381896 {
+		t.Errorf("Random(0) long is %v, want -4962768465676381896", got)
+	}
+	if got := NewRandom(0).NextDouble(); got != 0.730967787376657 {
+		t.Errorf("Random(0) double is %v, want 0.730967787376657", got)
+	}
+	if got := NewRandom(0).NextFloat(); got != 0.73096776 {
+		t.Errorf("Random(0) float is %v, want 0.73096776", got)
+	}
+	if !NewRandom(0).NextBoolean() {
+		t.Error("Random(0) boolean is false, want true")
+	}
+}
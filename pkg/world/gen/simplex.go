@@ -0,0 +1,124 @@
+package gen
+
+import "math"
+
+var (
+	simplexSkew   = 0.5 * (math.Sqrt(3) - 1)
+	simplexUnskew = (3 - math.Sqrt(3)) / 6
+
+	simplexGradients = [12][3]float64{
+		{1, 1, 0}, {-1, 1, 0}, {1, -1, 0}, {-1, -1, 0},
+		{1, 0, 1}, {-1, 0, 1}, {1, 0, -1}, {-1, 0, -1},
+		{0, 1, 1}, {0, -1, 1}, {0, 1, -1}, {0, -1, -1},
+	}
+)
+
+// simplexNoise is the 2D simplex noise used by the Notchian server for the climate
+type simplexNoise struct {
+	offsetX, offsetY, offsetZ float64
+	permutations              [512]int
+}
+
+func newSimplexNoise(random *Random) *simplexNoise {
+	s := &simplexNoise{
+		offsetX: random.NextDouble() * 256,
+		offsetY: random.NextDouble() * 256,
+		offsetZ: random.NextDouble() * 256,
+	}
+	for i := 0; i < 256; i++ {
+		s.permutations[i] = i
+	}
+	for i := 0; i < 256; i++ {
+		j := int(random.NextIntn(int32(256-i))) + i
+		s.permutations[i], s.permutations[j] = s.permutations[j], s.permutations[i]
+		s.permutations[i+256] = s.permutations[i]
+	}
+	return s
+}
+
+// add adds the noise of a region to the values, indexed by x*sizeZ + z
+func (s *simplexNoise) add(values []float64, x, z float64, sizeX, sizeZ int, scaleX, scaleZ, amplitude float64) {
+	perm := &s.permutations
+	i := 0
+	for ix := 0; ix < sizeX; ix++ {
+		nx := float64((x+float64(ix))*scaleX) + s.offsetX
+		for iz := 0; iz < sizeZ; iz++ {
+			// the Notchian implementation uses the Y offset for the Z axis
+			nz := float64((z+float64(iz))*scaleZ) + s.offsetY
+
+			skew := float64((nx + nz) * simplexSkew)
+			cellX := simplexFloor(nx + skew)
+			cellZ := simplexFloor(nz + skew)
+			unskew := float64(float64(cellX+cellZ) * simplexUnskew)
+			x0 := nx - (float64(cellX) - unskew)
+			z0 := nz - (float64(cellZ) - unskew)
+
+			var offsetX, offsetZ int
+			if x0 > z0 {
+				offsetX = 1
+			} else {
+				offsetZ = 1
+			}
+			x1 := x0 - float64(offsetX) + simplexUnskew
+			z1 := z0 - float64(offsetZ) + simplexUnskew
+			x2 := x0 - 1 + float64(2*simplexUnskew)
+			z2 := z0 - 1 + float64(2*simplexUnskew)
+
+			ii := cellX & 255
+			jj := cellZ & 255
+			g0 := perm[ii+perm[jj]] % 12
+			g1 := perm[ii+offsetX+perm[jj+offsetZ]] % 12
+			g2 := perm[ii+1+perm[jj+1]] % 12
+
+			values[i] += float64(70 * (simplexCorner(g0, x0, z0) + simplexCorner(g1, x1, z1) + simplexCorner(g2, x2, z2)) * amplitude)
+			i++
+		}
+	}
+}
+
+func simplexCorner(gradient int, x, z float64) float64 {
+	t := 0.5 - float64(x*x) - float64(z*z)
+	if t < 0 {
+		return 0
+	}
+	t *= t
+	return t * t * (float64(simplexGradients[gradient][0]*x) + float64(simplexGradients[gradient][1]*z))
+}
+
+// simplexFloor rounds down to int the way the Notchian simplex noise does, whole negative numbers included
+func simplexFloor(v float64) int {
+	if v > 0 {
+		return int(v)
+	}
+	return int(v) - 1
+}
+
+// octaveSimplexNoise sums several octaves of simplex noise
+type octaveSimplexNoise struct {
+	octaves []*simplexNoise
+}
+
+func newOctaveSimplexNoise(random *Random, count int) *octaveSimplexNoise {
+	o := &octaveSimplexNoise{octaves: make([]*simplexNoise, count)}
+	for i := range o.octaves {
+		o.octaves[i] = newSimplexNoise(random)
+	}
+	return o
+}
+
+// generate fills the values with noise of a region, indexed by x*sizeZ + z. Frequency of each octave
+// is multiplied by frequencyFactor and its amplitude by 1/persistence.
+func (o *octaveSimplexNoise) generate(values []float64, x, z int32, sizeX, sizeZ int, scaleX, scaleZ,
+	frequencyFactor, persistence float64) []float64 {
+	values = resize(values, sizeX*sizeZ)
+	scaleX /= 1.5
+	scaleZ /= 1.5
+	frequency := 1.0
+	amplitude := 1.0
+	for _, octave := range o.octaves {
+		octave.add(values, float64(x), float64(z), sizeX, sizeZ, scaleX*frequency, scaleZ*frequency, 0.55/amplitude)
+		frequency *= frequencyFactor
+		amplitude *= persistence
+	}
+	return values
+}
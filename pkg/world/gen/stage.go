@@ -0,0 +1,41 @@
+package gen
+
+import "github.com/Pesekjak/173go/pkg/world"
+
+// Stage is a single step of generating the blocks of a chunk. Stages run in order,
+// each of them working with the blocks produced by the previous ones.
+type Stage interface {
+	// Generate generates the blocks of chunk at given position, indexed by x<<11 | z<<7 | y
+	Generate(pos world.ChunkPos, blocks []byte) error
+}
+
+// StageFunc is a function used as a Stage
+type StageFunc func(pos world.ChunkPos, blocks []byte) error
+
+func (f StageFunc) Generate(pos world.ChunkPos, blocks []byte) error {
+	return f(pos, blocks)
+}
+
+// Pipeline is a generator running several stages
+type Pipeline struct {
+	stages []Stage
+}
+
+// NewPipeline creates generator running given stages in order.
+func NewPipeline(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// AddStage adds a stage to the end of the pipeline.
+func (p *Pipeline) AddStage(stage Stage) {
+	p.stages = append(p.stages, stage)
+}
+
+func (p *Pipeline) GenerateBlocks(chunk *world.Chunk) error {
+	for _, stage := range p.stages {
+		if err := stage.Generate(chunk.Pos(), chunk.BlockIDs()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,491 @@
+package gen
+
+import (
+	"math"
+
+	"github.com/Pesekjak/173go/pkg/world"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// wood and leaves data of the tree types
+const (
+	oakData    byte = 0
+	spruceData byte = 1
+	birchData  byte = 2
+)
+
+// treeSoil checks whether a tree can grow on the block
+func treeSoil(id byte) bool {
+	return id == byte(material.GrassBlock.Id()) || id == byte(material.Dirt.Id())
+}
+
+// canGrowThrough checks whether a tree can replace the block
+func canGrowThrough(id byte) bool {
+	return id == 0 || id == byte(material.Leaves.Id())
+}
+
+// leavesCanReplace checks whether leaves can replace the block. The Notchian server
+// treats leaves as opaque cubes, so leaves never replace other leaves.
+func leavesCanReplace(id byte) bool {
+	if id == byte(material.Leaves.Id()) {
+		return false
+	}
+	block, ok := material.BlockFromID(id)
+	return !ok || !block.IsOpaqueCube()
+}
+
+// hasRoomForTree checks whether there is nothing but air and leaves in the space of a tree.
+// The radius returns how far from the trunk the space is checked at given height above the base.
+func hasRoomForTree(a *Area, x, y, z, height int32, radius func(dy int32) int32) bool {
+	if y < 1 || y+height+1 > int32(world.ChunkHeight) {
+		return false
+	}
+	for by := y; by <= y+1+height; by++ {
+		r := radius(by - y)
+		for bx := x - r; bx <= x+r; bx++ {
+			for bz := z - r; bz <= z+r; bz++ {
+				if by < 0 || by >= int32(world.ChunkHeight) || !canGrowThrough(a.BlockID(bx, by, bz)) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// plantTree turns the soil under the tree to dirt, it reports whether the tree can grow there
+func plantTree(a *Area, x, y, z, height int32) bool {
+	if !treeSoil(a.BlockID(x, y-1, z)) || y >= int32(world.ChunkHeight)-height-1 {
+		return false
+	}
+	a.SetBlock(x, y-1, z, material.Dirt, 0)
+	return true
+}
+
+// placeTrunk places the trunk of a tree through air and leaves
+func placeTrunk(a *Area, x, y, z, height int32, data byte) {
+	for i := int32(0); i < height; i++ {
+		if canGrowThrough(a.BlockID(x, y+i, z)) {
+			a.SetBlock(x, y+i, z, material.Wood, data)
+		}
+	}
+}
+
+func abs(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// smallTree is the common oak or birch tree
+type smallTree struct {
+	minHeight int32
+	data      byte
+}
+
+var (
+	oakTree   = &smallTree{minHeight: 4, data: oakData}
+	birchTree = &smallTree{minHeight: 5, data: birchData}
+)
+
+func (t *smallTree) place(a *Area, x, y, z int32) bool {
+	r := a.Random
+	height := r.NextIntn(3) + t.minHeight
+
+	room := hasRoomForTree(a, x, y, z, height, func(dy int32) int32 {
+		switch {
+		case dy >= 1+height-2:
+			return 2
+		case dy == 0:
+			return 0
+		default:
+			return 1
+		}
+	})
+	if !room || !plantTree(a, x, y, z, height) {
+		return false
+	}
+
+	for by := y - 3 + height; by <= y+height; by++ {
+		dy := by - (y + height)
+		radius := 1 - dy/2
+		for bx := x - radius; bx <= x+radius; bx++ {
+			for bz := z - radius; bz <= z+radius; bz++ {
+				// the corners are cut randomly, except for the top layer where they are always cut
+				corner := abs(bx-x) == radius && abs(bz-z) == radius
+				if (!corner || r.NextIntn(2) != 0 && dy != 0) && leavesCanReplace(a.BlockID(bx, by, bz)) {
+					a.SetBlock(bx, by, bz, material.Leaves, t.data)
+				}
+			}
+		}
+	}
+	placeTrunk(a, x, y, z, height, t.data)
+	return true
+}
+
+// pineTree is the tall spruce with leaves only at its top
+type pineTree struct{}
+
+func (pineTree) place(a *Area, x, y, z int32) bool {
+	r := a.Random
+	height := r.NextIntn(5) + 7
+	bareHeight := height - r.NextIntn(2) - 3
+	leavesHeight := height - bareHeight
+	maxRadius := 1 + r.NextIntn(leavesHeight+1)
+
+	room := hasRoomForTree(a, x, y, z, height, func(dy int32) int32 {
+		if dy < bareHeight {
+			return 0
+		}
+		return maxRadius
+	})
+	if !room || !plantTree(a, x, y, z, height) {
+		return false
+	}
+
+	radius := int32(0)
+	for by := y + height; by >= y+bareHeight; by-- {
+		for bx := x - radius; bx <= x+radius; bx++ {
+			for bz := z - radius; bz <= z+radius; bz++ {
+				corner := abs(bx-x) == radius && abs(bz-z) == radius && radius > 0
+				if !corner && leavesCanReplace(a.BlockID(bx, by, bz)) {
+					a.SetBlock(bx, by, bz, material.Leaves, spruceData)
+				}
+			}
+		}
+		if radius >= 1 && by == y+bareHeight+1 {
+			radius--
+		} else if radius < maxRadius {
+			radius++
+		}
+	}
+	placeTrunk(a, x, y, z, height-1, spruceData)
+	return true
+}
+
+// spruceTree is the spruce with layered leaves
+type spruceTree struct{}
+
+func (spruceTree) place(a *Area, x, y, z int32) bool {
+	r := a.Random
+	height := r.NextIntn(4) + 6
+	bareHeight := 1 + r.NextIntn(2)
+	leavesHeight := height - bareHeight
+	maxRadius := 2 + r.NextIntn(2)
+
+	room := hasRoomForTree(a, x, y, z, height, func(dy int32) int32 {
+		if dy < bareHeight {
+			return 0
+		}
+		return maxRadius
+	})
+	if !room || !plantTree(a, x, y, z, height) {
+		return false
+	}
+
+	radius := r.NextIntn(2)
+	layerRadius := int32(1)
+	nextRadius := int32(0)
+	for i := int32(0); i <= leavesHeight; i++ {
+		by := y + height - i
+		for bx := x - radius; bx <= x+radius; bx++ {
+			for bz := z - radius; bz <= z+radius; bz++ {
+				corner := abs(bx-x) == radius && abs(bz-z) == radius && radius > 0
+				if !corner && leavesCanReplace(a.BlockID(bx, by, bz)) {
+					a.SetBlock(bx, by, bz, material.Leaves, spruceData)
+				}
+			}
+		}
+		if radius >= layerRadius {
+			radius = nextRadius
+			nextRadius = 1
+			layerRadius++
+			if layerRadius > maxRadius {
+				layerRadius = maxRadius
+			}
+		} else {
+			radius++
+		}
+	}
+	topGap := r.NextIntn(3)
+	placeTrunk(a, x, y, z, height-topGap, spruceData)
+	return true
+}
+
+// bigTreeAxes maps each axis to the other two axes
+var bigTreeAxes = [6]int{2, 0, 0, 1, 2, 1}
+
+// bigTree is the large oak with branches
+type bigTree struct {
+	area   *Area
+	random *Random
+	base   [3]int32
+
+	height      int32
+	trunkHeight int32
+	// leafDistance is the height of a leaf cluster
+	leafDistance int32
+	leafNodes    [][4]int32
+}
+
+const (
+	bigTreeMaxHeight         = 12
+	bigTreeHeightAttenuation = 0.618
+	bigTreeBranchSlope       = 0.381
+	bigTreeWidthScale        = 1.0
+	bigTreeLeafDensity       = 1.0
+)
+
+func (bigTree) place(a *Area, x, y, z int32) bool {
+	t := &bigTree{
+		area:         a,
+		random:       NewRandom(a.Random.NextLong()),
+		base:         [3]int32{x, y, z},
+		leafDistance: 5,
+	}
+	t.height = 5 + t.random.NextIntn(bigTreeMaxHeight)
+	if !t.validLocation() {
+		return false
+	}
+	t.generateLeafNodes()
+	t.generateLeaves()
+	t.generateTrunk()
+	t.generateBranches()
+	return true
+}
+
+// generateLeafNodes picks positions of the leaf clusters, each of them connected to the trunk by a branch
+func (t *bigTree) generateLeafNodes() {
+	t.trunkHeight = int32(float64(t.height) * bigTreeHeightAttenuation)
+	if t.trunkHeight >= t.height {
+		t.trunkHeight = t.height - 1
+	}
+	perLayer := int32(1.382 + math.Pow(bigTreeLeafDensity*float64(t.height)/13, 2))
+	if perLayer < 1 {
+		perLayer = 1
+	}
+
+	y := t.base[1] + t.height - t.leafDistance
+	trunkTop := t.base[1] + t.trunkHeight
+	layer := y - t.base[1]
+	t.leafNodes = append(t.leafNodes[:0], [4]int32{t.base[0], y, t.base[2], trunkTop})
+	y--
+
+	for ; layer >= 0; layer-- {
+		size := t.layerSize(layer)
+		if size < 0 {
+			y--
+			continue
+		}
+		for i := int32(0); i < perLayer; i++ {
+			distance := bigTreeWidthScale * float64(size) * (float64(t.random.NextFloat()) + 0.328)
+			angle := float64(t.random.NextFloat()) * 2 * 3.14159
+			nodeX := int32(floor(float64(distance*math.Sin(angle)) + float64(t.base[0]) + 0.5))
+			nodeZ := int32(floor(float64(distance*math.Cos(angle)) + float64(t.base[2]) + 0.5))
+			node := [3]int32{nodeX, y, nodeZ}
+			if t.checkLine(node, [3]int32{nodeX, y + t.leafDistance, nodeZ}) != -1 {
+				continue
+			}
+			branchBase := t.base
+			dx := float64(abs(t.base[0] - node[0]))
+			dz := float64(abs(t.base[2] - node[2]))
+			drop := float64(math.Sqrt(float64(dx*dx)+float64(dz*dz)) * bigTreeBranchSlope)
+			if float64(node[1])-drop > float64(trunkTop) {
+				branchBase[1] = trunkTop
+			} else {
+				branchBase[1] = int32(float64(node[1]) - drop)
+			}
+			if t.checkLine(branchBase, node) == -1 {
+				t.leafNodes = append(t.leafNodes, [4]int32{nodeX, y, nodeZ, branchBase[1]})
+			}
+		}
+		y--
+	}
+}
+
+// layerSize returns the radius of the leaves at given layer above the base, or a negative value for no leaves
+func (t *bigTree) layerSize(layer int32) float32 {
+	if float64(layer) < float64(float64(float32(t.height))*0.3) {
+		return -1.618
+	}
+	half := float32(t.height) / 2
+	offset := float32(t.height)/2 - float32(layer)
+	var size float32
+	switch {
+	case offset == 0:
+		size = half
+	case float32(math.Abs(float64(offset))) >= half:
+		size = 0
+	default:
+		size = float32(math.Sqrt(math.Pow(math.Abs(float64(half)), 2) - math.Pow(math.Abs(float64(offset)), 2)))
+	}
+	return size * 0.5
+}
+
+// leafSize returns the radius of a layer of a leaf cluster
+func (t *bigTree) leafSize(layer int32) float32 {
+	if layer < 0 || layer >= t.leafDistance {
+		return -1
+	}
+	if layer == 0 || layer == t.leafDistance-1 {
+		return 2
+	}
+	return 3
+}
+
+// generateLayer places a disc of the block perpendicular to the axis
+func (t *bigTree) generateLayer(center [3]int32, size float32, axis int, block *material.Block) {
+	extent := int32(float64(size) + 0.618)
+	axisA := bigTreeAxes[axis]
+	axisB := bigTreeAxes[axis+3]
+	var pos [3]int32
+	pos[axis] = center[axis]
+	for i := -extent; i <= extent; i++ {
+		pos[axisA] = center[axisA] + i
+		for j := -extent; j <= extent; j++ {
+			distance := math.Sqrt(math.Pow(float64(abs(i))+0.5, 2) + math.Pow(float64(abs(j))+0.5, 2))
+			if distance > float64(size) {
+				continue
+			}
+			pos[axisB] = center[axisB] + j
+			if canGrowThrough(t.area.BlockID(pos[0], pos[1], pos[2])) {
+				t.area.SetBlock(pos[0], pos[1], pos[2], block, 0)
+			}
+		}
+	}
+}
+
+func (t *bigTree) generateLeaves() {
+	for _, node := range t.leafNodes {
+		for y := node[1]; y < node[1]+t.leafDistance; y++ {
+			t.generateLayer([3]int32{node[0], y, node[2]}, t.leafSize(y-node[1]), 1, material.Leaves)
+		}
+	}
+}
+
+func (t *bigTree) generateTrunk() {
+	top := t.base
+	top[1] += t.trunkHeight
+	t.placeLine(t.base, top, material.Wood)
+}
+
+// generateBranches connects the leaf clusters high enough to the trunk
+func (t *bigTree) generateBranches() {
+	for _, node := range t.leafNodes {
+		start := [3]int32{t.base[0], node[3], t.base[2]}
+		if float64(node[3]-t.base[1]) >= float64(t.height)*0.2 {
+			t.placeLine(start, [3]int32{node[0], node[1], node[2]}, material.Wood)
+		}
+	}
+}
+
+// lineAxes returns the offset between the points, the axis with the largest offset and the two other axes
+func lineAxes(from, to [3]int32) (offset [3]int32, major, axisA, axisB int) {
+	for i := range offset {
+		offset[i] = to[i] - from[i]
+		if abs(offset[i]) > abs(offset[major]) {
+			major = i
+		}
+	}
+	return offset, major, bigTreeAxes[major], bigTreeAxes[major+3]
+}
+
+// placeLine places a line of the block between the points
+func (t *bigTree) placeLine(from, to [3]int32, block *material.Block) {
+	offset, major, axisA, axisB := lineAxes(from, to)
+	if offset[major] == 0 {
+		return
+	}
+	step := int32(1)
+	if offset[major] < 0 {
+		step = -1
+	}
+	ratioA := float64(offset[axisA]) / float64(offset[major])
+	ratioB := float64(offset[axisB]) / float64(offset[major])
+	var pos [3]int32
+	for i := int32(0); i != offset[major]+step; i += step {
+		pos[major] = int32(floor(float64(from[major]+i) + 0.5))
+		pos[axisA] = int32(floor(float64(from[axisA]) + float64(float64(i)*ratioA) + 0.5))
+		pos[axisB] = int32(floor(float64(from[axisB]) + float64(float64(i)*ratioB) + 0.5))
+		t.area.SetBlock(pos[0], pos[1], pos[2], block, 0)
+	}
+}
+
+// checkLine returns the distance to the first block other than air and leaves on the line between the points,
+// or -1 if the line is free
+func (t *bigTree) checkLine(from, to [3]int32) int32 {
+	offset, major, axisA, axisB := lineAxes(from, to)
+	if offset[major] == 0 {
+		return -1
+	}
+	step := int32(1)
+	if offset[major] < 0 {
+		step = -1
+	}
+	ratioA := float64(offset[axisA]) / float64(offset[major])
+	ratioB := float64(offset[axisB]) / float64(offset[major])
+	var pos [3]int32
+	i := int32(0)
+	end := offset[major] + step
+	for ; i != end; i += step {
+		pos[major] = from[major] + i
+		pos[axisA] = int32(floor(float64(from[axisA]) + float64(float64(i)*ratioA)))
+		pos[axisB] = int32(floor(float64(from[axisB]) + float64(float64(i)*ratioB)))
+		if !canGrowThrough(t.area.BlockID(pos[0], pos[1], pos[2])) {
+			break
+		}
+	}
+	if i == end {
+		return -1
+	}
+	return abs(i)
+}
+
+// validLocation checks the soil and shortens the tree if something is in the way of its trunk
+func (t *bigTree) validLocation() bool {
+	if !treeSoil(t.area.BlockID(t.base[0], t.base[1]-1, t.base[2])) {
+		return false
+	}
+	top := t.base
+	top[1] += t.height - 1
+	distance := t.checkLine(t.base, top)
+	switch {
+	case distance == -1:
+		return true
+	case distance < 6:
+		return false
+	default:
+		t.height = distance
+		return true
+	}
+}
+
+// treeFor picks a tree growing in the biome
+func treeFor(biome *world.Biome, r *Random) feature {
+	switch biome {
+	case world.Forest:
+		if r.NextIntn(5) == 0 {
+			return birchTree
+		}
+		if r.NextIntn(3) == 0 {
+			return bigTree{}
+		}
+		return oakTree
+	case world.Rainforest:
+		if r.NextIntn(3) == 0 {
+			return bigTree{}
+		}
+		return oakTree
+	case world.Taiga:
+		if r.NextIntn(3) == 0 {
+			return pineTree{}
+		}
+		return spruceTree{}
+	default:
+		if r.NextIntn(10) == 0 {
+			return bigTree{}
+		}
+		return oakTree
+	}
+}
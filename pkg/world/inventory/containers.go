@@ -0,0 +1,89 @@
+package inventory
+
+const (
+	// WorkbenchGridSize is the number of the slots of the crafting grid of a workbench
+	WorkbenchGridSize = 9
+
+	// The slots of a furnace
+	FurnaceIngredientSlot = 0
+	FurnaceFuelSlot       = 1
+	FurnaceResultSlot     = 2
+)
+
+// NewChestWindow creates the window of a chest, the window of a double chest if two inventories are given.
+func NewChestWindow(id byte, player *PlayerInventory, chests ...*Inventory) *Window {
+	var slots []*Slot
+	for _, chest := range chests {
+		for i := 0; i < chest.Size(); i++ {
+			slots = append(slots, &Slot{Inventory: chest, Index: i})
+		}
+	}
+	size := len(slots)
+	name := "Chest"
+	if len(chests) > 1 {
+		name = "Large chest"
+	}
+	slots = append(slots, player.MainSlots()...)
+	return NewWindow(id, Chest, name, byte(size), slots, func(slot int) (int, int, bool) {
+		if slot < size {
+			return size, len(slots), true
+		}
+		return 0, size, false
+	})
+}
+
+// NewWorkbenchWindow creates the window of a workbench with the crafting grid and the result of the player.
+func NewWorkbenchWindow(id byte, player *PlayerInventory, grid, result *Inventory) *Window {
+	slots := []*Slot{{Inventory: result, Index: 0, Accepts: acceptsNothing}}
+	for i := 0; i < grid.Size(); i++ {
+		slots = append(slots, &Slot{Inventory: grid, Index: i})
+	}
+	size := len(slots)
+	slots = append(slots, player.MainSlots()...)
+	// the client is told about the slots of the grid only
+	return NewWindow(id, Crafting, "Crafting", WorkbenchGridSize, slots, craftingTransfer(size, 0))
+}
+
+// NewFurnaceWindow creates the window of a furnace, progress returns the values of its progress bars.
+func NewFurnaceWindow(id byte, player *PlayerInventory, furnace *Inventory, progress func() []int16) *Window {
+	slots := []*Slot{
+		{Inventory: furnace, Index: FurnaceIngredientSlot},
+		{Inventory: furnace, Index: FurnaceFuelSlot},
+		{Inventory: furnace, Index: FurnaceResultSlot, Accepts: acceptsNothing},
+	}
+	size := len(slots)
+	slots = append(slots, player.MainSlots()...)
+	window := NewWindow(id, Furnace, "Furnace", byte(size), slots, craftingTransfer(size, FurnaceResultSlot))
+	window.ProgressBars = progress
+	return window
+}
+
+// NewDispenserWindow creates the window of a dispenser. Clicks with shift move nothing in the dispenser,
+// as on Notchian.
+func NewDispenserWindow(id byte, player *PlayerInventory, dispenser *Inventory) *Window {
+	var slots []*Slot
+	for i := 0; i < dispenser.Size(); i++ {
+		slots = append(slots, &Slot{Inventory: dispenser, Index: i})
+	}
+	size := len(slots)
+	slots = append(slots, player.MainSlots()...)
+	return NewWindow(id, Dispenser, "Trap", byte(size), slots, nil)
+}
+
+// craftingTransfer moves the result to the main inventory, filled from its end, and the stacks between
+// the hotbar and the rest of the main inventory. The main inventory follows the first size slots of the window,
+// nothing is moved to them.
+func craftingTransfer(size, result int) TransferTarget {
+	hotbar := size + MainSize - HotbarSize
+	return func(slot int) (int, int, bool) {
+		switch {
+		case slot == result:
+			return size, size + MainSize, true
+		case slot >= size && slot < hotbar:
+			return hotbar, size + MainSize, false
+		case slot >= hotbar:
+			return size, hotbar, false
+		}
+		return size, size + MainSize, false
+	}
+}
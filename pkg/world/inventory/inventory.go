@@ -9,8 +9,67 @@ const (
 	Dispenser
 )
 
-type Window interface {
-	Type() Type
-	Name() string
-	Size() byte
+// Inventory holds item stacks in a fixed number of slots.
+type Inventory struct {
+	slots []ItemStack
+}
+
+// NewInventory creates an inventory with given number of empty slots.
+func NewInventory(size int) *Inventory {
+	inventory := &Inventory{slots: make([]ItemStack, size)}
+	inventory.Clear()
+	return inventory
+}
+
+// Size returns the number of slots of the inventory.
+func (i *Inventory) Size() int {
+	return len(i.slots)
+}
+
+// Slot returns the stack in the slot.
+func (i *Inventory) Slot(slot int) ItemStack {
+	return i.slots[slot]
+}
+
+// SetSlot puts the stack in the slot, replacing the previous stack.
+func (i *Inventory) SetSlot(slot int, stack ItemStack) {
+	if stack.IsEmpty() {
+		stack = EmptyStack()
+	}
+	i.slots[slot] = stack
+}
+
+// Clear empties all slots.
+func (i *Inventory) Clear() {
+	for slot := range i.slots {
+		i.slots[slot] = EmptyStack()
+	}
+}
+
+// Add puts the items of the stack into the inventory, first onto the stacks of the same items,
+// then into the empty slots. It returns the items that did not fit.
+func (i *Inventory) Add(stack ItemStack) ItemStack {
+	for slot, current := range i.slots {
+		if stack.IsEmpty() {
+			break
+		}
+		if current.StacksWith(stack) && current.Count < current.MaxStackSize() {
+			moved := min(stack.Count, current.MaxStackSize()-current.Count)
+			current.Count += moved
+			stack.Count -= moved
+			i.slots[slot] = current
+		}
+	}
+	for slot, current := range i.slots {
+		if stack.IsEmpty() {
+			break
+		}
+		if current.IsEmpty() {
+			i.slots[slot] = stack.Split(stack.MaxStackSize())
+		}
+	}
+	if stack.IsEmpty() {
+		return EmptyStack()
+	}
+	return stack
 }
@@ -28,6 +28,44 @@ func (s ItemStack) IsEmpty() bool {
 	return s.Count == 0 || s.Material == material.Air
 }
 
+// MaxStackSize returns the maximum number of items of the stack's material in a single stack.
+func (s ItemStack) MaxStackSize() byte {
+	if item, ok := s.Material.(*material.Item); ok {
+		return byte(item.MaxStackSize())
+	}
+	return 64
+}
+
+// StacksWith returns true if the items of both stacks are the same and can be in a single stack.
+func (s ItemStack) StacksWith(other ItemStack) bool {
+	return !s.IsEmpty() && !other.IsEmpty() && s.Material == other.Material && s.Data == other.Data
+}
+
+// Split removes up to count items from the stack and returns them as a new stack.
+func (s *ItemStack) Split(count byte) ItemStack {
+	count = min(count, s.Count)
+	split := NewItemStack(s.Material, count, s.Data)
+	s.Count -= count
+	if s.Count == 0 {
+		*s = EmptyStack()
+	}
+	return split
+}
+
+// Damage wears the item of the stack down, the item breaks once the damage exceeds its durability.
+// Items without durability are not damaged.
+func (s *ItemStack) Damage(amount uint16) {
+	item, ok := s.Material.(*material.Item)
+	if !ok || !item.HasDurability() || s.IsEmpty() {
+		return
+	}
+	s.Data += amount
+	if s.Data > item.MaxDamage() {
+		s.Split(1)
+		s.Data = 0
+	}
+}
+
 func (s ItemStack) String() string {
 	return strconv.Itoa(int(s.Count)) + "x " + s.Material.String()
 }
@@ -0,0 +1,106 @@
+package inventory
+
+import "github.com/Pesekjak/173go/pkg/world/material"
+
+const (
+	// HotbarSize is the number of the slots of the hotbar, the first slots of the main inventory
+	HotbarSize = 9
+	// MainSize is the number of the slots of the main inventory, including the hotbar
+	MainSize = 36
+	// ArmorSize is the number of the armor slots
+	ArmorSize = 4
+	// PlayerGridSize is the number of the slots of the player's crafting grid
+	PlayerGridSize = 4
+)
+
+// The slots of the player's own window, the window with id 0
+const (
+	PlayerResultSlot  = 0
+	PlayerGridStart   = 1
+	PlayerArmorStart  = 5
+	PlayerMainStart   = 9
+	PlayerHotbarStart = 36
+	PlayerWindowSize  = 45
+)
+
+// PlayerInventory holds the items of a player.
+type PlayerInventory struct {
+	// Main holds the main inventory, the first slots are the hotbar
+	Main *Inventory
+	// Armor holds the worn armor, from the boots to the helmet
+	Armor *Inventory
+	// Grid is the 2x2 crafting grid of the player and Result is the crafted item
+	Grid   *Inventory
+	Result *Inventory
+	// Held is the selected slot of the hotbar
+	Held int
+	// Cursor is the stack the player moves with the mouse in an open window
+	Cursor ItemStack
+
+	window *Window
+}
+
+func NewPlayerInventory() *PlayerInventory {
+	p := &PlayerInventory{
+		Main:   NewInventory(MainSize),
+		Armor:  NewInventory(ArmorSize),
+		Grid:   NewInventory(PlayerGridSize),
+		Result: NewInventory(1),
+		Cursor: EmptyStack(),
+	}
+
+	slots := make([]*Slot, 0, PlayerWindowSize)
+	slots = append(slots, &Slot{Inventory: p.Result, Index: 0, Accepts: acceptsNothing})
+	for i := 0; i < PlayerGridSize; i++ {
+		slots = append(slots, &Slot{Inventory: p.Grid, Index: i})
+	}
+	// the window shows the armor from the helmet to the boots
+	for i, equipSlot := range [...]material.EquipSlot{material.SlotHead, material.SlotChest, material.SlotLegs, material.SlotFeet} {
+		slots = append(slots, &Slot{Inventory: p.Armor, Index: ArmorSize - 1 - i, Accepts: acceptsArmor(equipSlot), MaxCount: 1})
+	}
+	slots = append(slots, p.MainSlots()...)
+	p.window = NewWindow(0, Crafting, "", 0, slots, craftingTransfer(PlayerMainStart, PlayerResultSlot))
+	return p
+}
+
+// Window returns the player's own window, with the crafting grid, the armor and the main inventory.
+func (p *PlayerInventory) Window() *Window {
+	return p.window
+}
+
+// MainSlots creates the slots of the main inventory in the order they are shown at the bottom of the windows,
+// the hotbar is the last.
+func (p *PlayerInventory) MainSlots() []*Slot {
+	slots := make([]*Slot, 0, MainSize)
+	for i := HotbarSize; i < MainSize; i++ {
+		slots = append(slots, &Slot{Inventory: p.Main, Index: i})
+	}
+	for i := 0; i < HotbarSize; i++ {
+		slots = append(slots, &Slot{Inventory: p.Main, Index: i})
+	}
+	return slots
+}
+
+// HeldStack returns the stack in the selected slot of the hotbar.
+func (p *PlayerInventory) HeldStack() ItemStack {
+	return p.Main.Slot(p.Held)
+}
+
+// SetHeldStack puts the stack in the selected slot of the hotbar.
+func (p *PlayerInventory) SetHeldStack(stack ItemStack) {
+	p.Main.SetSlot(p.Held, stack)
+}
+
+func acceptsNothing(ItemStack) bool {
+	return false
+}
+
+// acceptsArmor accepts the armor worn in the slot, pumpkins are worn on the head too
+func acceptsArmor(slot material.EquipSlot) func(ItemStack) bool {
+	return func(stack ItemStack) bool {
+		if item, ok := stack.Material.(*material.Item); ok {
+			return item.EquipSlot() == slot
+		}
+		return slot == material.SlotHead && stack.Material == material.Pumpkin
+	}
+}
@@ -0,0 +1,286 @@
+package inventory
+
+// OutsideSlot is the slot number of the clicks outside of a window, they throw the items on the cursor away
+const OutsideSlot = -999
+
+// Slot is a slot shown in a window, backed by a slot of an inventory.
+type Slot struct {
+	Inventory *Inventory
+	Index     int
+	// Accepts checks whether the player can put the stack into the slot, nil accepts any stack
+	Accepts func(stack ItemStack) bool
+	// MaxCount limits the number of items in the slot below the max stack size, 0 for no limit
+	MaxCount byte
+	// OnTake is called after the player takes the stack from the slot
+	OnTake func(stack ItemStack)
+}
+
+// Stack returns the stack in the slot.
+func (s *Slot) Stack() ItemStack {
+	return s.Inventory.Slot(s.Index)
+}
+
+// Set puts the stack in the slot.
+func (s *Slot) Set(stack ItemStack) {
+	s.Inventory.SetSlot(s.Index, stack)
+}
+
+func (s *Slot) accepts(stack ItemStack) bool {
+	return s.Accepts == nil || s.Accepts(stack)
+}
+
+// maxCount returns the number of the items of the stack the slot holds
+func (s *Slot) maxCount(stack ItemStack) byte {
+	if s.MaxCount > 0 {
+		return min(s.MaxCount, stack.MaxStackSize())
+	}
+	return stack.MaxStackSize()
+}
+
+func (s *Slot) take(stack ItemStack) {
+	if s.OnTake != nil {
+		s.OnTake(stack)
+	}
+}
+
+// TransferTarget returns the range of the slots a stack is moved to when its slot is shift clicked,
+// and whether the range is filled from its end
+type TransferTarget func(slot int) (start, end int, reverse bool)
+
+// Window is a set of slots of one or more inventories a player works with at once, like the player's own
+// inventory or an opened chest together with the inventory of the player.
+//
+// The window remembers the stacks last sent to the player, the changes made by others can be sent later.
+type Window struct {
+	Id         byte
+	windowType Type
+	name       string
+	// size is the number of the slots of the opened container, without the slots of the player's inventory
+	size     byte
+	Slots    []*Slot
+	transfer TransferTarget
+	// sent are the stacks of the slots as they were last sent to the player
+	sent []ItemStack
+	// ProgressBars returns the values of the progress bars shown in the window, like the progress of smelting
+	// in a furnace, nil for the windows without them
+	ProgressBars func() []int16
+	sentBars     []int16
+}
+
+// NewWindow creates a window with given slots, the first size slots belong to the opened container.
+func NewWindow(id byte, windowType Type, name string, size byte, slots []*Slot, transfer TransferTarget) *Window {
+	window := &Window{
+		Id:         id,
+		windowType: windowType,
+		name:       name,
+		size:       size,
+		Slots:      slots,
+		transfer:   transfer,
+		sent:       make([]ItemStack, len(slots)),
+	}
+	for i := range window.sent {
+		window.sent[i] = EmptyStack()
+	}
+	return window
+}
+
+func (w *Window) Type() Type {
+	return w.windowType
+}
+
+func (w *Window) Name() string {
+	return w.name
+}
+
+func (w *Window) Size() byte {
+	return w.size
+}
+
+// Click performs a click of the player at the slot of the window, as the Notchian server does. The left button
+// takes, puts or swaps the whole stack, the right button takes a half of the stack or puts a single item, and
+// a click with shift moves the stack to the other part of the window. Clicks outside of the window throw
+// the items on the cursor away.
+//
+// It returns the stack in the clicked slot before the click, which the client sends to be compared,
+// and the items thrown away. It fails if the slot is not in the window.
+func (w *Window) Click(slot int, right, shift bool, cursor *ItemStack) (clicked, thrown ItemStack, ok bool) {
+	clicked, thrown = EmptyStack(), EmptyStack()
+	if slot == OutsideSlot {
+		if right {
+			thrown = cursor.Split(1)
+		} else {
+			thrown, *cursor = *cursor, EmptyStack()
+		}
+		return clicked, thrown, true
+	}
+	if slot < 0 || slot >= len(w.Slots) {
+		return clicked, thrown, false
+	}
+	if shift {
+		return w.shiftClick(slot), thrown, true
+	}
+
+	s := w.Slots[slot]
+	stack := s.Stack()
+	clicked = stack
+	switch {
+	case stack.IsEmpty():
+		if !cursor.IsEmpty() && s.accepts(*cursor) {
+			count := cursor.Count
+			if right {
+				count = 1
+			}
+			s.Set(cursor.Split(min(count, s.maxCount(*cursor))))
+		}
+	case cursor.IsEmpty():
+		// half of the stack is taken with the right button, but the whole stack from the slots taking no items,
+		// taking a crafting result consumes the ingredients of the whole stack
+		count := stack.Count
+		if right && s.accepts(stack) {
+			count = (stack.Count + 1) / 2
+		}
+		*cursor = stack.Split(count)
+		s.Set(stack)
+		s.take(*cursor)
+	case s.accepts(*cursor):
+		if stack.StacksWith(*cursor) {
+			count := int(cursor.Count)
+			if right {
+				count = 1
+			}
+			count = max(min(count, int(s.maxCount(stack))-int(stack.Count)), 0)
+			cursor.Split(byte(count))
+			stack.Count += byte(count)
+			s.Set(stack)
+		} else if cursor.Count <= s.maxCount(*cursor) {
+			s.Set(*cursor)
+			*cursor = stack
+		}
+	case stack.StacksWith(*cursor) && cursor.MaxStackSize() > 1:
+		// the slot takes no items, like a crafting result, the whole stack is taken if it fits to the cursor
+		if int(stack.Count)+int(cursor.Count) <= int(cursor.MaxStackSize()) {
+			cursor.Count += stack.Count
+			s.Set(EmptyStack())
+			s.take(stack)
+		}
+	}
+	return clicked, thrown, true
+}
+
+// shiftClick moves the stack of the slot to the other part of the window. It returns the stack as it was
+// before it was moved, or an empty stack if nothing could be moved.
+func (w *Window) shiftClick(slot int) ItemStack {
+	if w.transfer == nil {
+		return EmptyStack()
+	}
+	s := w.Slots[slot]
+	first := EmptyStack()
+	for {
+		stack := s.Stack()
+		if stack.IsEmpty() || (!first.IsEmpty() && stack.Material != first.Material) {
+			break
+		}
+		start, end, reverse := w.transfer(slot)
+		left := stack
+		w.merge(&left, start, end, reverse)
+		if left.Count == stack.Count {
+			break
+		}
+		if first.IsEmpty() {
+			first = stack
+		}
+		s.Set(left)
+		s.take(NewItemStack(stack.Material, stack.Count-left.Count, stack.Data))
+		// slots filled again after taking their stack, like a crafting result, are moved again
+	}
+	return first
+}
+
+// merge moves the items of the stack to the slots in the range, onto the stacks of the same items first
+// and then to the first empty slot
+func (w *Window) merge(stack *ItemStack, start, end int, reverse bool) {
+	indices := make([]int, 0, end-start)
+	for i := start; i < end; i++ {
+		if reverse {
+			indices = append(indices, end-1-(i-start))
+		} else {
+			indices = append(indices, i)
+		}
+	}
+
+	if stack.MaxStackSize() > 1 {
+		for _, i := range indices {
+			if stack.IsEmpty() {
+				break
+			}
+			current := w.Slots[i].Stack()
+			if current.StacksWith(*stack) && current.Count < stack.MaxStackSize() {
+				moved := min(stack.Count, stack.MaxStackSize()-current.Count)
+				current.Count += moved
+				stack.Count -= moved
+				w.Slots[i].Set(current)
+			}
+		}
+	}
+	for _, i := range indices {
+		if stack.IsEmpty() {
+			break
+		}
+		if w.Slots[i].Stack().IsEmpty() {
+			w.Slots[i].Set(*stack)
+			stack.Count = 0
+		}
+	}
+	if stack.Count == 0 {
+		*stack = EmptyStack()
+	}
+}
+
+// Changes returns the slots whose stacks changed since they were last sent to the player
+// and remembers their stacks as sent.
+func (w *Window) Changes() []int {
+	var changed []int
+	for i, s := range w.Slots {
+		if stack := s.Stack(); stack != w.sent[i] {
+			w.sent[i] = stack
+			changed = append(changed, i)
+		}
+	}
+	return changed
+}
+
+// Contents returns the stacks of all slots and remembers them as sent to the player.
+// The progress bars are sent again with the next changes.
+func (w *Window) Contents() []ItemStack {
+	for i, s := range w.Slots {
+		w.sent[i] = s.Stack()
+	}
+	w.sentBars = nil
+	return append([]ItemStack(nil), w.sent...)
+}
+
+// ProgressBarChanges returns the indexes of the progress bars changed since they were last sent to the player
+// and remembers their values as sent.
+func (w *Window) ProgressBarChanges() []int {
+	if w.ProgressBars == nil {
+		return nil
+	}
+	var changed []int
+	bars := w.ProgressBars()
+	for i, value := range bars {
+		if i >= len(w.sentBars) || w.sentBars[i] != value {
+			changed = append(changed, i)
+		}
+	}
+	w.sentBars = bars
+	return changed
+}
+
+// Resend forgets the stack last sent for the slot of the inventory, so it is sent with the next changes.
+func (w *Window) Resend(inventory *Inventory, index int) {
+	for i, s := range w.Slots {
+		if s.Inventory == inventory && s.Index == index {
+			w.sent[i] = ItemStack{}
+		}
+	}
+}
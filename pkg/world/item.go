@@ -0,0 +1,278 @@
+package world
+
+import (
+	"math"
+
+	"github.com/Pesekjak/173go/pkg/base"
+	"github.com/Pesekjak/173go/pkg/prot"
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/loot"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+const (
+	// itemLifetime is the number of ticks a dropped item stays in the world, five minutes
+	itemLifetime = 6000
+	// itemPickupDelay and thrownItemPickupDelay are the numbers of ticks before the dropped items can be picked up
+	itemPickupDelay       = 10
+	thrownItemPickupDelay = 40
+	// itemGravity and itemDrag change the vertical motion of the items every tick
+	itemGravity = 0.04
+	itemDrag    = 0.98
+	// voidDepth is the height below which the items fallen out of the world are removed
+	voidDepth = -64
+	// pickupReach is the horizontal distance from a player within which the player picks up items
+	pickupReach = 1.3
+)
+
+// ItemEntity is an item stack dropped in the world.
+//
+// The server simulates only the fall of the items, their horizontal motion is small and it is left
+// to the clients.
+type ItemEntity struct {
+	id       int32
+	world    *World
+	location Location
+	// motion is the velocity of the item in blocks per tick, only the vertical motion changes
+	motion      [3]float64
+	stack       inventory.ItemStack
+	age         int
+	pickupDelay int
+}
+
+func (i *ItemEntity) Id() int32 {
+	return i.id
+}
+
+func (i *ItemEntity) Location() Location {
+	return i.location
+}
+
+func (i *ItemEntity) World() *World {
+	return i.world
+}
+
+func (i *ItemEntity) EntityType() EntityType {
+	return Item
+}
+
+// Stack returns the dropped item stack.
+func (i *ItemEntity) Stack() inventory.ItemStack {
+	return i.stack
+}
+
+// spawnPacket creates the packet showing the item to a player
+func (i *ItemEntity) spawnPacket() *prot.PacketOutPickupSpawn {
+	return &prot.PacketOutPickupSpawn{
+		EntityId: i.id,
+		ItemId:   int16(i.stack.Material.Id()),
+		Count:    i.stack.Count,
+		Damage:   int16(i.stack.Data),
+		X:        int32(math.Floor(i.location.X * 32)),
+		Y:        int32(math.Floor(i.location.Y * 32)),
+		Z:        int32(math.Floor(i.location.Z * 32)),
+		// the client reads the motion of the item from its rotation
+		Rotation: byte(int8(i.motion[0] * 128)),
+		Pitch:    byte(int8(i.motion[1] * 128)),
+		Roll:     byte(int8(i.motion[2] * 128)),
+	}
+}
+
+// DropItem spawns the item stack at given location, with a small random motion like the items
+// dropped from broken blocks. The item is shown to the players who have its chunk loaded.
+func (w *World) DropItem(location Location, stack inventory.ItemStack) (*ItemEntity, error) {
+	motion := [3]float64{w.random.Float64()*0.2 - 0.1, 0.2, w.random.Float64()*0.2 - 0.1}
+	return w.spawnItem(location, motion, stack, itemPickupDelay)
+}
+
+// ThrowItem spawns the item stack thrown by a player from the location of the player's eyes
+// in the direction the player looks.
+func (w *World) ThrowItem(eyes Location, stack inventory.ItemStack) (*ItemEntity, error) {
+	const speed, spread = 0.3, 0.02
+	yaw := float64(eyes.Yaw) / 180 * math.Pi
+	pitch := float64(eyes.Pitch) / 180 * math.Pi
+	motion := [3]float64{
+		-math.Sin(yaw) * math.Cos(pitch) * speed,
+		-math.Sin(pitch)*speed + 0.1,
+		math.Cos(yaw) * math.Cos(pitch) * speed,
+	}
+	angle := w.random.Float64() * math.Pi * 2
+	distance := spread * w.random.Float64()
+	motion[0] += math.Cos(angle) * distance
+	motion[1] += (w.random.Float64() - w.random.Float64()) * 0.1
+	motion[2] += math.Sin(angle) * distance
+
+	location := eyes.Add(0, -0.3, 0)
+	return w.spawnItem(location, motion, stack, thrownItemPickupDelay)
+}
+
+// spawnItem adds the item to the world and shows it to the players who have its chunk loaded
+func (w *World) spawnItem(location Location, motion [3]float64, stack inventory.ItemStack, pickupDelay int) (*ItemEntity, error) {
+	pos, _, _, _ := WorldToChunkLocal(int32(math.Floor(location.X)), 0, int32(math.Floor(location.Z)))
+	chunk, err := w.LoadChunk(pos)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &ItemEntity{
+		id:          base.NextEntityId(),
+		world:       w,
+		location:    location,
+		motion:      motion,
+		stack:       stack,
+		pickupDelay: pickupDelay,
+	}
+	w.entities[item.id] = item
+	if chunk.items == nil {
+		chunk.items = make(map[int32]*ItemEntity)
+	}
+	chunk.items[item.id] = item
+
+	packet := item.spawnPacket()
+	for _, player := range chunk.viewers {
+		if err := player.Connection().WritePacket(packet, true); err != nil {
+			player.Disconnect(err)
+		}
+	}
+	return item, nil
+}
+
+// RemoveItem removes the dropped item from the world and from the clients of the players who see it.
+func (w *World) RemoveItem(item *ItemEntity) {
+	w.RemoveEntity(item)
+	pos, _, _, _ := WorldToChunkLocal(int32(math.Floor(item.location.X)), 0, int32(math.Floor(item.location.Z)))
+	chunk, ok := w.chunks[pos]
+	if !ok {
+		return
+	}
+	delete(chunk.items, item.id)
+	for _, player := range chunk.viewers {
+		err := player.Connection().WritePacket(&prot.PacketOutDestroyEntity{EntityId: item.id}, true)
+		if err != nil {
+			player.Disconnect(err)
+		}
+	}
+}
+
+// tickItems moves the dropped items, lets the players pick them up and removes the items
+// that have been lying in the world too long
+func (w *World) tickItems() {
+	players := w.Players()
+	for _, entity := range w.entities {
+		item, ok := entity.(*ItemEntity)
+		if !ok {
+			continue
+		}
+		item.age++
+		if item.age >= itemLifetime {
+			w.RemoveItem(item)
+			continue
+		}
+		item.fall()
+		if item.location.Y < voidDepth {
+			w.RemoveItem(item)
+			continue
+		}
+		if item.pickupDelay > 0 {
+			item.pickupDelay--
+			continue
+		}
+		for _, player := range players {
+			if item.pickUp(player) {
+				break
+			}
+		}
+	}
+}
+
+// fall moves the item down until it lands on a solid block
+func (i *ItemEntity) fall() {
+	i.motion[1] -= itemGravity
+	y := i.location.Y + i.motion[1]
+	if i.motion[1] < 0 && y >= 0 && y < float64(ChunkHeight) {
+		pos, cx, cy, cz := WorldToChunkLocal(int32(math.Floor(i.location.X)), int32(y), int32(math.Floor(i.location.Z)))
+		// the chunk of the item stays loaded while the item exists
+		if chunk, ok := i.world.chunks[pos]; ok && chunk.blockMaterial(cx, cy, cz).Group.IsSolid() {
+			y = math.Floor(y) + 1
+			i.motion[1] = 0
+		}
+	}
+	i.location.Y = y
+	i.motion[1] *= itemDrag
+}
+
+// pickUp adds the item to the player's inventory if the player is close enough. The item is removed
+// if the whole stack fits into the inventory, the inventory is synchronized by the server.
+func (i *ItemEntity) pickUp(player PlayerEntity) bool {
+	location := player.Location()
+	if math.Abs(location.X-i.location.X) > pickupReach || math.Abs(location.Z-i.location.Z) > pickupReach ||
+		i.location.Y < location.Y-0.5 || i.location.Y > location.Y+2.3 {
+		return false
+	}
+	i.stack = player.Inventory().Main.Add(i.stack)
+	if !i.stack.IsEmpty() {
+		return false
+	}
+
+	pos, _, _, _ := WorldToChunkLocal(int32(math.Floor(i.location.X)), 0, int32(math.Floor(i.location.Z)))
+	if chunk, ok := i.world.chunks[pos]; ok {
+		packet := &prot.PacketOutCollectItem{CollectedId: i.id, CollectorId: player.Id()}
+		for _, viewer := range chunk.viewers {
+			if err := viewer.Connection().WritePacket(packet, false); err != nil {
+				viewer.Disconnect(err)
+			}
+		}
+	}
+	i.world.RemoveItem(i)
+	return true
+}
+
+// BreakBlock replaces the block with air, together with the other half of a door or a bed. If the block
+// is harvested, its loot is dropped in its place.
+// The items of tile entities, like the items in a chest, are always dropped.
+func (w *World) BreakBlock(x, y, z int32, harvest bool) error {
+	block, err := w.GetBlock(x, y, z)
+	if err != nil {
+		return err
+	}
+	pos := NewBlockPos(x, y, z)
+	// doors and beds break as a whole, their item drops once from the lower half of the door or the foot of the bed
+	other, paired, err := w.otherHalf(pos, block.Material(), block.Data())
+	if err != nil {
+		return err
+	}
+	data := block.Data()
+	if paired && data&0x8 != 0 {
+		if _, data, err = w.blockAt(other); err != nil {
+			return err
+		}
+	}
+
+	var drops []inventory.ItemStack
+	if harvest {
+		drops = loot.GetDrops(block.Material(), data, w.random)
+	}
+	drops = append(drops, w.removeTileEntity(pos)...)
+	if err := block.Set(material.Air, 0); err != nil {
+		return err
+	}
+	if paired {
+		if err := w.setBlock(other.X, other.Y, other.Z, material.Air, 0); err != nil {
+			return err
+		}
+	}
+
+	for _, stack := range drops {
+		// the items are dropped at a random point inside the block
+		location := NewLocation(
+			float64(x)+w.random.Float64()*0.7+0.15,
+			float64(y)+w.random.Float64()*0.7+0.15,
+			float64(z)+w.random.Float64()*0.7+0.15,
+			0, 0,
+		)
+		if _, err := w.DropItem(location, stack); err != nil {
+			return err
+		}
+	}
+	return nil
+}
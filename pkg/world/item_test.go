@@ -0,0 +1,143 @@
+package world
+
+import (
+	"testing"
+
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// newTestWorld creates a world of the standard flat generator in a temporary directory,
+// its ground is the grass at y=4
+func newTestWorld(t *testing.T) *World {
+	t.Helper()
+	w, err := NewWorld(t.TempDir(), Overworld, 0, func(int64) (Generator, error) {
+		return MakeStandardFlatGenerator()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+// droppedItems returns the counts of the items dropped in the world by their material
+func droppedItems(w *World) map[material.Material]int {
+	counts := make(map[material.Material]int)
+	for _, entity := range w.entities {
+		if item, ok := entity.(*ItemEntity); ok {
+			counts[item.stack.Material] += int(item.stack.Count)
+		}
+	}
+	return counts
+}
+
+func TestBreakBlockPairedHalves(t *testing.T) {
+	tests := []struct {
+		name string
+		item *material.Item
+		// broken is the offset of the broken half from the lower half of the door or the foot of the bed
+		broken BlockPos
+		// other is the offset of the other half
+		other BlockPos
+		block *material.Block
+	}{
+		{"lower door half", material.WoodenDoorItem, BlockPos{}, NewBlockPos(0, 1, 0), material.WoodenDoorBlock},
+		{"upper door half", material.WoodenDoorItem, NewBlockPos(0, 1, 0), BlockPos{}, material.WoodenDoorBlock},
+		{"upper iron door half", material.IronDoorItem, NewBlockPos(0, 1, 0), BlockPos{}, material.IronDoorBlock},
+		{"bed foot", material.BedItem, BlockPos{}, NewBlockPos(0, 0, 1), material.BedBlock},
+		{"bed head", material.BedItem, NewBlockPos(0, 0, 1), BlockPos{}, material.BedBlock},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := newTestWorld(t)
+			base := NewBlockPos(0, 5, 0)
+			// yaw 0 faces south, the head of the bed is at +z
+			placed, err := w.PlaceItem(base.Down(1), 1, inventory.NewItemStack(test.item, 1, 0), 0)
+			if err != nil || !placed {
+				t.Fatalf("placing %v: placed %v, error %v", test.item, placed, err)
+			}
+			broken := base.Offset(test.broken.X, test.broken.Y, test.broken.Z)
+			other := base.Offset(test.other.X, test.other.Y, test.other.Z)
+			if block, _, _ := w.blockAt(other); block != test.block {
+				t.Fatalf("other half is %v, want %v", block, test.block)
+			}
+
+			if err := w.BreakBlock(broken.X, broken.Y, broken.Z, true); err != nil {
+				t.Fatal(err)
+			}
+			for _, pos := range [...]BlockPos{broken, other} {
+				if block, _, _ := w.blockAt(pos); block != material.Air {
+					t.Errorf("block at %v is %v after breaking, want air", pos, block)
+				}
+			}
+			if drops := droppedItems(w); len(drops) != 1 || drops[test.item] != 1 {
+				t.Errorf("dropped %v, want one %v", drops, test.item)
+			}
+		})
+	}
+}
+
+func TestBreakBlockContainerDrops(t *testing.T) {
+	tests := []struct {
+		name    string
+		block   *material.Block
+		harvest bool
+		// items are put to the first slots of the container
+		items []inventory.ItemStack
+		want  map[material.Material]int
+	}{
+		{"chest", material.Chest, true,
+			[]inventory.ItemStack{inventory.NewItemStack(material.Diamond, 3, 0), inventory.NewItemStack(material.IronIngot, 64, 0)},
+			map[material.Material]int{material.Chest: 1, material.Diamond: 3, material.IronIngot: 64}},
+		{"empty chest", material.Chest, true, nil, map[material.Material]int{material.Chest: 1}},
+		{"chest not harvested", material.Chest, false,
+			[]inventory.ItemStack{inventory.NewItemStack(material.Diamond, 3, 0)},
+			map[material.Material]int{material.Diamond: 3}},
+		{"furnace", material.Furnace, true,
+			[]inventory.ItemStack{inventory.NewItemStack(material.IronOre, 5, 0), inventory.NewItemStack(material.Coal, 2, 0),
+				inventory.NewItemStack(material.IronIngot, 7, 0)},
+			map[material.Material]int{material.Furnace: 1, material.IronOre: 5, material.Coal: 2, material.IronIngot: 7}},
+		{"lit furnace", material.FurnaceLit, true,
+			[]inventory.ItemStack{inventory.NewItemStack(material.Coal, 1, 0)},
+			map[material.Material]int{material.Furnace: 1, material.Coal: 1}},
+		{"dispenser", material.Dispenser, true,
+			[]inventory.ItemStack{inventory.NewItemStack(material.Arrow, 16, 0)},
+			map[material.Material]int{material.Dispenser: 1, material.Arrow: 16}},
+		{"stone not harvested", material.Stone, false, nil, map[material.Material]int{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := newTestWorld(t)
+			pos := NewBlockPos(0, 5, 0)
+			if err := w.setBlock(pos.X, pos.Y, pos.Z, test.block, 0); err != nil {
+				t.Fatal(err)
+			}
+			if len(test.items) > 0 {
+				container, err := w.Container(pos)
+				if err != nil || container == nil {
+					t.Fatalf("container of %v: %v, error %v", test.block, container, err)
+				}
+				for i, stack := range test.items {
+					container.SetSlot(i, stack)
+				}
+			}
+
+			if err := w.BreakBlock(pos.X, pos.Y, pos.Z, test.harvest); err != nil {
+				t.Fatal(err)
+			}
+			if drops := droppedItems(w); len(drops) != len(test.want) {
+				t.Errorf("dropped %v, want %v", drops, test.want)
+			} else {
+				for item, count := range test.want {
+					if drops[item] != count {
+						t.Errorf("dropped %v, want %v", drops, test.want)
+						break
+					}
+				}
+			}
+			if tileEntity, err := w.TileEntity(pos); err != nil || tileEntity != nil {
+				t.Errorf("tile entity %v left after breaking, error %v", tileEntity, err)
+			}
+		})
+	}
+}
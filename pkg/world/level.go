@@ -0,0 +1,149 @@
+package world
+
+import (
+	"bufio"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+	"unicode/utf16"
+
+	"github.com/Pesekjak/173go/pkg/nbt"
+)
+
+const (
+	levelFile = "level.dat"
+	// mcRegionVersion is the level version of worlds stored in McRegion files
+	mcRegionVersion = 19132
+)
+
+// levelRoot is the root compound of level.dat
+type levelRoot struct {
+	Data levelData `nbt:"Data"`
+}
+
+// levelData is the world metadata stored in level.dat
+type levelData struct {
+	LevelName   string       `nbt:"LevelName"`
+	Version     int32        `nbt:"version"`
+	RandomSeed  int64        `nbt:"RandomSeed"`
+	SpawnX      int32        `nbt:"SpawnX"`
+	SpawnY      int32        `nbt:"SpawnY"`
+	SpawnZ      int32        `nbt:"SpawnZ"`
+	Time        int64        `nbt:"Time"`
+	LastPlayed  int64        `nbt:"LastPlayed"`
+	SizeOnDisk  int64        `nbt:"SizeOnDisk"`
+	Raining     bool         `nbt:"raining"`
+	RainTime    int32        `nbt:"rainTime"`
+	Thundering  bool         `nbt:"thundering"`
+	ThunderTime int32        `nbt:"thunderTime"`
+	Player      nbt.Compound `nbt:"Player,omitempty"` // the single player, kept so the world can be opened in single player again
+}
+
+// Exists checks whether a world is stored in given directory.
+func Exists(dir string) (bool, error) {
+	_, err := os.Stat(filepath.Join(dir, levelFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// readLevel reads level.dat in given world directory, ok is false if the world has none
+func readLevel(dir string) (level *levelData, ok bool, err error) {
+	file, err := os.Open(filepath.Join(dir, levelFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	reader, err := nbt.NewReader(bufio.NewReader(file), nbt.Gzip)
+	if err != nil {
+		return nil, false, err
+	}
+	defer reader.Close()
+
+	var root levelRoot
+	if _, err = nbt.NewDecoder(bufio.NewReader(reader)).Decode(&root); err != nil {
+		return nil, false, err
+	}
+	return &root.Data, true, nil
+}
+
+// writeLevel writes level.dat to given world directory. The previous file is kept as level.dat_old.
+func writeLevel(dir string, level *levelData) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, levelFile)
+
+	file, err := os.Create(path + "_new")
+	if err != nil {
+		return err
+	}
+	writer, err := nbt.NewWriter(file, nbt.Gzip)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	buffered := bufio.NewWriter(writer)
+	err = nbt.NewEncoder(buffered).Encode("", levelRoot{Data: *level})
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err = os.Stat(path); err == nil {
+		if err = os.Rename(path, path+"_old"); err != nil {
+			return err
+		}
+	}
+	return os.Rename(path+"_new", path)
+}
+
+// directorySize returns the total size of files in the directory
+func directorySize(dir string) int64 {
+	var size int64
+	_ = filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// ParseSeed converts a seed entered by a user to a world seed the same way as the Notchian server.
+// Numbers are used as they are, other text is hashed. Empty seed and zero result in a random one.
+func ParseSeed(seed string) int64 {
+	number, err := strconv.ParseInt(seed, 10, 64)
+	if seed == "" || err == nil && number == 0 {
+		return rand.Int63() - rand.Int63()
+	}
+	if err == nil {
+		return number
+	}
+	// Java's String.hashCode
+	var hash int32
+	for _, char := range utf16.Encode([]rune(seed)) {
+		hash = 31*hash + int32(char)
+	}
+	return int64(hash)
+}
+
+// currentTimeMillis returns the current time in milliseconds, as stored in level.dat
+func currentTimeMillis() int64 {
+	return time.Now().UnixMilli()
+}
@@ -0,0 +1,106 @@
+package world
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Pesekjak/173go/pkg/nbt"
+)
+
+func TestLevelRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	levels := []levelData{
+		{
+			LevelName: "world", Version: mcRegionVersion, RandomSeed: -8737612371621,
+			SpawnX: -120, SpawnY: 64, SpawnZ: 2400, Time: 123456789, LastPlayed: 1700000000000, SizeOnDisk: 4096,
+			Raining: true, RainTime: 3500, Thundering: true, ThunderTime: 12000,
+			Player: nbt.Compound{"Health": int16(20), "Pos": nbt.NewList(nbt.TagDouble, 0.5, 65.0, -3.5)},
+		},
+		// written again, the previous file is kept as level.dat_old
+		{LevelName: "world", Version: mcRegionVersion, RandomSeed: 42, Time: 1, RainTime: 1, ThunderTime: 1},
+	}
+	for _, want := range levels {
+		if err := writeLevel(dir, &want); err != nil {
+			t.Fatal(err)
+		}
+		got, ok, err := readLevel(dir)
+		if err != nil || !ok {
+			t.Fatalf("reading level.dat: ok %v, error %v", ok, err)
+		}
+		if !reflect.DeepEqual(*got, want) {
+			t.Errorf("read %+v, want %+v", *got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, levelFile+"_old")); err != nil {
+		t.Errorf("previous level.dat was not kept: %v", err)
+	}
+}
+
+func TestWorldLevel(t *testing.T) {
+	dir := t.TempDir()
+	if _, ok, err := readLevel(dir); ok || err != nil {
+		t.Fatalf("empty directory has level.dat: ok %v, error %v", ok, err)
+	}
+
+	// a new world has the given seed and the default metadata, which are written when it is saved
+	w, err := NewWorld(dir, Overworld, 42, func(int64) (Generator, error) { return MakeStandardFlatGenerator() })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Seed() != 42 || w.Time() != 0 || w.weather != (weather{}) {
+		t.Errorf("new world has seed %v, time %v and weather %+v, want 42, 0 and no weather", w.Seed(), w.Time(), w.weather)
+	}
+	if err = w.Save(); err != nil {
+		t.Fatal(err)
+	}
+	level, ok, err := readLevel(dir)
+	if err != nil || !ok {
+		t.Fatalf("reading level.dat: ok %v, error %v", ok, err)
+	}
+	defaults := levelData{
+		LevelName: filepath.Base(dir), Version: mcRegionVersion, RandomSeed: 42,
+		SpawnX: w.SpawnPoint.X, SpawnY: w.SpawnPoint.Y, SpawnZ: w.SpawnPoint.Z,
+		LastPlayed: level.LastPlayed, SizeOnDisk: level.SizeOnDisk,
+	}
+	if !reflect.DeepEqual(*level, defaults) {
+		t.Errorf("written %+v, want %+v", *level, defaults)
+	}
+
+	// the metadata of the saved world replace the seed given when it is opened again
+	w.SpawnPoint = NewBlockPos(-100, 70, 250)
+	w.time = 24000*3 + 6000
+	w.weather = weather{raining: true, rainTime: 1200, thundering: false, thunderTime: 50000}
+	w = reopenWorld(t, w)
+	if w.Seed() != 42 || w.SpawnPoint != NewBlockPos(-100, 70, 250) || w.Time() != 24000*3+6000 {
+		t.Errorf("reopened world has seed %v, spawn %v and time %v", w.Seed(), w.SpawnPoint, w.Time())
+	}
+	if want := (weather{raining: true, rainTime: 1200, thunderTime: 50000}); w.weather != want {
+		t.Errorf("reopened world has weather %+v, want %+v", w.weather, want)
+	}
+}
+
+func TestParseSeed(t *testing.T) {
+	tests := []struct {
+		seed string
+		want int64
+	}{
+		{"42", 42},
+		{"-8737612371621", -8737612371621},
+		// text is hashed by Java's String.hashCode
+		{"hello", 99162322},
+		{"Minecraft", -1595926131},
+	}
+	for _, test := range tests {
+		if got := ParseSeed(test.seed); got != test.want {
+			t.Errorf("seed %q is %v, want %v", test.seed, got, test.want)
+		}
+	}
+	// empty seed and zero are replaced with random seeds
+	for _, seed := range []string{"", "0"} {
+		if first, second := ParseSeed(seed), ParseSeed(seed); first == second || first == 48 {
+			t.Errorf("seed %q is %v and %v, want random seeds", seed, first, second)
+		}
+	}
+}
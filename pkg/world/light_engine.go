@@ -0,0 +1,313 @@
+package world
+
+// lightDirections are the offsets of the six blocks light spreads to
+var lightDirections = [...][3]int32{{-1, 0, 0}, {1, 0, 0}, {0, 0, -1}, {0, 0, 1}, {0, -1, 0}, {0, 1, 0}}
+
+// lightNode is a block in the light queues, with the light value it spreads or had before it was removed
+type lightNode struct {
+	x, y, z int32
+	value   byte
+}
+
+// lightEngine keeps the block light and the sky light of a world up to date. Changed blocks are collected
+// and their light is updated together once per tick, or before a chunk is sent, so blocks changed together,
+// like the leaves of a tree, do not spread the same light many times.
+type lightEngine struct {
+	world *World
+	// changed are the blocks changed since the light was updated
+	changed map[BlockPos]struct{}
+	// increase and decrease are the queues of light to spread and to remove, indexed by the light type.
+	// They are kept between the updates, so they do not have to grow again.
+	increase [2][]lightNode
+	decrease [2][]lightNode
+	// last is the chunk accessed last, the light mostly spreads within the same chunk
+	last *Chunk
+}
+
+func newLightEngine(world *World) *lightEngine {
+	return &lightEngine{world: world, changed: make(map[BlockPos]struct{})}
+}
+
+// chunk returns the loaded chunk containing block at given coordinates and the index of the block in it
+func (e *lightEngine) chunk(x, y, z int32) (*Chunk, uint32, bool) {
+	pos, cx, cy, cz := WorldToChunkLocal(x, y, z)
+	if e.last == nil || e.last.pos != pos {
+		chunk, ok := e.world.Chunk(pos)
+		if !ok {
+			return nil, 0, false
+		}
+		e.last = chunk
+	}
+	return e.last, blockIndex(cx, cy, cz), true
+}
+
+// markChanged queues the changed block for the next light update
+func (e *lightEngine) markChanged(pos BlockPos) {
+	e.changed[pos] = struct{}{}
+}
+
+// queueIncrease sets the light of the block if it is brighter than the current light and queues it to spread
+func (e *lightEngine) queueIncrease(t lightType, chunk *Chunk, index uint32, x, y, z int32, value byte) {
+	if value == 0 {
+		return
+	}
+	if chunk.lightOf(t).at(index) < value {
+		chunk.setLight(t, index, value)
+	}
+	e.increase[t] = append(e.increase[t], lightNode{x, y, z, value})
+}
+
+// queueDecrease removes the light of the block and queues it, so the light that spread from it is removed too
+func (e *lightEngine) queueDecrease(t lightType, chunk *Chunk, index uint32, x, y, z int32) {
+	value := chunk.lightOf(t).at(index)
+	if value == 0 {
+		return
+	}
+	chunk.setLight(t, index, 0)
+	e.decrease[t] = append(e.decrease[t], lightNode{x, y, z, value})
+}
+
+// flush updates the light of the blocks changed since the last update and spreads all queued light
+func (e *lightEngine) flush() {
+	hasSky := e.world.Dimension().HasSkyLight()
+
+	// stage 1: remove the light of the changed blocks, and the direct sky light of the columns that were covered
+	for pos := range e.changed {
+		chunk, index, ok := e.chunk(pos.X, pos.Y, pos.Z)
+		if !ok {
+			delete(e.changed, pos)
+			continue
+		}
+		x, _, z := blockPos(index)
+		previous := chunk.height(x, z)
+		height := chunk.computeHeight(x, z)
+		chunk.heights[z<<4|x] = byte(height)
+
+		e.queueDecrease(blockLightType, chunk, index, pos.X, pos.Y, pos.Z)
+		if !hasSky {
+			continue
+		}
+		for y := previous; y < height; y++ {
+			e.queueDecrease(skyLightType, chunk, blockIndex(x, y, z), pos.X, int32(y), pos.Z)
+		}
+		for y := height; y < previous; y++ {
+			e.queueIncrease(skyLightType, chunk, blockIndex(x, y, z), pos.X, int32(y), pos.Z, maxLight)
+		}
+		if uint32(pos.Y) < min(height, previous) {
+			e.queueDecrease(skyLightType, chunk, index, pos.X, pos.Y, pos.Z)
+		}
+	}
+	e.spreadDecrease(blockLightType)
+	e.spreadDecrease(skyLightType)
+
+	// stage 2: the changed blocks emit their own light or take it from the blocks around
+	for pos := range e.changed {
+		chunk, index, _ := e.chunk(pos.X, pos.Y, pos.Z)
+		emission := chunk.blockMaterial(blockPos(index)).LightEmission()
+		e.queueIncrease(blockLightType, chunk, index, pos.X, pos.Y, pos.Z, emission)
+
+		for _, d := range lightDirections {
+			x, y, z := pos.X+d[0], pos.Y+d[1], pos.Z+d[2]
+			if y < 0 || y >= int32(ChunkHeight) {
+				continue
+			}
+			neighbour, neighbourIndex, ok := e.chunk(x, y, z)
+			if !ok {
+				continue
+			}
+			e.queueIncrease(blockLightType, neighbour, neighbourIndex, x, y, z, neighbour.blockLight.at(neighbourIndex))
+			if hasSky {
+				e.queueIncrease(skyLightType, neighbour, neighbourIndex, x, y, z, neighbour.skyLight.at(neighbourIndex))
+			}
+		}
+	}
+	clear(e.changed)
+	e.spreadIncrease(blockLightType)
+	e.spreadIncrease(skyLightType)
+}
+
+// spreadDecrease removes the light that spread from the blocks in the decrease queue. The blocks lit
+// by other sources are queued to spread their light again into the darkened blocks.
+func (e *lightEngine) spreadDecrease(t lightType) {
+	queue := e.decrease[t]
+	for i := 0; i < len(queue); i++ {
+		node := queue[i]
+		for _, d := range lightDirections {
+			x, y, z := node.x+d[0], node.y+d[1], node.z+d[2]
+			if y < 0 || y >= int32(ChunkHeight) {
+				continue
+			}
+			chunk, index, ok := e.chunk(x, y, z)
+			if !ok {
+				continue
+			}
+			current := chunk.lightOf(t).at(index)
+			if current == 0 {
+				continue // there is no light, or it has already been removed
+			}
+
+			block := chunk.blockMaterial(blockPos(index))
+			decay := lightDecay(block)
+			if node.value <= decay || current > node.value-decay {
+				// the light comes from a different source
+				e.increase[t] = append(e.increase[t], lightNode{x, y, z, current})
+				continue
+			}
+
+			chunk.setLight(t, index, 0)
+			queue = append(queue, lightNode{x, y, z, current})
+			if t == blockLightType {
+				e.queueIncrease(t, chunk, index, x, y, z, block.LightEmission())
+			}
+		}
+	}
+	e.decrease[t] = queue[:0]
+}
+
+// spreadIncrease spreads the light of the blocks in the increase queue
+func (e *lightEngine) spreadIncrease(t lightType) {
+	queue := e.increase[t]
+	for i := 0; i < len(queue); i++ {
+		node := queue[i]
+		chunk, index, ok := e.chunk(node.x, node.y, node.z)
+		if !ok || chunk.lightOf(t).at(index) != node.value {
+			continue // the light has changed since the block was queued, the block was queued again if needed
+		}
+
+		for _, d := range lightDirections {
+			x, y, z := node.x+d[0], node.y+d[1], node.z+d[2]
+			if y < 0 || y >= int32(ChunkHeight) {
+				continue
+			}
+			chunk, index, ok := e.chunk(x, y, z)
+			if !ok {
+				continue
+			}
+			decay := lightDecay(chunk.blockMaterial(blockPos(index)))
+			if node.value <= decay {
+				continue // the block would block all the light
+			}
+			value := node.value - decay
+			if chunk.lightOf(t).at(index) >= value {
+				continue // there is higher or same light value already present
+			}
+			chunk.setLight(t, index, value)
+			queue = append(queue, lightNode{x, y, z, value})
+		}
+	}
+	e.increase[t] = queue[:0]
+}
+
+// lightChunk computes the light of a chunk whose blocks were set without lighting. The blocks the sky reaches
+// directly get the full light, which then spreads below the tops of the columns around, like the light
+// of the light emitting blocks. The light of the loaded neighbouring chunks enters through the borders.
+func (e *lightEngine) lightChunk(chunk *Chunk) {
+	// the pending changes are applied first, so the heights of the neighbouring columns are up to date
+	e.flush()
+	e.queueChunk(chunk)
+	e.flush()
+}
+
+// queueChunk computes the height map of the chunk and queues all light sources of the chunk
+// and the light of its borders with the loaded neighbouring chunks
+func (e *lightEngine) queueChunk(chunk *Chunk) {
+	chunk.updateHeightMap()
+	baseX, baseZ := chunk.pos.X*16, chunk.pos.Z*16
+
+	for i, id := range chunk.blockTypes {
+		if id == 0 {
+			continue
+		}
+		x, y, z := blockPos(uint32(i))
+		emission := chunk.blockMaterial(x, y, z).LightEmission()
+		e.queueIncrease(blockLightType, chunk, uint32(i), baseX+int32(x), int32(y), baseZ+int32(z), emission)
+	}
+
+	if e.world.Dimension().HasSkyLight() {
+		for x := uint32(0); x < ChunkSize; x++ {
+			for z := uint32(0); z < ChunkSize; z++ {
+				height := chunk.height(x, z)
+				for y := height; y < ChunkHeight; y++ {
+					chunk.setLight(skyLightType, blockIndex(x, y, z), maxLight)
+				}
+
+				// the light enters the neighbouring columns from the sides up to their tops
+				worldX, worldZ := baseX+int32(x), baseZ+int32(z)
+				top := height
+				for _, d := range lightDirections[:4] {
+					if sideHeight, ok := e.world.columnHeight(worldX+d[0], worldZ+d[2]); ok {
+						top = max(top, sideHeight)
+					}
+				}
+				for y := height; y <= min(top, ChunkHeight-1); y++ {
+					e.increase[skyLightType] = append(e.increase[skyLightType], lightNode{worldX, int32(y), worldZ, maxLight})
+				}
+			}
+		}
+	}
+
+	// the light of the neighbours at the borders spreads into the chunk
+	for _, neighbourPos := range chunk.pos.Neighbours() {
+		neighbour, ok := e.world.Chunk(neighbourPos)
+		if !ok {
+			continue
+		}
+		// the neighbour's column along the border, the chunk is at one of its sides
+		minX, maxX, minZ, maxZ := uint32(0), ChunkSize-1, uint32(0), ChunkSize-1
+		switch {
+		case neighbourPos.X < chunk.pos.X:
+			minX = ChunkSize - 1
+		case neighbourPos.X > chunk.pos.X:
+			maxX = 0
+		case neighbourPos.Z < chunk.pos.Z:
+			minZ = ChunkSize - 1
+		default:
+			maxZ = 0
+		}
+		for x := minX; x <= maxX; x++ {
+			for z := minZ; z <= maxZ; z++ {
+				for y := uint32(0); y < ChunkHeight; y++ {
+					index := blockIndex(x, y, z)
+					worldX, worldZ := neighbourPos.X*16+int32(x), neighbourPos.Z*16+int32(z)
+					for _, t := range [...]lightType{blockLightType, skyLightType} {
+						if value := neighbour.lightOf(t).at(index); value > 1 {
+							e.increase[t] = append(e.increase[t], lightNode{worldX, int32(y), worldZ, value})
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// Relight computes the light of the chunks between given corners from scratch, the chunks are loaded
+// if they are not yet. The chunks have to be sent again to the players.
+func (w *World) Relight(from, to ChunkPos) error {
+	var chunks []*Chunk
+	for x := min(from.X, to.X); x <= max(from.X, to.X); x++ {
+		for z := min(from.Z, to.Z); z <= max(from.Z, to.Z); z++ {
+			chunk, err := w.LoadChunk(NewChunkPos(x, z))
+			if err != nil {
+				return err
+			}
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	w.light.flush()
+	for _, chunk := range chunks {
+		chunk.blockLight.clear()
+		chunk.skyLight.clear()
+		chunk.cache = nil
+		chunk.dirty = true
+	}
+	// the heights are computed before any light is spread, the sky light of each chunk depends on the columns around
+	for _, chunk := range chunks {
+		chunk.updateHeightMap()
+	}
+	for _, chunk := range chunks {
+		w.light.queueChunk(chunk)
+	}
+	w.light.flush()
+	return nil
+}
@@ -0,0 +1,278 @@
+package world
+
+import (
+	"testing"
+
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// fixtureGenerator generates the blocks given by the function, by their world coordinates
+type fixtureGenerator func(x, y, z int32) *material.Block
+
+func (f fixtureGenerator) GenerateBlocks(chunk *Chunk) error {
+	blocks := chunk.BlockIDs()
+	for x := uint32(0); x < ChunkSize; x++ {
+		for z := uint32(0); z < ChunkSize; z++ {
+			for y := uint32(0); y < ChunkHeight; y++ {
+				block := f(chunk.pos.X*16+int32(x), int32(y), chunk.pos.Z*16+int32(z))
+				blocks[blockIndex(x, y, z)] = byte(block.Id())
+			}
+		}
+	}
+	return nil
+}
+
+// lightFixture is the terrain of the light tests, stone up to Y 4 with structures in the chunks around the origin:
+//   - a closed stone room with a torch at 5 6 5, the room is dark to the sky
+//   - a stone roof at Y 8 over X -12 to -4 and Z 10 to 21, across the border of the chunks at Z 16
+//   - a pool of water at Y 2 to 4 over X and Z 20 to 26
+//   - a glowstone block at -1 4 -5, next to the border of the chunks at X 0
+//   - a column of leaves at 10 5..12 -10
+func lightFixture(x, y, z int32) *material.Block {
+	between := func(v, from, to int32) bool { return v >= from && v <= to }
+	switch {
+	case between(x, 3, 7) && between(y, 6, 8) && between(z, 3, 7):
+		if x == 5 && y == 6 && z == 5 {
+			return material.Torch
+		}
+		return material.Air
+	case between(x, 2, 8) && between(y, 5, 9) && between(z, 2, 8):
+		return material.Stone
+	case between(x, -12, -4) && y == 8 && between(z, 10, 21):
+		return material.Stone
+	case between(x, 20, 26) && between(y, 2, 4) && between(z, 20, 26):
+		return material.WaterStill
+	case x == -1 && y == 4 && z == -5:
+		return material.GlowstoneBlock
+	case x == 10 && between(y, 5, 12) && z == -10:
+		return material.Leaves
+	case y <= 4:
+		return material.Stone
+	}
+	return material.Air
+}
+
+// newLightWorld creates a world of the light fixture with the chunks from -1;-1 to 1;1 loaded
+func newLightWorld(t *testing.T) *World {
+	t.Helper()
+	w, err := NewWorld(t.TempDir(), Overworld, 0, func(int64) (Generator, error) {
+		return fixtureGenerator(lightFixture), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := int32(-1); x <= 1; x++ {
+		for z := int32(-1); z <= 1; z++ {
+			if _, err := w.LoadChunk(NewChunkPos(x, z)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return w
+}
+
+// referenceLight computes the light of all loaded chunks from scratch, without the light engine. Each block
+// has the light of its source, or the brightest light of its neighbours reduced by the decay of the block,
+// whichever is higher. The blocks above the highest block absorbing light are the sources of the sky light.
+// The values are repeatedly recomputed until none of them changes.
+func referenceLight(w *World, t lightType) map[ChunkPos][]byte {
+	// the light is computed in a box spanning all loaded chunks, the columns of the missing chunks have no decay
+	var from, to ChunkPos
+	first := true
+	for pos := range w.chunks {
+		if first {
+			from, to, first = pos, pos, false
+		}
+		from = NewChunkPos(min(from.X, pos.X), min(from.Z, pos.Z))
+		to = NewChunkPos(max(to.X, pos.X), max(to.Z, pos.Z))
+	}
+	sizeX, sizeZ := int(to.X-from.X+1)*int(ChunkSize), int(to.Z-from.Z+1)*int(ChunkSize)
+	height := int(ChunkHeight)
+	index := func(x, y, z int) int { return (x*sizeZ+z)*height + y }
+	light := make([]byte, sizeX*sizeZ*height)
+	decay := make([]byte, len(light))
+
+	for pos, chunk := range w.chunks {
+		for x := uint32(0); x < ChunkSize; x++ {
+			for z := uint32(0); z < ChunkSize; z++ {
+				boxX, boxZ := int(pos.X-from.X)*int(ChunkSize)+int(x), int(pos.Z-from.Z)*int(ChunkSize)+int(z)
+				skyReached := t == skyLightType
+				for y := int(ChunkHeight) - 1; y >= 0; y-- {
+					block := chunk.blockMaterial(x, uint32(y), z)
+					if block.LightOpacity() != 0 {
+						skyReached = false
+					}
+					i := index(boxX, y, boxZ)
+					decay[i] = lightDecay(block)
+					if t == blockLightType {
+						light[i] = block.LightEmission()
+					} else if skyReached {
+						light[i] = maxLight
+					}
+				}
+			}
+		}
+	}
+
+	// the sweeps alternate their direction, so the light spreads both ways in a few of them
+	for sweep, changed := 0, true; changed; sweep++ {
+		changed = false
+		for n := range light {
+			i := n
+			if sweep%2 == 1 {
+				i = len(light) - 1 - n
+			}
+			if decay[i] == 0 {
+				continue // the block is in a chunk that is not loaded
+			}
+			x, y, z := i/(sizeZ*height), i%height, i/height%sizeZ
+			for _, d := range lightDirections {
+				nx, ny, nz := x+int(d[0]), y+int(d[1]), z+int(d[2])
+				if nx < 0 || nx >= sizeX || ny < 0 || ny >= height || nz < 0 || nz >= sizeZ {
+					continue
+				}
+				if neighbour := light[index(nx, ny, nz)]; neighbour > decay[i] && neighbour-decay[i] > light[i] {
+					light[i] = neighbour - decay[i]
+					changed = true
+				}
+			}
+		}
+	}
+
+	values := make(map[ChunkPos][]byte)
+	for pos := range w.chunks {
+		chunkLight := make([]byte, ChunkSize*ChunkHeight*ChunkSize)
+		for i := range chunkLight {
+			x, y, z := blockPos(uint32(i))
+			boxX, boxZ := int(pos.X-from.X)*int(ChunkSize)+int(x), int(pos.Z-from.Z)*int(ChunkSize)+int(z)
+			chunkLight[i] = light[index(boxX, int(y), boxZ)]
+		}
+		values[pos] = chunkLight
+	}
+	return values
+}
+
+// compareLight reports the first block of each loaded chunk whose light differs from the reference light
+func compareLight(t *testing.T, w *World) {
+	t.Helper()
+	for _, lt := range [...]lightType{blockLightType, skyLightType} {
+		for pos, want := range referenceLight(w, lt) {
+			got := w.chunks[pos].lightOf(lt)
+			for i := range want {
+				if got.at(uint32(i)) != want[i] {
+					x, y, z := blockPos(uint32(i))
+					t.Errorf("light %v of block %v %v %v is %v, want %v", lt, pos.X*16+int32(x), y, pos.Z*16+int32(z),
+						got.at(uint32(i)), want[i])
+					break
+				}
+			}
+		}
+	}
+}
+
+// lightCase is the expected light of a block
+type lightCase struct {
+	pos        BlockPos
+	block, sky byte
+}
+
+// checkLight compares the light of the blocks with the expected values
+func checkLight(t *testing.T, w *World, cases []lightCase) {
+	t.Helper()
+	for _, c := range cases {
+		chunk, index, ok := w.light.chunk(c.pos.X, c.pos.Y, c.pos.Z)
+		if !ok {
+			t.Fatalf("chunk of %v is not loaded", c.pos)
+		}
+		if block, sky := chunk.blockLight.at(index), chunk.skyLight.at(index); block != c.block || sky != c.sky {
+			t.Errorf("light of %v is %v block and %v sky, want %v and %v", c.pos, block, sky, c.block, c.sky)
+		}
+	}
+}
+
+func TestLightChunk(t *testing.T) {
+	w := newLightWorld(t)
+	compareLight(t, w)
+	checkLight(t, w, []lightCase{
+		// the open air and the ground
+		{NewBlockPos(0, 5, 10), 0, 15},
+		{NewBlockPos(0, 4, 10), 0, 0},
+		{NewBlockPos(20, 127, 5), 0, 15},
+		// the torch lights the closed room, one level less per block
+		{NewBlockPos(5, 6, 5), 14, 0},
+		{NewBlockPos(5, 7, 5), 13, 0},
+		{NewBlockPos(3, 6, 5), 12, 0},
+		{NewBlockPos(3, 8, 3), 8, 0},
+		{NewBlockPos(5, 9, 5), 0, 0},
+		// the sky light enters under the roof from its sides, also across the border of the chunks
+		{NewBlockPos(-12, 6, 16), 0, 14},
+		{NewBlockPos(-8, 5, 16), 0, 10},
+		{NewBlockPos(-8, 7, 15), 0, 10},
+		{NewBlockPos(-8, 8, 16), 0, 0},
+		// the water absorbs three levels of the light per block
+		{NewBlockPos(23, 4, 23), 0, 12},
+		{NewBlockPos(23, 3, 23), 0, 9},
+		{NewBlockPos(23, 2, 23), 0, 6},
+		// the glowstone lights the chunk next to it
+		{NewBlockPos(-1, 4, -5), 15, 0},
+		{NewBlockPos(-1, 5, -5), 14, 15},
+		{NewBlockPos(0, 5, -5), 13, 15},
+		{NewBlockPos(2, 5, -5), 11, 15},
+		// the leaves absorb a single level
+		{NewBlockPos(10, 12, -10), 0, 14},
+		{NewBlockPos(10, 5, -10), 0, 14},
+	})
+}
+
+func TestLightBlockUpdates(t *testing.T) {
+	w := newLightWorld(t)
+	steps := []struct {
+		name   string
+		pos    BlockPos
+		block  *material.Block
+		checks []lightCase
+	}{
+		{"remove torch", NewBlockPos(5, 6, 5), material.Air, []lightCase{
+			{NewBlockPos(5, 6, 5), 0, 0},
+			{NewBlockPos(3, 8, 3), 0, 0},
+		}},
+		{"place torch at chunk border", NewBlockPos(15, 5, 0), material.Torch, []lightCase{
+			{NewBlockPos(15, 5, 0), 14, 15},
+			{NewBlockPos(16, 5, 0), 13, 15},
+			{NewBlockPos(18, 5, 0), 11, 15},
+		}},
+		{"open room roof", NewBlockPos(5, 9, 5), material.Air, []lightCase{
+			{NewBlockPos(5, 6, 5), 0, 15},
+			{NewBlockPos(4, 7, 5), 0, 14},
+			{NewBlockPos(3, 6, 3), 0, 11},
+		}},
+		{"close room roof", NewBlockPos(5, 9, 5), material.Stone, []lightCase{
+			{NewBlockPos(5, 6, 5), 0, 0},
+			{NewBlockPos(3, 6, 3), 0, 0},
+		}},
+		{"open roof across chunk border", NewBlockPos(-8, 8, 16), material.Air, []lightCase{
+			{NewBlockPos(-8, 5, 16), 0, 15},
+			{NewBlockPos(-8, 7, 15), 0, 14},
+		}},
+		{"cover column", NewBlockPos(10, 20, 10), material.Stone, []lightCase{
+			{NewBlockPos(10, 19, 10), 0, 14},
+			{NewBlockPos(10, 5, 10), 0, 14},
+			{NewBlockPos(10, 21, 10), 0, 15},
+		}},
+		{"dig into ground", NewBlockPos(-1, 4, -5), material.Air, []lightCase{
+			{NewBlockPos(-1, 4, -5), 0, 15},
+			{NewBlockPos(0, 5, -5), 0, 15},
+			{NewBlockPos(-1, 3, -5), 0, 0},
+		}},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if err := w.setBlock(step.pos.X, step.pos.Y, step.pos.Z, step.block, 0); err != nil {
+				t.Fatal(err)
+			}
+			w.light.flush()
+			compareLight(t, w)
+			checkLight(t, w, step.checks)
+		})
+	}
+}
@@ -23,6 +23,10 @@ func (l Location) ToBlockPos() BlockPos {
 	}
 }
 
+func (l Location) ToChunkPos() ChunkPos {
+	return l.ToBlockPos().ToChunkPos()
+}
+
 func (l Location) Add(x, y, z float64) Location {
 	return Location{
 		X:     l.X + x,
@@ -114,6 +118,26 @@ func (p BlockPos) Neighbours() [6]BlockPos {
 	return [...]BlockPos{p.Up(1), p.Down(1), p.North(1), p.South(1), p.East(1), p.West(1)}
 }
 
+// Relative returns the position next to the face of the block, with the faces numbered as in the protocol:
+// bottom, top, north, south, west and east. It fails for other faces, like -1 of items used in the air.
+func (p BlockPos) Relative(face int8) (BlockPos, bool) {
+	switch face {
+	case 0:
+		return p.Down(1), true
+	case 1:
+		return p.Up(1), true
+	case 2:
+		return p.North(1), true
+	case 3:
+		return p.South(1), true
+	case 4:
+		return p.West(1), true
+	case 5:
+		return p.East(1), true
+	}
+	return p, false
+}
+
 func (p BlockPos) ToChunkPos() ChunkPos {
 	return ChunkPos{X: p.X >> 4, Z: p.Z >> 4}
 }
@@ -137,12 +161,19 @@ func (c ChunkPos) Add(other ChunkPos) ChunkPos {
 func (c ChunkPos) Neighbours() [4]ChunkPos {
 	return [...]ChunkPos{
 		{c.X + 1, c.Z},
-		{c.X + 1, c.Z + 1},
+		{c.X, c.Z + 1},
 		{c.X - 1, c.Z},
-		{c.X - 1, c.Z - 1},
+		{c.X, c.Z - 1},
 	}
 }
 
+// DistanceSquared returns the squared distance between two chunk positions.
+func (c ChunkPos) DistanceSquared(other ChunkPos) int64 {
+	dx := int64(c.X - other.X)
+	dz := int64(c.Z - other.Z)
+	return dx*dx + dz*dz
+}
+
 func (c ChunkPos) String() string {
 	return fmt.Sprintf("ChunkPos(X: %d, Z: %d)", c.X, c.Z)
 }
@@ -11,7 +11,7 @@ import (
 // It accepts the block's material, its metadata, and a random source.
 func GetDrops(block *material.Block, metadata byte, r *rand.Rand) []inventory.ItemStack {
 	tries := getBlockLootTries(block, r)
-	var drops = make([]inventory.ItemStack, tries)
+	var drops = make([]inventory.ItemStack, 0, tries)
 
 	for i := 0; i < tries; i++ {
 		// check if this specific drop attempt is successful
@@ -0,0 +1,99 @@
+package loot
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// trials is the number of the blocks broken by each test
+const trials = 10000
+
+func TestGetDrops(t *testing.T) {
+	tests := []struct {
+		name     string
+		block    *material.Block
+		metadata byte
+		want     []inventory.ItemStack
+	}{
+		{"stone", material.Stone, 0, []inventory.ItemStack{inventory.NewItemStack(material.Cobblestone, 1, 0)}},
+		{"cobblestone", material.Cobblestone, 0, []inventory.ItemStack{inventory.NewItemStack(material.Cobblestone, 1, 0)}},
+		{"grass", material.GrassBlock, 0, []inventory.ItemStack{inventory.NewItemStack(material.Dirt, 1, 0)}},
+		{"coal ore", material.CoalOre, 0, []inventory.ItemStack{inventory.NewItemStack(material.Coal, 1, 0)}},
+		{"iron ore", material.IronOre, 0, []inventory.ItemStack{inventory.NewItemStack(material.IronOre, 1, 0)}},
+		{"gold ore", material.GoldOre, 0, []inventory.ItemStack{inventory.NewItemStack(material.GoldOre, 1, 0)}},
+		{"diamond ore", material.DiamondOre, 0, []inventory.ItemStack{inventory.NewItemStack(material.Diamond, 1, 0)}},
+		{"glass", material.Glass, 0, nil},
+		{"ice", material.Ice, 0, nil},
+		{"mob spawner", material.MobSpawner, 0, nil},
+		{"lit furnace", material.FurnaceLit, 3, []inventory.ItemStack{inventory.NewItemStack(material.Furnace, 1, 0)}},
+		{"lower door half", material.WoodenDoorBlock, 1, []inventory.ItemStack{inventory.NewItemStack(material.WoodenDoorItem, 1, 0)}},
+		{"upper door half", material.WoodenDoorBlock, 9, nil},
+		{"bed foot", material.BedBlock, 2, []inventory.ItemStack{inventory.NewItemStack(material.BedItem, 1, 0)}},
+		{"bed head", material.BedBlock, 10, nil},
+		{"wool", material.Wool, 14, []inventory.ItemStack{inventory.NewItemStack(material.Wool, 1, 14)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(1))
+			for i := 0; i < trials; i++ {
+				drops := GetDrops(test.block, test.metadata, r)
+				if !equalDrops(drops, test.want) {
+					t.Fatalf("dropped %v, want %v", drops, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetDropsChance(t *testing.T) {
+	tests := []struct {
+		name     string
+		block    *material.Block
+		metadata byte
+		// rare is the drop of the given chance, common is dropped otherwise
+		rare   inventory.ItemStack
+		common []inventory.ItemStack
+		chance float64
+	}{
+		{"gravel", material.Gravel, 0, inventory.NewItemStack(material.Flint, 1, 0),
+			[]inventory.ItemStack{inventory.NewItemStack(material.Gravel, 1, 0)}, 1.0 / 10},
+		{"oak leaves", material.Leaves, 0, inventory.NewItemStack(material.Sapling, 1, 0), nil, 1.0 / 20},
+		{"birch leaves", material.Leaves, 2 | 4, inventory.NewItemStack(material.Sapling, 1, 2), nil, 1.0 / 20},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(1))
+			rare := 0
+			for i := 0; i < trials; i++ {
+				drops := GetDrops(test.block, test.metadata, r)
+				switch {
+				case equalDrops(drops, []inventory.ItemStack{test.rare}):
+					rare++
+				case !equalDrops(drops, test.common):
+					t.Fatalf("dropped %v, want %v or %v", drops, test.rare, test.common)
+				}
+			}
+			// the drops of the fixed seed stay within four standard deviations of the chance
+			deviation := math.Sqrt(trials * test.chance * (1 - test.chance))
+			if math.Abs(float64(rare)-trials*test.chance) > 4*deviation {
+				t.Errorf("dropped %v %v times out of %v, want about %v", test.rare, rare, trials, trials*test.chance)
+			}
+		})
+	}
+}
+
+func equalDrops(drops, want []inventory.ItemStack) bool {
+	if len(drops) != len(want) {
+		return false
+	}
+	for i := range drops {
+		if drops[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
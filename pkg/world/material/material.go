@@ -40,6 +40,8 @@ type Item struct {
 	isTool        bool
 	isFood        bool
 	equipSlot     EquipSlot
+	toolKind      ToolKind
+	toolTier      ToolTier
 }
 
 func (i *Item) Id() uint16 {
@@ -52,6 +54,9 @@ func (i *Item) String() string {
 
 var materials = make(map[uint16]Material)
 
+// blocks are the registered blocks indexed by their ID
+var blocks [256]*Block
+
 func newBlock(id byte, name string) *Block {
 	iid := uint16(id)
 	if _, exists := materials[iid]; exists {
@@ -73,6 +78,7 @@ func newBlock(id byte, name string) *Block {
 		fireBurnRate:        0,
 	}
 	materials[iid] = b
+	blocks[id] = b
 	return b
 }
 
@@ -95,6 +101,12 @@ func newItem(id uint16, name string) *Item {
 	return i
 }
 
+// BlockFromID returns the block with given ID, ok is false if there is no such block.
+func BlockFromID(id byte) (block *Block, ok bool) {
+	block = blocks[id]
+	return block, block != nil
+}
+
 func FromID(id uint16) (Material, error) {
 	m, ok := materials[id]
 	if !ok {
@@ -415,9 +427,9 @@ func init() {
 		diamondMaxUses = 1561
 	)
 
-	IronShovel = newItem(0, "Iron Shovel").makeTool(ironMaxUses)
-	IronPickaxe = newItem(1, "Iron Pickaxe").makeTool(ironMaxUses)
-	IronAxe = newItem(2, "Iron Axe").makeTool(ironMaxUses)
+	IronShovel = newItem(0, "Iron Shovel").makeTool(ironMaxUses).withTool(ToolShovel, TierIron)
+	IronPickaxe = newItem(1, "Iron Pickaxe").makeTool(ironMaxUses).withTool(ToolPickaxe, TierIron)
+	IronAxe = newItem(2, "Iron Axe").makeTool(ironMaxUses).withTool(ToolAxe, TierIron)
 	FlintAndSteel = newItem(3, "Flint and Steel").makeTool(64)
 	Apple = newItem(4, "Apple").makeFood()
 	Bow = newItem(5, "Bow").makeTool(384) // bow durability is 384
@@ -426,34 +438,34 @@ func init() {
 	Diamond = newItem(8, "Diamond")
 	IronIngot = newItem(9, "Iron Ingot")
 	GoldIngot = newItem(10, "Gold Ingot")
-	IronSword = newItem(11, "Iron Sword").makeTool(ironMaxUses)
-	WoodenSword = newItem(12, "Wooden Sword").makeTool(woodMaxUses)
-	WoodenShovel = newItem(13, "Wooden Shovel").makeTool(woodMaxUses)
-	WoodenPickaxe = newItem(14, "Wooden Pickaxe").makeTool(woodMaxUses)
-	WoodenAxe = newItem(15, "Wooden Axe").makeTool(woodMaxUses)
-	StoneSword = newItem(16, "Stone Sword").makeTool(stoneMaxUses)
-	StoneShovel = newItem(17, "Stone Shovel").makeTool(stoneMaxUses)
-	StonePickaxe = newItem(18, "Stone Pickaxe").makeTool(stoneMaxUses)
-	StoneAxe = newItem(19, "Stone Axe").makeTool(stoneMaxUses)
-	DiamondSword = newItem(20, "Diamond Sword").makeTool(diamondMaxUses)
-	DiamondShovel = newItem(21, "Diamond Shovel").makeTool(diamondMaxUses)
-	DiamondPickaxe = newItem(22, "Diamond Pickaxe").makeTool(diamondMaxUses)
-	DiamondAxe = newItem(23, "Diamond Axe").makeTool(diamondMaxUses)
+	IronSword = newItem(11, "Iron Sword").makeTool(ironMaxUses).withTool(ToolSword, TierIron)
+	WoodenSword = newItem(12, "Wooden Sword").makeTool(woodMaxUses).withTool(ToolSword, TierWood)
+	WoodenShovel = newItem(13, "Wooden Shovel").makeTool(woodMaxUses).withTool(ToolShovel, TierWood)
+	WoodenPickaxe = newItem(14, "Wooden Pickaxe").makeTool(woodMaxUses).withTool(ToolPickaxe, TierWood)
+	WoodenAxe = newItem(15, "Wooden Axe").makeTool(woodMaxUses).withTool(ToolAxe, TierWood)
+	StoneSword = newItem(16, "Stone Sword").makeTool(stoneMaxUses).withTool(ToolSword, TierStone)
+	StoneShovel = newItem(17, "Stone Shovel").makeTool(stoneMaxUses).withTool(ToolShovel, TierStone)
+	StonePickaxe = newItem(18, "Stone Pickaxe").makeTool(stoneMaxUses).withTool(ToolPickaxe, TierStone)
+	StoneAxe = newItem(19, "Stone Axe").makeTool(stoneMaxUses).withTool(ToolAxe, TierStone)
+	DiamondSword = newItem(20, "Diamond Sword").makeTool(diamondMaxUses).withTool(ToolSword, TierDiamond)
+	DiamondShovel = newItem(21, "Diamond Shovel").makeTool(diamondMaxUses).withTool(ToolShovel, TierDiamond)
+	DiamondPickaxe = newItem(22, "Diamond Pickaxe").makeTool(diamondMaxUses).withTool(ToolPickaxe, TierDiamond)
+	DiamondAxe = newItem(23, "Diamond Axe").makeTool(diamondMaxUses).withTool(ToolAxe, TierDiamond)
 	Stick = newItem(24, "Stick")
 	Bowl = newItem(25, "Bowl")
 	MushroomStew = newItem(26, "Mushroom Stew").makeFood()
-	GoldSword = newItem(27, "Gold Sword").makeTool(goldMaxUses)
-	GoldShovel = newItem(28, "Gold Shovel").makeTool(goldMaxUses)
-	GoldPickaxe = newItem(29, "Gold Pickaxe").makeTool(goldMaxUses)
-	GoldAxe = newItem(30, "Gold Axe").makeTool(goldMaxUses)
+	GoldSword = newItem(27, "Gold Sword").makeTool(goldMaxUses).withTool(ToolSword, TierGold)
+	GoldShovel = newItem(28, "Gold Shovel").makeTool(goldMaxUses).withTool(ToolShovel, TierGold)
+	GoldPickaxe = newItem(29, "Gold Pickaxe").makeTool(goldMaxUses).withTool(ToolPickaxe, TierGold)
+	GoldAxe = newItem(30, "Gold Axe").makeTool(goldMaxUses).withTool(ToolAxe, TierGold)
 	StringItem = newItem(31, "String")
 	Feather = newItem(32, "Feather")
 	Gunpowder = newItem(33, "Gunpowder")
-	WoodenHoe = newItem(34, "Wooden Hoe").makeTool(woodMaxUses)
-	StoneHoe = newItem(35, "Stone Hoe").makeTool(stoneMaxUses)
-	IronHoe = newItem(36, "Iron Hoe").makeTool(ironMaxUses)
-	DiamondHoe = newItem(37, "Diamond Hoe").makeTool(diamondMaxUses)
-	GoldHoe = newItem(38, "Gold Hoe").makeTool(goldMaxUses)
+	WoodenHoe = newItem(34, "Wooden Hoe").makeTool(woodMaxUses).withTool(ToolHoe, TierWood)
+	StoneHoe = newItem(35, "Stone Hoe").makeTool(stoneMaxUses).withTool(ToolHoe, TierStone)
+	IronHoe = newItem(36, "Iron Hoe").makeTool(ironMaxUses).withTool(ToolHoe, TierIron)
+	DiamondHoe = newItem(37, "Diamond Hoe").makeTool(diamondMaxUses).withTool(ToolHoe, TierDiamond)
+	GoldHoe = newItem(38, "Gold Hoe").makeTool(goldMaxUses).withTool(ToolHoe, TierGold)
 	WheatSeeds = newItem(39, "Wheat Seeds")
 	Wheat = newItem(40, "Wheat")
 	Bread = newItem(41, "Bread").makeFood()
@@ -518,7 +530,7 @@ func init() {
 	RedstoneRepeaterItem = newItem(100, "Redstone Repeater")
 	Cookie = newItem(101, "Cookie").withMaxStackSize(8).makeFood()
 	Map = newItem(102, "Map").withMaxStackSize(1)
-	Shears = newItem(103, "Shears").makeTool(238)
+	Shears = newItem(103, "Shears").makeTool(238).withTool(ToolShears, TierIron)
 	Record13 = newItem(2000, "13 Disc").withMaxStackSize(1)
 	RecordCat = newItem(2001, "Cat Disc").withMaxStackSize(1)
 }
@@ -0,0 +1,182 @@
+package material
+
+// ToolKind defines the blocks a tool is made for.
+type ToolKind int
+
+const (
+	ToolNone ToolKind = iota
+	ToolPickaxe
+	ToolShovel
+	ToolAxe
+	ToolSword
+	ToolHoe
+	ToolShears
+)
+
+// ToolTier defines the material a tool is made of.
+type ToolTier int
+
+const (
+	TierWood ToolTier = iota
+	TierStone
+	TierIron
+	TierDiamond
+	TierGold
+)
+
+// efficiency returns how many times faster than by hand a tool of the tier breaks the blocks it is made for.
+func (t ToolTier) efficiency() float32 {
+	switch t {
+	case TierStone:
+		return 4
+	case TierIron:
+		return 6
+	case TierDiamond:
+		return 8
+	case TierGold:
+		return 12
+	default:
+		return 2
+	}
+}
+
+// harvestLevel returns the level of the tier used to decide which ores a pickaxe harvests.
+func (t ToolTier) harvestLevel() int {
+	switch t {
+	case TierStone:
+		return 1
+	case TierIron:
+		return 2
+	case TierDiamond:
+		return 3
+	default:
+		return 0
+	}
+}
+
+func (i *Item) withTool(kind ToolKind, tier ToolTier) *Item {
+	i.toolKind = kind
+	i.toolTier = tier
+	return i
+}
+
+// ToolKind returns the kind of the tool. Returns ToolNone if the item is not a digging tool or a weapon.
+func (i *Item) ToolKind() ToolKind {
+	return i.toolKind
+}
+
+// ToolTier returns the material the tool is made of.
+func (i *Item) ToolTier() ToolTier {
+	return i.toolTier
+}
+
+// DigSpeed returns how many times faster than by hand the item breaks the block.
+func (i *Item) DigSpeed(b *Block) float32 {
+	switch i.toolKind {
+	case ToolPickaxe, ToolShovel, ToolAxe:
+		if isEffective(i.toolKind, b) {
+			return i.toolTier.efficiency()
+		}
+	case ToolSword:
+		if b == Cobweb {
+			return 15
+		}
+		return 1.5
+	case ToolShears:
+		switch b {
+		case Cobweb, Leaves:
+			return 15
+		case Wool:
+			return 5
+		}
+	}
+	return 1
+}
+
+// BreakDamage returns the damage the item takes when it breaks the block. Digging tools take 1, swords take 2
+// and shears are only damaged by the blocks they are made for.
+func (i *Item) BreakDamage(b *Block) uint16 {
+	switch i.toolKind {
+	case ToolPickaxe, ToolShovel, ToolAxe:
+		return 1
+	case ToolSword:
+		return 2
+	case ToolShears:
+		if b == Cobweb || b == Leaves {
+			return 1
+		}
+	}
+	return 0
+}
+
+// canHarvest returns true if the item harvests a block that is not harvested by hand.
+func (i *Item) canHarvest(b *Block) bool {
+	switch i.toolKind {
+	case ToolPickaxe:
+		level := i.toolTier.harvestLevel()
+		switch b {
+		case Obsidian:
+			return level == 3
+		case DiamondBlock, DiamondOre, GoldBlock, GoldOre, RedstoneOre, RedstoneOreGlowing:
+			return level >= 2
+		case IronBlock, IronOre, LapisLazuliBlock, LapisLazuliOre:
+			return level >= 1
+		}
+		return b.Group == GroupRock || b.Group == GroupIron
+	case ToolShovel:
+		return b == SnowLayer || b == SnowBlock
+	case ToolSword, ToolShears:
+		return b == Cobweb
+	}
+	return false
+}
+
+// isEffective returns true if the tool kind is made for the block.
+func isEffective(kind ToolKind, b *Block) bool {
+	switch kind {
+	case ToolPickaxe:
+		switch b {
+		case Cobblestone, DoubleSlab, Slab, Stone, Sandstone, MossStone, IronOre, IronBlock, CoalOre, GoldBlock,
+			GoldOre, DiamondOre, DiamondBlock, Ice, Netherrack, LapisLazuliOre, LapisLazuliBlock:
+			return true
+		}
+	case ToolShovel:
+		switch b {
+		case GrassBlock, Dirt, Sand, Gravel, SnowLayer, SnowBlock, ClayBlock, Farmland:
+			return true
+		}
+	case ToolAxe:
+		switch b {
+		case WoodenPlanks, Bookshelf, Wood, Chest:
+			return true
+		}
+	}
+	return false
+}
+
+// CanHarvest returns true if the block drops its loot when broken while holding the material.
+// The held material is nil for an empty hand.
+func CanHarvest(held Material, b *Block) bool {
+	if b.IsBreakableByDefault() {
+		return true
+	}
+	item, ok := held.(*Item)
+	return ok && item.canHarvest(b)
+}
+
+// DigProgress returns the part of the block broken in a single tick of digging while holding the material,
+// the block breaks once the progress reaches 1. The held material is nil for an empty hand.
+func DigProgress(held Material, b *Block) float32 {
+	hardness := b.BreakHardness()
+	if hardness < 0 {
+		return 0
+	}
+	if !CanHarvest(held, b) {
+		return 1 / hardness / 100
+	}
+	speed := float32(1)
+	if item, ok := held.(*Item); ok {
+		speed = item.DigSpeed(b)
+	}
+	return speed / hardness / 30
+}
@@ -0,0 +1,454 @@
+package world
+
+import (
+	"math"
+
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// attachment is a face of a block another block can be attached to, like a torch to a wall,
+// with the data of the attached block
+type attachment struct {
+	// face is the face of the supporting block the attached block is placed at
+	face int8
+	data byte
+}
+
+var (
+	// torchAttachments are the faces torches hang on, in the order the Notchian server tries them
+	torchAttachments = []attachment{{5, 1}, {4, 2}, {3, 3}, {2, 4}, {1, 5}}
+	// buttonAttachments are the faces buttons are placed at
+	buttonAttachments = torchAttachments[:4]
+	// ladderAttachments are the faces ladders hang on, in the order the Notchian server tries them
+	ladderAttachments = []attachment{{2, 2}, {3, 3}, {4, 4}, {5, 5}}
+)
+
+// PlaceItem places the block of the item stack next to the clicked face of the clicked block, facing the way
+// a player looking in the yaw direction places it. Doors and beds place both of their halves and slabs placed
+// on a slab of the same type merge into a double slab. It reports whether the block was placed.
+func (w *World) PlaceItem(clicked BlockPos, face int8, stack inventory.ItemStack, yaw float32) (bool, error) {
+	if stack.IsEmpty() {
+		return false, nil
+	}
+	clickedBlock, _, err := w.blockAt(clicked)
+	if err != nil {
+		return false, err
+	}
+
+	switch stack.Material {
+	case material.WoodenDoorItem, material.IronDoorItem, material.BedItem:
+		// doors and beds are placed only on top of blocks
+		if face != 1 {
+			return false, nil
+		}
+		switch stack.Material {
+		case material.WoodenDoorItem:
+			return w.placeDoor(clicked.Up(1), material.WoodenDoorBlock, yaw)
+		case material.IronDoorItem:
+			return w.placeDoor(clicked.Up(1), material.IronDoorBlock, yaw)
+		}
+		return w.placeBed(clicked.Up(1), yaw)
+	}
+
+	block := placedBlock(stack.Material)
+	if block == nil {
+		return false, nil
+	}
+	pos := clicked
+	// snow layers are replaced by the placed block instead of the block being placed on top of them
+	if clickedBlock != material.SnowLayer {
+		var ok bool
+		if pos, ok = clicked.Relative(face); !ok {
+			return false, nil
+		}
+	}
+	if pos.Y < 0 || pos.Y >= int32(ChunkHeight) || (pos.Y == int32(ChunkHeight)-1 && block.Group.IsSolid()) {
+		return false, nil
+	}
+	if ok, err := w.canReplace(pos); err != nil || !ok {
+		return false, err
+	}
+	if w.collidesWithPlayer(pos, block) {
+		return false, nil
+	}
+	data, ok, err := w.placementData(pos, block, face, yaw, stack.Data)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if block == material.Slab {
+		below, belowData, err := w.blockAt(pos.Down(1))
+		if err != nil {
+			return false, err
+		}
+		if below == material.Slab && belowData == data {
+			return true, w.setBlock(pos.X, pos.Y-1, pos.Z, material.DoubleSlab, data)
+		}
+	}
+	return true, w.setBlock(pos.X, pos.Y, pos.Z, block, data)
+}
+
+// placedBlock returns the block placed by the material, or nil if it does not place any block
+func placedBlock(m material.Material) *material.Block {
+	switch m {
+	case material.RedstoneDust:
+		return material.RedstoneWire
+	case material.SugarCaneItem:
+		return material.SugarCaneBlock
+	case material.RedstoneRepeaterItem:
+		return material.RedstoneRepeaterOff
+	case material.CakeItem:
+		return material.CakeBlock
+	case material.WheatSeeds:
+		return material.Seeds
+	}
+	if block, ok := m.(*material.Block); ok && block != material.Air {
+		return block
+	}
+	return nil
+}
+
+// placementData checks whether the block can stand at the position and returns its data
+func (w *World) placementData(pos BlockPos, block *material.Block, face int8, yaw float32, itemData uint16) (byte, bool, error) {
+	switch block {
+	case material.Torch, material.RedstoneTorchOn:
+		return w.attachedData(pos, face, torchAttachments, false)
+	case material.StoneButton:
+		return w.attachedData(pos, face, buttonAttachments, true)
+	case material.Lever:
+		data, ok, err := w.attachedData(pos, face, torchAttachments, true)
+		if data == 5 {
+			// levers on the floor point along one of the axes at random
+			data += byte(w.random.Intn(2))
+		}
+		return data, ok, err
+	case material.Ladder:
+		return w.attachedData(pos, face, ladderAttachments, false)
+
+	case material.WoodenStairs, material.CobblestoneStairs:
+		return [...]byte{2, 1, 3, 0}[yawDirection(yaw)], true, nil
+	case material.Furnace, material.Dispenser:
+		return [...]byte{2, 5, 3, 4}[yawDirection(yaw)], true, nil
+	case material.Pumpkin, material.JackOLantern:
+		ok, err := w.isNormalCube(pos.Down(1))
+		return byte((yawDirection(yaw) + 2) & 3), ok, err
+	case material.RedstoneRepeaterOff:
+		ok, err := w.isNormalCube(pos.Down(1))
+		return byte((yawDirection(yaw) + 2) & 3), ok, err
+	case material.Chest:
+		ok, err := w.canPlaceChest(pos)
+		return 0, ok, err
+
+	case material.Rails, material.PoweredRail, material.DetectorRail, material.StonePressurePlate,
+		material.WoodenPressurePlate, material.RedstoneWire, material.SnowLayer:
+		ok, err := w.isNormalCube(pos.Down(1))
+		return 0, ok, err
+	case material.CakeBlock:
+		below, _, err := w.blockAt(pos.Down(1))
+		return 0, below.Group.IsSolid(), err
+	case material.Sapling, material.Dandelion, material.Rose, material.TallGrass:
+		below, _, err := w.blockAt(pos.Down(1))
+		ok := below == material.GrassBlock || below == material.Dirt || below == material.Farmland
+		return byte(itemData & 3), ok, err
+	case material.DeadBush:
+		below, _, err := w.blockAt(pos.Down(1))
+		return 0, below == material.Sand, err
+	case material.BrownMushroom, material.RedMushroom:
+		below, _, err := w.blockAt(pos.Down(1))
+		return 0, below.IsOpaqueCube(), err
+	case material.Seeds:
+		below, _, err := w.blockAt(pos.Down(1))
+		return 0, face == 1 && below == material.Farmland, err
+	case material.Cactus:
+		ok, err := w.canPlaceCactus(pos)
+		return 0, ok, err
+	case material.SugarCaneBlock:
+		ok, err := w.canPlaceSugarCane(pos)
+		return 0, ok, err
+
+	case material.Wool, material.Wood, material.Slab, material.DoubleSlab:
+		return byte(itemData), true, nil
+	case material.Leaves:
+		return byte(itemData & 3), true, nil
+	}
+	return 0, true, nil
+}
+
+// attachedData finds the block the attached block hangs on and returns the data of the attached block.
+// The clicked face is preferred, if the block does not have to be attached to it, the other faces are tried.
+func (w *World) attachedData(pos BlockPos, face int8, attachments []attachment, onClickedFace bool) (byte, bool, error) {
+	found := -1
+	for i, a := range attachments {
+		// the supporting block is on the opposite side of its face
+		support, _ := pos.Relative(a.face ^ 1)
+		cube, err := w.isNormalCube(support)
+		if err != nil {
+			return 0, false, err
+		}
+		if !cube {
+			continue
+		}
+		if a.face == face {
+			return a.data, true, nil
+		}
+		if found < 0 {
+			found = i
+		}
+	}
+	if found < 0 || onClickedFace {
+		return 0, false, nil
+	}
+	return attachments[found].data, true, nil
+}
+
+// canPlaceChest checks that the chest would not be a part of a chest bigger than a double chest
+func (w *World) canPlaceChest(pos BlockPos) (bool, error) {
+	chests := 0
+	for _, side := range [...]BlockPos{pos.North(1), pos.South(1), pos.East(1), pos.West(1)} {
+		block, _, err := w.blockAt(side)
+		if err != nil {
+			return false, err
+		}
+		if block != material.Chest {
+			continue
+		}
+		chests++
+		double, err := w.hasChestNeighbour(side)
+		if err != nil || double {
+			return false, err
+		}
+	}
+	return chests <= 1, nil
+}
+
+// hasChestNeighbour checks whether there is a chest next to the block
+func (w *World) hasChestNeighbour(pos BlockPos) (bool, error) {
+	for _, side := range [...]BlockPos{pos.North(1), pos.South(1), pos.East(1), pos.West(1)} {
+		block, _, err := w.blockAt(side)
+		if err != nil {
+			return false, err
+		}
+		if block == material.Chest {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// canPlaceCactus checks that the cactus stands on sand or another cactus, without solid blocks around it
+func (w *World) canPlaceCactus(pos BlockPos) (bool, error) {
+	for _, side := range [...]BlockPos{pos.North(1), pos.South(1), pos.East(1), pos.West(1)} {
+		block, _, err := w.blockAt(side)
+		if err != nil || block.Group.IsSolid() {
+			return false, err
+		}
+	}
+	below, _, err := w.blockAt(pos.Down(1))
+	return below == material.Sand || below == material.Cactus, err
+}
+
+// canPlaceSugarCane checks that the sugar cane grows on another sugar cane, or on grass or dirt next to water
+func (w *World) canPlaceSugarCane(pos BlockPos) (bool, error) {
+	ground := pos.Down(1)
+	below, _, err := w.blockAt(ground)
+	if err != nil {
+		return false, err
+	}
+	if below == material.SugarCaneBlock {
+		return true, nil
+	}
+	if below != material.GrassBlock && below != material.Dirt {
+		return false, nil
+	}
+	for _, side := range [...]BlockPos{ground.North(1), ground.South(1), ground.East(1), ground.West(1)} {
+		block, _, err := w.blockAt(side)
+		if err != nil {
+			return false, err
+		}
+		if block.Group == material.GroupWater {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// placeDoor places both halves of a door standing at the position. The door opens away from the wall
+// next to it, or towards the door it is placed next to.
+func (w *World) placeDoor(pos BlockPos, door *material.Block, yaw float32) (bool, error) {
+	if pos.Y < 0 || pos.Y+1 >= int32(ChunkHeight) {
+		return false, nil
+	}
+	for _, check := range [...]func() (bool, error){
+		func() (bool, error) { return w.isNormalCube(pos.Down(1)) },
+		func() (bool, error) { return w.canReplace(pos) },
+		func() (bool, error) { return w.canReplace(pos.Up(1)) },
+	} {
+		if ok, err := check(); err != nil || !ok {
+			return false, err
+		}
+	}
+	if w.collidesWithPlayer(pos, door) || w.collidesWithPlayer(pos.Up(1), door) {
+		return false, nil
+	}
+
+	direction := int(math.Floor(float64(yaw+180)*4/360-0.5)) & 3
+	var dx, dz int32
+	switch direction {
+	case 0:
+		dz = 1
+	case 1:
+		dx = -1
+	case 2:
+		dz = -1
+	case 3:
+		dx = 1
+	}
+	// the walls and doors on both sides of the door, at the height of both halves
+	var walls, doors [2]int
+	for i, side := range [...]BlockPos{pos.Offset(-dx, 0, -dz), pos.Offset(dx, 0, dz)} {
+		for _, half := range [...]BlockPos{side, side.Up(1)} {
+			block, _, err := w.blockAt(half)
+			if err != nil {
+				return false, err
+			}
+			if block.IsNormalCube() {
+				walls[i]++
+			}
+			if block == door {
+				doors[i]++
+			}
+		}
+	}
+	if (doors[0] > 0 && doors[1] == 0) || walls[1] > walls[0] {
+		direction = (direction-1)&3 + 4
+	}
+
+	if err := w.setBlock(pos.X, pos.Y, pos.Z, door, byte(direction)); err != nil {
+		return false, err
+	}
+	return true, w.setBlock(pos.X, pos.Y+1, pos.Z, door, byte(direction)+8)
+}
+
+// placeBed places both halves of a bed with its foot at the position, the head is further in the yaw direction
+func (w *World) placeBed(pos BlockPos, yaw float32) (bool, error) {
+	direction := yawDirection(yaw)
+	head := bedHead(pos, byte(direction))
+	if pos.Y < 0 || pos.Y >= int32(ChunkHeight) {
+		return false, nil
+	}
+	for _, half := range [...]BlockPos{pos, head} {
+		block, _, err := w.blockAt(half)
+		if err != nil || block != material.Air {
+			return false, err
+		}
+		if ok, err := w.isNormalCube(half.Down(1)); err != nil || !ok {
+			return false, err
+		}
+		if w.collidesWithPlayer(half, material.BedBlock) {
+			return false, nil
+		}
+	}
+
+	if err := w.setBlock(pos.X, pos.Y, pos.Z, material.BedBlock, byte(direction)); err != nil {
+		return false, err
+	}
+	return true, w.setBlock(head.X, head.Y, head.Z, material.BedBlock, byte(direction)+8)
+}
+
+// bedHead returns the position of the head of the bed with its foot at the position, facing in the direction
+// of the bed's data
+func bedHead(foot BlockPos, data byte) BlockPos {
+	switch data & 3 {
+	case 0:
+		return foot.Offset(0, 0, 1)
+	case 1:
+		return foot.Offset(-1, 0, 0)
+	case 2:
+		return foot.Offset(0, 0, -1)
+	}
+	return foot.Offset(1, 0, 0)
+}
+
+// otherHalf returns the position of the other half of the door or the bed at the position.
+// It reports false for other blocks and for the halves left without the other one.
+func (w *World) otherHalf(pos BlockPos, block *material.Block, data byte) (BlockPos, bool, error) {
+	// the upper half of a door and the head of a bed have the 0x8 bit set
+	upper := data&0x8 != 0
+	var other BlockPos
+	switch block {
+	case material.WoodenDoorBlock, material.IronDoorBlock:
+		if upper {
+			other = pos.Down(1)
+		} else {
+			other = pos.Up(1)
+		}
+	case material.BedBlock:
+		if upper {
+			// the head is as far from the foot as the foot is from the head, in the opposite direction
+			head := bedHead(pos, data)
+			other = pos.Offset(pos.X-head.X, 0, pos.Z-head.Z)
+		} else {
+			other = bedHead(pos, data)
+		}
+	default:
+		return pos, false, nil
+	}
+	otherBlock, otherData, err := w.blockAt(other)
+	if err != nil || otherBlock != block || (otherData&0x8 != 0) == upper {
+		return pos, false, err
+	}
+	return other, true, nil
+}
+
+// yawDirection returns the horizontal direction a player looking in the yaw direction faces,
+// 0 is south and the directions follow clockwise
+func yawDirection(yaw float32) int {
+	return int(math.Floor(float64(yaw)*4/360+0.5)) & 3
+}
+
+// blockAt returns the block at the position and its data, air outside the world height
+func (w *World) blockAt(pos BlockPos) (*material.Block, byte, error) {
+	if pos.Y < 0 || pos.Y >= int32(ChunkHeight) {
+		return material.Air, 0, nil
+	}
+	block, err := w.GetBlock(pos.X, pos.Y, pos.Z)
+	if err != nil {
+		return material.Air, 0, err
+	}
+	return block.Material(), block.Data(), nil
+}
+
+// isNormalCube checks whether the block at the position is a full opaque cube, which other blocks can be attached to
+func (w *World) isNormalCube(pos BlockPos) (bool, error) {
+	block, _, err := w.blockAt(pos)
+	return block.IsNormalCube(), err
+}
+
+// canReplace checks whether a placed block can replace the block at the position
+func (w *World) canReplace(pos BlockPos) (bool, error) {
+	block, _, err := w.blockAt(pos)
+	return block.Group.IsReplaceable(), err
+}
+
+// collidesWithPlayer checks whether the block placed at the position would overlap a player.
+// Solid blocks are treated as full cubes, except of slabs filling only the lower half of the block.
+func (w *World) collidesWithPlayer(pos BlockPos, block *material.Block) bool {
+	if !block.Group.IsSolid() {
+		return false
+	}
+	const halfWidth, height = 0.3, 1.8
+	minX, minY, minZ := float64(pos.X), float64(pos.Y), float64(pos.Z)
+	maxX, maxY, maxZ := minX+1, minY+1, minZ+1
+	if block == material.Slab {
+		maxY -= 0.5
+	}
+	for _, player := range w.Players() {
+		l := player.Location()
+		if l.X+halfWidth > minX && l.X-halfWidth < maxX && l.Y+height > minY && l.Y < maxY &&
+			l.Z+halfWidth > minZ && l.Z-halfWidth < maxZ {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,397 @@
+package world
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+const (
+	// portalSearchRadius is the distance in blocks within which an existing portal is used as the exit
+	portalSearchRadius = 128
+	// portalBuildRadius is the distance in blocks within which a new portal is built
+	portalBuildRadius = 16
+	// PortalScale is the ratio of overworld distances to nether distances
+	PortalScale = 8
+)
+
+// blockID returns id of the block at given coordinates, air outside the world height
+func (w *World) blockID(x, y, z int32) (byte, error) {
+	if y < 0 || y >= int32(ChunkHeight) {
+		return 0, nil
+	}
+	pos, cx, cy, cz := WorldToChunkLocal(x, y, z)
+	chunk, err := w.LoadChunk(pos)
+	if err != nil {
+		return 0, err
+	}
+	return chunk.blockTypes[blockIndex(cx, cy, cz)], nil
+}
+
+// setBlock sets the block at given coordinates, coordinates outside the world height are ignored
+func (w *World) setBlock(x, y, z int32, block *material.Block, data byte) error {
+	if y < 0 || y >= int32(ChunkHeight) {
+		return nil
+	}
+	b, err := w.GetBlock(x, y, z)
+	if err != nil {
+		return err
+	}
+	return b.Set(block, data)
+}
+
+// PlaceFire lights fire at given coordinates, as flint and steel does. Fire lit on obsidian inside
+// of a portal frame opens the portal instead. It reports whether the fire or the portal was placed.
+func (w *World) PlaceFire(x, y, z int32) (bool, error) {
+	if id, err := w.blockID(x, y, z); err != nil || id != 0 {
+		return false, err
+	}
+	below, err := w.blockID(x, y-1, z)
+	if err != nil {
+		return false, err
+	}
+	if below == byte(material.Obsidian.Id()) {
+		if opened, err := w.IgnitePortal(x, y, z); err != nil || opened {
+			return opened, err
+		}
+	}
+
+	// fire needs a solid block below it or something to burn next to it
+	canStay := false
+	if block, ok := material.BlockFromID(below); ok && block.IsNormalCube() {
+		canStay = true
+	}
+	for _, side := range NewBlockPos(x, y, z).Neighbours() {
+		id, err := w.blockID(side.X, side.Y, side.Z)
+		if err != nil {
+			return false, err
+		}
+		if block, ok := material.BlockFromID(id); ok && block.FireFlammability() > 0 {
+			canStay = true
+		}
+	}
+	if !canStay {
+		return false, nil
+	}
+	return true, w.setBlock(x, y, z, material.Fire, 0)
+}
+
+// IgnitePortal fills the obsidian frame around given coordinates with portal blocks. The frame must be
+// 4 blocks wide and 5 blocks tall, with an inside of air or fire. It reports whether the portal was opened.
+func (w *World) IgnitePortal(x, y, z int32) (bool, error) {
+	obsidian := byte(material.Obsidian.Id())
+	isObsidian := func(x, y, z int32) (bool, error) {
+		id, err := w.blockID(x, y, z)
+		return id == obsidian, err
+	}
+
+	// the frame is aligned with the axis along which obsidian is next to the block
+	var alongX, alongZ int32
+	if west, err := isObsidian(x-1, y, z); err != nil {
+		return false, err
+	} else if east, err := isObsidian(x+1, y, z); err != nil {
+		return false, err
+	} else if west || east {
+		alongX = 1
+	}
+	if north, err := isObsidian(x, y, z-1); err != nil {
+		return false, err
+	} else if south, err := isObsidian(x, y, z+1); err != nil {
+		return false, err
+	} else if north || south {
+		alongZ = 1
+	}
+	if alongX == alongZ {
+		return false, nil
+	}
+
+	// the block can be either of the two columns of the inside
+	if id, err := w.blockID(x-alongX, y, z-alongZ); err != nil {
+		return false, err
+	} else if id == 0 {
+		x -= alongX
+		z -= alongZ
+	}
+
+	fire := byte(material.Fire.Id())
+	for i := int32(-1); i <= 2; i++ {
+		for dy := int32(-1); dy <= 3; dy++ {
+			edge := i == -1 || i == 2 || dy == -1 || dy == 3
+			corner := (i == -1 || i == 2) && (dy == -1 || dy == 3)
+			if corner {
+				continue // corners of the frame can be anything
+			}
+			id, err := w.blockID(x+alongX*i, y+dy, z+alongZ*i)
+			if err != nil {
+				return false, err
+			}
+			if edge && id != obsidian || !edge && id != 0 && id != fire {
+				return false, nil
+			}
+		}
+	}
+
+	for i := int32(0); i < 2; i++ {
+		for dy := int32(0); dy < 3; dy++ {
+			if err := w.setBlock(x+alongX*i, y+dy, z+alongZ*i, material.Portal, 0); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// InPortal checks whether the box between given corners touches a portal block. Portal blocks are
+// thin slabs in the middle of the blocks, facing the axis along which the portal spans.
+func (w *World) InPortal(min, max Location) (bool, error) {
+	portal := byte(material.Portal.Id())
+	for x := int32(math.Floor(min.X)); x <= int32(math.Floor(max.X)); x++ {
+		for y := int32(math.Floor(min.Y)); y <= int32(math.Floor(max.Y)); y++ {
+			for z := int32(math.Floor(min.Z)); z <= int32(math.Floor(max.Z)); z++ {
+				id, err := w.blockID(x, y, z)
+				if err != nil {
+					return false, err
+				}
+				if id != portal {
+					continue
+				}
+
+				west, err := w.blockID(x-1, y, z)
+				if err != nil {
+					return false, err
+				}
+				east, err := w.blockID(x+1, y, z)
+				if err != nil {
+					return false, err
+				}
+				minX, minZ, maxX, maxZ := 0.375, 0.0, 0.625, 1.0
+				if west == portal || east == portal {
+					minX, minZ, maxX, maxZ = 0, 0.375, 1, 0.625
+				}
+				if max.X > float64(x)+minX && min.X < float64(x)+maxX && max.Z > float64(z)+minZ && min.Z < float64(z)+maxZ {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// PortalExit returns location at which an entity travelling through a portal to the location
+// in this world appears. The nearest portal within 128 blocks is used, if there is none,
+// a new portal is built within 16 blocks of the location.
+func (w *World) PortalExit(location Location) (Location, error) {
+	exit, ok, err := w.findPortal(location)
+	if err != nil || ok {
+		return exit, err
+	}
+	if err = w.buildPortal(location); err != nil {
+		return Location{}, fmt.Errorf("failed to build a portal: %v", err)
+	}
+	exit, ok, err = w.findPortal(location)
+	if err != nil {
+		return Location{}, err
+	}
+	if !ok {
+		return Location{}, fmt.Errorf("built portal at %v was not found", location)
+	}
+	return exit, nil
+}
+
+// findPortal finds the portal block closest to the location, preferring the bottom blocks of portals,
+// and returns the location in the middle of the portal
+func (w *World) findPortal(location Location) (Location, bool, error) {
+	portal := byte(material.Portal.Id())
+	originX := int32(math.Floor(location.X))
+	originZ := int32(math.Floor(location.Z))
+
+	closest := -1.0
+	var foundX, foundY, foundZ int32
+	for x := originX - portalSearchRadius; x <= originX+portalSearchRadius; x++ {
+		dx := float64(x) + 0.5 - location.X
+		for z := originZ - portalSearchRadius; z <= originZ+portalSearchRadius; z++ {
+			dz := float64(z) + 0.5 - location.Z
+			pos, cx, _, cz := WorldToChunkLocal(x, 0, z)
+			chunk, err := w.LoadChunk(pos)
+			if err != nil {
+				return Location{}, false, err
+			}
+			column := chunk.blockTypes[blockIndex(cx, 0, cz) : blockIndex(cx, 0, cz)+uint32(ChunkHeight)]
+			for y := int32(ChunkHeight) - 1; y >= 0; y-- {
+				if column[y] != portal {
+					continue
+				}
+				for y > 0 && column[y-1] == portal {
+					y--
+				}
+				dy := float64(y) + 0.5 - location.Y
+				distance := dx*dx + dy*dy + dz*dz
+				if closest < 0 || distance < closest {
+					closest = distance
+					foundX, foundY, foundZ = x, y, z
+				}
+			}
+		}
+	}
+	if closest < 0 {
+		return Location{}, false, nil
+	}
+
+	// the exit is in the middle of the two blocks wide portal
+	exit := NewLocation(float64(foundX)+0.5, float64(foundY)+0.5, float64(foundZ)+0.5, location.Yaw, 0)
+	for _, side := range [...]struct {
+		dx, dz           int32
+		offsetX, offsetZ float64
+	}{{-1, 0, -0.5, 0}, {1, 0, 0.5, 0}, {0, -1, 0, -0.5}, {0, 1, 0, 0.5}} {
+		id, err := w.blockID(foundX+side.dx, foundY, foundZ+side.dz)
+		if err != nil {
+			return Location{}, false, err
+		}
+		if id == portal {
+			exit = exit.Add(side.offsetX, 0, side.offsetZ)
+		}
+	}
+	return exit, true, nil
+}
+
+// buildPortal builds a portal near the location. It looks for the closest floor with enough room
+// for the portal and a platform in front of it, then for a floor with room for the portal alone.
+// When there is no such floor, the portal is built in the air on a platform of obsidian.
+func (w *World) buildPortal(location Location) error {
+	originX := int32(math.Floor(location.X))
+	originY := int32(math.Floor(location.Y))
+	originZ := int32(math.Floor(location.Z))
+
+	isEmpty := func(x, y, z int32) (bool, error) {
+		id, err := w.blockID(x, y, z)
+		return id == 0, err
+	}
+	isSolid := func(x, y, z int32) (bool, error) {
+		id, err := w.blockID(x, y, z)
+		if err != nil {
+			return false, err
+		}
+		block, ok := material.BlockFromID(id)
+		return ok && block.Group.IsSolid(), nil
+	}
+
+	// fits checks whether the box of given depth in front of the portal plane stands on solid blocks in air
+	fits := func(x, y, z, stepX, stepZ, depth int32) (bool, error) {
+		for d := int32(0); d < depth; d++ {
+			for i := int32(0); i < 4; i++ {
+				for dy := int32(-1); dy < 4; dy++ {
+					bx := x + (i-1)*stepX + d*stepZ
+					by := y + dy
+					bz := z + (i-1)*stepZ - d*stepX
+					var ok bool
+					var err error
+					if dy < 0 {
+						ok, err = isSolid(bx, by, bz)
+					} else {
+						ok, err = isEmpty(bx, by, bz)
+					}
+					if err != nil || !ok {
+						return false, err
+					}
+				}
+			}
+		}
+		return true, nil
+	}
+
+	closest := -1.0
+	bestX, bestY, bestZ := originX, originY, originZ
+	bestDirection := int32(0)
+	start := int32(w.random.Intn(4))
+
+	// the first pass needs room for a platform in front of the portal, the second one only for the portal
+	for pass := 0; pass < 2 && closest < 0; pass++ {
+		directions, depth := int32(4), int32(3)
+		if pass == 1 {
+			directions, depth = 2, 1
+		}
+		for x := originX - portalBuildRadius; x <= originX+portalBuildRadius; x++ {
+			dx := float64(x) + 0.5 - location.X
+			for z := originZ - portalBuildRadius; z <= originZ+portalBuildRadius; z++ {
+				dz := float64(z) + 0.5 - location.Z
+				for y := int32(ChunkHeight) - 1; y >= 0; y-- {
+					if empty, err := isEmpty(x, y, z); err != nil {
+						return err
+					} else if !empty {
+						continue
+					}
+					// the portal stands on the floor of the air gap
+					for y > 0 {
+						if empty, err := isEmpty(x, y-1, z); err != nil {
+							return err
+						} else if !empty {
+							break
+						}
+						y--
+					}
+
+					for direction := start; direction < start+directions; direction++ {
+						stepX, stepZ := portalDirection(direction % directions)
+						if ok, err := fits(x, y, z, stepX, stepZ, depth); err != nil {
+							return err
+						} else if !ok {
+							// the Notchian server gives up on the whole gap when one direction does not fit
+							break
+						}
+						dy := float64(y) + 0.5 - location.Y
+						distance := dx*dx + dy*dy + dz*dz
+						if closest < 0 || distance < closest {
+							closest = distance
+							bestX, bestY, bestZ = x, y, z
+							bestDirection = direction % directions
+						}
+					}
+				}
+			}
+		}
+	}
+
+	stepX, stepZ := portalDirection(bestDirection)
+	if closest < 0 {
+		// nowhere to stand, the portal is built in the air with an obsidian platform in front of it
+		bestY = min(max(originY, 70), 118)
+		for d := int32(-1); d <= 1; d++ {
+			for i := int32(1); i < 3; i++ {
+				for dy := int32(-1); dy < 3; dy++ {
+					block := material.Air
+					if dy < 0 {
+						block = material.Obsidian
+					}
+					err := w.setBlock(bestX+(i-1)*stepX+d*stepZ, bestY+dy, bestZ+(i-1)*stepZ-d*stepX, block, 0)
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	for i := int32(0); i < 4; i++ {
+		for dy := int32(-1); dy < 4; dy++ {
+			block := material.Portal
+			if i == 0 || i == 3 || dy == -1 || dy == 3 {
+				block = material.Obsidian
+			}
+			if err := w.setBlock(bestX+(i-1)*stepX, bestY+dy, bestZ+(i-1)*stepZ, block, 0); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// portalDirection returns the step along the plane of a portal facing given direction
+func portalDirection(direction int32) (stepX, stepZ int32) {
+	stepX = direction % 2
+	stepZ = 1 - stepX
+	if direction%4 >= 2 {
+		stepX, stepZ = -stepX, -stepZ
+	}
+	return stepX, stepZ
+}
@@ -0,0 +1,43 @@
+package recipe
+
+import (
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// Grid is a crafting grid with the slot showing the result of the recipe its items match.
+type Grid struct {
+	Items   *inventory.Inventory
+	Result  *inventory.Inventory
+	Width   int
+	recipes *Registry
+}
+
+func NewGrid(recipes *Registry, items, result *inventory.Inventory, width int) *Grid {
+	return &Grid{Items: items, Result: result, Width: width, recipes: recipes}
+}
+
+// Update shows the result of the recipe matching the items in the grid, it has to be called
+// whenever the items change.
+func (g *Grid) Update() {
+	result, _ := g.recipes.Match(g.Items, g.Width)
+	g.Result.SetSlot(0, result)
+}
+
+// Take uses up an item from every slot of the grid after the player took the result.
+// Containers of the ingredients, like the buckets of milk, are left in the grid.
+func (g *Grid) Take(inventory.ItemStack) {
+	for slot := 0; slot < g.Items.Size(); slot++ {
+		stack := g.Items.Slot(slot)
+		if stack.IsEmpty() {
+			continue
+		}
+		if stack.Material == material.MilkBucket {
+			g.Items.SetSlot(slot, inventory.NewItemStack(material.Bucket, 1, 0))
+			continue
+		}
+		stack.Split(1)
+		g.Items.SetSlot(slot, stack)
+	}
+	g.Update()
+}
@@ -0,0 +1,122 @@
+package recipe
+
+import (
+	"slices"
+
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// Ingredient is an item a recipe needs in a slot of the crafting grid.
+type Ingredient struct {
+	Material material.Material
+	Data     uint16
+	// AnyData makes the ingredient match the items with any data, like wool of any color
+	AnyData bool
+}
+
+func (i Ingredient) matches(stack inventory.ItemStack) bool {
+	return !stack.IsEmpty() && stack.Material == i.Material && (i.AnyData || stack.Data == i.Data)
+}
+
+// Recipe turns the items in a crafting grid into its result.
+type Recipe interface {
+	// Matches checks whether the items in the grid of given width are the ingredients of the recipe
+	Matches(grid *inventory.Inventory, width int) bool
+	Result() inventory.ItemStack
+	// size is the number of the slots of the recipe, the larger recipes are matched first
+	size() int
+}
+
+// Shaped is a recipe with its ingredients in a pattern. The pattern can be anywhere in the grid
+// and it can be mirrored horizontally.
+type Shaped struct {
+	Width, Height int
+	// Ingredients are the ingredients of the pattern row by row, nil for the slots that must be empty
+	Ingredients []*Ingredient
+	result      inventory.ItemStack
+}
+
+func NewShaped(width, height int, ingredients []*Ingredient, result inventory.ItemStack) *Shaped {
+	return &Shaped{Width: width, Height: height, Ingredients: ingredients, result: result}
+}
+
+func (r *Shaped) Matches(grid *inventory.Inventory, width int) bool {
+	height := grid.Size() / width
+	for x := 0; x <= width-r.Width; x++ {
+		for y := 0; y <= height-r.Height; y++ {
+			if r.matchesAt(grid, width, x, y, false) || r.matchesAt(grid, width, x, y, true) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesAt checks the grid with the pattern moved by the offset, the slots outside of the pattern must be empty
+func (r *Shaped) matchesAt(grid *inventory.Inventory, width, offsetX, offsetY int, mirrored bool) bool {
+	for slot := 0; slot < grid.Size(); slot++ {
+		x, y := slot%width-offsetX, slot/width-offsetY
+		var ingredient *Ingredient
+		if x >= 0 && y >= 0 && x < r.Width && y < r.Height {
+			if mirrored {
+				x = r.Width - 1 - x
+			}
+			ingredient = r.Ingredients[y*r.Width+x]
+		}
+
+		stack := grid.Slot(slot)
+		if ingredient == nil {
+			if !stack.IsEmpty() {
+				return false
+			}
+		} else if !ingredient.matches(stack) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Shaped) Result() inventory.ItemStack {
+	return r.result
+}
+
+func (r *Shaped) size() int {
+	return r.Width * r.Height
+}
+
+// Shapeless is a recipe with its ingredients anywhere in the grid.
+type Shapeless struct {
+	Ingredients []Ingredient
+	result      inventory.ItemStack
+}
+
+func NewShapeless(ingredients []Ingredient, result inventory.ItemStack) *Shapeless {
+	return &Shapeless{Ingredients: ingredients, result: result}
+}
+
+func (r *Shapeless) Matches(grid *inventory.Inventory, _ int) bool {
+	remaining := slices.Clone(r.Ingredients)
+	for slot := 0; slot < grid.Size(); slot++ {
+		stack := grid.Slot(slot)
+		if stack.IsEmpty() {
+			continue
+		}
+		i := slices.IndexFunc(remaining, func(ingredient Ingredient) bool {
+			return ingredient.matches(stack)
+		})
+		if i < 0 {
+			return false
+		}
+		remaining = slices.Delete(remaining, i, i+1)
+	}
+	return len(remaining) == 0
+}
+
+func (r *Shapeless) Result() inventory.ItemStack {
+	return r.result
+}
+
+func (r *Shapeless) size() int {
+	return len(r.Ingredients)
+}
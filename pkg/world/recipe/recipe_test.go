@@ -0,0 +1,232 @@
+package recipe
+
+import (
+	"testing"
+
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+var (
+	empty    = inventory.EmptyStack()
+	planks   = inventory.NewItemStack(material.WoodenPlanks, 1, 0)
+	stick    = inventory.NewItemStack(material.Stick, 1, 0)
+	iron     = inventory.NewItemStack(material.IronIngot, 1, 0)
+	redWool  = inventory.NewItemStack(material.Wool, 1, 14)
+	blueWool = inventory.NewItemStack(material.Wool, 1, 11)
+)
+
+// gridOf creates a crafting grid of the stacks given row by row
+func gridOf(stacks ...inventory.ItemStack) *inventory.Inventory {
+	grid := inventory.NewInventory(len(stacks))
+	for i, stack := range stacks {
+		grid.SetSlot(i, stack)
+	}
+	return grid
+}
+
+func TestMatch(t *testing.T) {
+	registry, err := NewDefaultRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		width int
+		grid  *inventory.Inventory
+		want  inventory.ItemStack
+	}{
+		{"shaped", 3, gridOf(
+			planks, planks, planks,
+			empty, stick, empty,
+			empty, stick, empty,
+		), inventory.NewItemStack(material.WoodenPickaxe, 1, 0)},
+		{"shaped moved", 3, gridOf(
+			empty, empty, iron,
+			empty, empty, iron,
+			empty, empty, stick,
+		), inventory.NewItemStack(material.IronSword, 1, 0)},
+		{"shaped with an extra item", 3, gridOf(
+			empty, empty, iron,
+			empty, empty, iron,
+			stick, empty, stick,
+		), empty},
+		{"shaped mirrored", 3, gridOf(
+			iron, iron, empty,
+			stick, iron, empty,
+			stick, empty, empty,
+		), inventory.NewItemStack(material.IronAxe, 1, 0)},
+		{"shaped not turned", 3, gridOf(
+			iron, stick, stick,
+			iron, iron, empty,
+			empty, empty, empty,
+		), empty},
+		{"2x2 in 2x2 grid", 2, gridOf(
+			planks, planks,
+			planks, planks,
+		), inventory.NewItemStack(material.CraftingTable, 1, 0)},
+		{"2x2 in 3x3 grid", 3, gridOf(
+			empty, empty, empty,
+			empty, planks, planks,
+			empty, planks, planks,
+		), inventory.NewItemStack(material.CraftingTable, 1, 0)},
+		{"2x2 mirrored in 3x3 grid", 3, gridOf(
+			empty, iron, empty,
+			iron, empty, empty,
+			empty, empty, empty,
+		), inventory.NewItemStack(material.Shears, 1, 0)},
+		{"3x3 does not fit 2x2 grid", 2, gridOf(
+			planks, planks,
+			planks, empty,
+		), empty},
+		{"shapeless", 3, gridOf(
+			empty, empty, inventory.NewItemStack(material.Wool, 1, 0),
+			empty, empty, empty,
+			inventory.NewItemStack(material.Dye, 1, 1), empty, empty,
+		), inventory.NewItemStack(material.Wool, 1, 14)},
+		{"shapeless with an extra item", 2, gridOf(
+			inventory.NewItemStack(material.Dye, 1, 1), inventory.NewItemStack(material.Wool, 1, 0),
+			stick, empty,
+		), empty},
+		{"shapeless with other data", 2, gridOf(
+			inventory.NewItemStack(material.Dye, 1, 1), blueWool,
+			empty, empty,
+		), empty},
+		// blocks without data in the recipe match any data, items match data 0 only
+		{"blocks of any data", 3, gridOf(
+			redWool, blueWool, inventory.NewItemStack(material.Wool, 1, 0),
+			planks, planks, planks,
+			empty, empty, empty,
+		), inventory.NewItemStack(material.BedItem, 1, 0)},
+		{"logs of any data", 2, gridOf(
+			empty, inventory.NewItemStack(material.Wood, 1, 2),
+			empty, empty,
+		), inventory.NewItemStack(material.WoodenPlanks, 4, 0)},
+		{"items of data 0", 2, gridOf(
+			inventory.NewItemStack(material.Coal, 1, 0), empty,
+			stick, empty,
+		), inventory.NewItemStack(material.Torch, 4, 0)},
+		{"items of other data", 2, gridOf(
+			inventory.NewItemStack(material.Coal, 1, 1), empty,
+			stick, empty,
+		), empty},
+		{"items of data 1", 2, gridOf(
+			empty, inventory.NewItemStack(material.IronIngot, 1, 1),
+			iron, empty,
+		), empty},
+		{"damaged items", 3, gridOf(
+			inventory.NewItemStack(material.Cobblestone, 1, 0), inventory.NewItemStack(material.Cobblestone, 1, 0),
+			inventory.NewItemStack(material.Cobblestone, 1, 0),
+			inventory.NewItemStack(material.Cobblestone, 1, 0), inventory.NewItemStack(material.Bow, 1, 20),
+			inventory.NewItemStack(material.Cobblestone, 1, 0),
+			inventory.NewItemStack(material.Cobblestone, 1, 0), inventory.NewItemStack(material.RedstoneDust, 1, 0),
+			inventory.NewItemStack(material.Cobblestone, 1, 0),
+		), empty},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := registry.Match(test.grid, test.width)
+			if got != test.want || ok == test.want.IsEmpty() {
+				t.Errorf("crafted %v:%v, want %v:%v", got, got.Data, test.want, test.want.Data)
+			}
+		})
+	}
+}
+
+func TestCraftingClick(t *testing.T) {
+	registry, err := NewDefaultRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, right := range []bool{false, true} {
+		player := inventory.NewPlayerInventory()
+		items, result := inventory.NewInventory(inventory.WorkbenchGridSize), inventory.NewInventory(1)
+		window := inventory.NewWorkbenchWindow(1, player, items, result)
+		grid := NewGrid(registry, items, result, 3)
+		window.Slots[0].OnTake = grid.Take
+
+		// two stacks of planks make two crafting tables, or four stacks of sticks
+		for _, slot := range []int{0, 3} {
+			items.SetSlot(slot, inventory.NewItemStack(material.WoodenPlanks, 2, 0))
+		}
+		grid.Update()
+		cursor := inventory.EmptyStack()
+		if _, _, ok := window.Click(0, right, false, &cursor); !ok {
+			t.Fatal("click on the result was rejected")
+		}
+		// the whole result is taken whichever button is used, the ingredients of a single craft are used up
+		if want := inventory.NewItemStack(material.Stick, 4, 0); cursor != want {
+			t.Errorf("right %v: cursor has %v, want %v", right, cursor, want)
+		}
+		for _, slot := range []int{0, 3} {
+			if want := inventory.NewItemStack(material.WoodenPlanks, 1, 0); items.Slot(slot) != want {
+				t.Errorf("right %v: slot %v of the grid has %v, want %v", right, slot, items.Slot(slot), want)
+			}
+		}
+		if want := inventory.NewItemStack(material.Stick, 4, 0); result.Slot(0) != want {
+			t.Errorf("right %v: result is %v after crafting, want %v", right, result.Slot(0), want)
+		}
+
+		// the next result is added to the same items on the cursor
+		window.Click(0, right, false, &cursor)
+		if want := inventory.NewItemStack(material.Stick, 8, 0); cursor != want {
+			t.Errorf("right %v: cursor has %v after crafting again, want %v", right, cursor, want)
+		}
+		if !items.Slot(0).IsEmpty() || !items.Slot(3).IsEmpty() || !result.Slot(0).IsEmpty() {
+			t.Errorf("right %v: grid has %v and %v and result %v, want them empty", right,
+				items.Slot(0), items.Slot(3), result.Slot(0))
+		}
+	}
+}
+
+func TestTakeLeavesContainers(t *testing.T) {
+	registry, err := NewDefaultRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	milk, sugar := inventory.NewItemStack(material.MilkBucket, 1, 0), inventory.NewItemStack(material.Sugar, 1, 0)
+	wheat, egg := inventory.NewItemStack(material.Wheat, 1, 0), inventory.NewItemStack(material.Egg, 1, 0)
+	items := gridOf(
+		milk, milk, milk,
+		sugar, egg, sugar,
+		wheat, wheat, wheat,
+	)
+	grid := NewGrid(registry, items, inventory.NewInventory(1), 3)
+	grid.Update()
+	if want := inventory.NewItemStack(material.CakeItem, 1, 0); grid.Result.Slot(0) != want {
+		t.Fatalf("result is %v, want %v", grid.Result.Slot(0), want)
+	}
+	grid.Take(grid.Result.Slot(0))
+	for slot := 0; slot < items.Size(); slot++ {
+		want := empty
+		if slot < 3 {
+			want = inventory.NewItemStack(material.Bucket, 1, 0)
+		}
+		if items.Slot(slot) != want {
+			t.Errorf("slot %v has %v after crafting, want %v", slot, items.Slot(slot), want)
+		}
+	}
+}
+
+func TestParseIngredient(t *testing.T) {
+	tests := []struct {
+		item string
+		want Ingredient
+	}{
+		{"35", Ingredient{Material: material.Wool, AnyData: true}},
+		{"35:14", Ingredient{Material: material.Wool, Data: 14}},
+		{"265", Ingredient{Material: material.IronIngot}},
+		{"351:4", Ingredient{Material: material.Dye, Data: 4}},
+	}
+	for _, test := range tests {
+		got, err := parseIngredient(test.item)
+		if err != nil || got != test.want {
+			t.Errorf("item %q is %+v, error %v, want %+v", test.item, got, err, test.want)
+		}
+	}
+	for _, item := range []string{"", "0", "65535", "wool", "35:x"} {
+		if _, err := parseIngredient(item); err == nil {
+			t.Errorf("invalid item %q was parsed", item)
+		}
+	}
+}
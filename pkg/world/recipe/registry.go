@@ -0,0 +1,184 @@
+package recipe
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// defaultRecipes are the recipes of the Notchian server
+//
+//go:embed recipes.json
+var defaultRecipes string
+
+// Registry holds the recipes the players can craft.
+type Registry struct {
+	recipes []Recipe
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// NewDefaultRegistry creates a registry with all recipes of the Notchian server.
+func NewDefaultRegistry() (*Registry, error) {
+	registry := NewRegistry()
+	if err := registry.Load(strings.NewReader(defaultRecipes)); err != nil {
+		return nil, fmt.Errorf("failed to load the default recipes: %w", err)
+	}
+	return registry, nil
+}
+
+// Add adds the recipes to the registry. The shaped recipes are matched before the shapeless ones
+// and the larger recipes before the smaller ones, as on Notchian.
+func (r *Registry) Add(recipes ...Recipe) {
+	r.recipes = append(r.recipes, recipes...)
+	sort.SliceStable(r.recipes, func(i, j int) bool {
+		_, shapelessI := r.recipes[i].(*Shapeless)
+		_, shapelessJ := r.recipes[j].(*Shapeless)
+		if shapelessI != shapelessJ {
+			return shapelessJ
+		}
+		return r.recipes[i].size() > r.recipes[j].size()
+	})
+}
+
+// Match returns the result of the first recipe matching the items in the grid of given width.
+func (r *Registry) Match(grid *inventory.Inventory, width int) (inventory.ItemStack, bool) {
+	for _, recipe := range r.recipes {
+		if recipe.Matches(grid, width) {
+			return recipe.Result(), true
+		}
+	}
+	return inventory.EmptyStack(), false
+}
+
+// recipeData is a recipe as it is written in the data files. The items are written as "id" or "id:data",
+// blocks written without data match the blocks with any data and items written without data match data 0,
+// as on Notchian.
+type recipeData struct {
+	Result string `json:"result"`
+	Count  byte   `json:"count"`
+	// Pattern are the rows of a shaped recipe, each character is an ingredient of the key, spaces are empty slots
+	Pattern []string          `json:"pattern"`
+	Key     map[string]string `json:"key"`
+	// Ingredients of a shapeless recipe
+	Ingredients []string `json:"ingredients"`
+}
+
+// Load adds the recipes from the data file, a JSON list of recipes.
+func (r *Registry) Load(reader io.Reader) error {
+	var data []recipeData
+	if err := json.NewDecoder(reader).Decode(&data); err != nil {
+		return err
+	}
+	recipes := make([]Recipe, 0, len(data))
+	for i, d := range data {
+		recipe, err := d.recipe()
+		if err != nil {
+			return fmt.Errorf("recipe %v: %w", i, err)
+		}
+		recipes = append(recipes, recipe)
+	}
+	r.Add(recipes...)
+	return nil
+}
+
+// LoadFile adds the recipes from the data file at the path.
+func (r *Registry) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err = r.Load(file); err != nil {
+		return fmt.Errorf("%v: %w", path, err)
+	}
+	return nil
+}
+
+func (d recipeData) recipe() (Recipe, error) {
+	result, err := parseIngredient(d.Result)
+	if err != nil {
+		return nil, err
+	}
+	count := d.Count
+	if count == 0 {
+		count = 1
+	}
+	stack := inventory.NewItemStack(result.Material, count, result.Data)
+
+	if d.Pattern != nil {
+		return d.shaped(stack)
+	}
+	if len(d.Ingredients) == 0 || len(d.Ingredients) > 9 {
+		return nil, fmt.Errorf("a recipe needs a pattern or 1 to 9 ingredients")
+	}
+	ingredients := make([]Ingredient, len(d.Ingredients))
+	for i, item := range d.Ingredients {
+		if ingredients[i], err = parseIngredient(item); err != nil {
+			return nil, err
+		}
+	}
+	return NewShapeless(ingredients, stack), nil
+}
+
+func (d recipeData) shaped(result inventory.ItemStack) (*Shaped, error) {
+	height := len(d.Pattern)
+	if height == 0 || height > 3 {
+		return nil, fmt.Errorf("the pattern must have 1 to 3 rows")
+	}
+	width := len(d.Pattern[0])
+	ingredients := make([]*Ingredient, 0, width*height)
+	for _, row := range d.Pattern {
+		if len(row) != width || width == 0 || width > 3 {
+			return nil, fmt.Errorf("the rows of the pattern must have the same length of 1 to 3")
+		}
+		for _, symbol := range row {
+			if symbol == ' ' {
+				ingredients = append(ingredients, nil)
+				continue
+			}
+			item, ok := d.Key[string(symbol)]
+			if !ok {
+				return nil, fmt.Errorf("'%c' is not in the key", symbol)
+			}
+			ingredient, err := parseIngredient(item)
+			if err != nil {
+				return nil, err
+			}
+			ingredients = append(ingredients, &ingredient)
+		}
+	}
+	return NewShaped(width, height, ingredients, result), nil
+}
+
+// parseIngredient parses an item written as "id" or "id:data"
+func parseIngredient(item string) (Ingredient, error) {
+	idText, dataText, hasData := strings.Cut(item, ":")
+	id, err := strconv.ParseUint(idText, 10, 16)
+	if err != nil {
+		return Ingredient{}, fmt.Errorf("invalid item '%v'", item)
+	}
+	m, err := material.FromID(uint16(id))
+	if err != nil || m == material.Air {
+		return Ingredient{}, fmt.Errorf("unknown item '%v'", item)
+	}
+	if !hasData {
+		_, block := m.(*material.Block)
+		return Ingredient{Material: m, AnyData: block}, nil
+	}
+	data, err := strconv.ParseUint(dataText, 10, 16)
+	if err != nil {
+		return Ingredient{}, fmt.Errorf("invalid data of item '%v'", item)
+	}
+	return Ingredient{Material: m, Data: uint16(data)}, nil
+}
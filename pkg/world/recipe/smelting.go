@@ -0,0 +1,42 @@
+package recipe
+
+import (
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// smelting are the items the materials turn into in a furnace
+var smelting = map[material.Material]inventory.ItemStack{
+	material.IronOre:     inventory.NewItemStack(material.IronIngot, 1, 0),
+	material.GoldOre:     inventory.NewItemStack(material.GoldIngot, 1, 0),
+	material.DiamondOre:  inventory.NewItemStack(material.Diamond, 1, 0),
+	material.Sand:        inventory.NewItemStack(material.Glass, 1, 0),
+	material.RawPorkchop: inventory.NewItemStack(material.CookedPorkchop, 1, 0),
+	material.RawFish:     inventory.NewItemStack(material.CookedFish, 1, 0),
+	material.Cobblestone: inventory.NewItemStack(material.Stone, 1, 0),
+	material.ClayBalls:   inventory.NewItemStack(material.ClayBrick, 1, 0),
+	material.Cactus:      inventory.NewItemStack(material.Dye, 1, 2),  // cactus green
+	material.Wood:        inventory.NewItemStack(material.Coal, 1, 1), // charcoal
+}
+
+// Smelt returns the item the material turns into when it is smelted in a furnace.
+func Smelt(m material.Material) (inventory.ItemStack, bool) {
+	result, ok := smelting[m]
+	return result, ok
+}
+
+// FuelTime returns the number of ticks the item burns for in a furnace, 0 if it is not a fuel.
+func FuelTime(m material.Material) int {
+	if block, ok := m.(*material.Block); ok && block.Group == material.GroupWood {
+		return 300
+	}
+	switch m {
+	case material.Stick:
+		return 100
+	case material.Coal:
+		return 1600
+	case material.LavaBucket:
+		return 20000
+	}
+	return 0
+}
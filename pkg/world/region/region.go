@@ -0,0 +1,253 @@
+// Package region implements the McRegion file format used by Beta 1.3 - 1.7.3 to store chunks.
+//
+// A region file holds 32x32 chunks. It starts with a header of two tables of 1024 entries, the chunk
+// locations (sector offset and sector count) and the chunk timestamps. Chunk data is stored in 4096 bytes
+// long sectors, prefixed by its length and compression type.
+package region
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// Size is the number of chunks along a side of a region
+	Size = 32
+
+	sectorSize   = 4096
+	headerSize   = 2 * sectorSize
+	maxSectors   = 255 // sector count is stored in a single byte
+	headerLength = 5   // chunk data length and compression type
+)
+
+// Compression is the compression type of chunk data
+type Compression byte
+
+const (
+	Gzip Compression = 1
+	Zlib Compression = 2
+)
+
+// FileName returns name of the region file containing chunk at given chunk coordinates.
+func FileName(chunkX, chunkZ int32) string {
+	return fmt.Sprintf("r.%d.%d.mcr", chunkX>>5, chunkZ>>5)
+}
+
+// File is an opened region file.
+type File struct {
+	file *os.File
+
+	locations  [Size * Size]uint32
+	timestamps [Size * Size]uint32
+
+	// free marks sectors that are not used by any chunk
+	free []bool
+}
+
+// Open opens the region file at given path, the file is created if it does not exist yet.
+func Open(path string) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	r := &File{file: file}
+	if err = r.readHeader(); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to read region file %v: %v", path, err)
+	}
+	return r, nil
+}
+
+func (r *File) readHeader() error {
+	info, err := r.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := info.Size()
+	if size < headerSize {
+		// new or damaged file, write an empty header
+		if _, err = r.file.WriteAt(make([]byte, headerSize-size), size); err != nil {
+			return err
+		}
+		size = headerSize
+	}
+	if size%sectorSize != 0 {
+		// pad the file to whole sectors
+		if _, err = r.file.WriteAt(make([]byte, sectorSize-size%sectorSize), size); err != nil {
+			return err
+		}
+		size += sectorSize - size%sectorSize
+	}
+
+	r.free = make([]bool, size/sectorSize)
+	for i := 2; i < len(r.free); i++ {
+		r.free[i] = true
+	}
+
+	header := make([]byte, headerSize)
+	if _, err = r.file.ReadAt(header, 0); err != nil {
+		return err
+	}
+	for i := range r.locations {
+		location := binary.BigEndian.Uint32(header[i*4:])
+		r.timestamps[i] = binary.BigEndian.Uint32(header[sectorSize+i*4:])
+
+		offset, count := int(location>>8), int(location&0xFF)
+		if location == 0 || offset < 2 || offset+count > len(r.free) {
+			continue // not present or invalid, the chunk will be regenerated
+		}
+		r.locations[i] = location
+		for sector := offset; sector < offset+count; sector++ {
+			r.free[sector] = false
+		}
+	}
+	return nil
+}
+
+// HasChunk checks whether the region contains chunk at given chunk coordinates.
+func (r *File) HasChunk(chunkX, chunkZ int32) bool {
+	return r.locations[index(chunkX, chunkZ)] != 0
+}
+
+// ReadChunk returns the decompressed data of chunk at given chunk coordinates,
+// or nil if the chunk is not present in the region.
+func (r *File) ReadChunk(chunkX, chunkZ int32) ([]byte, error) {
+	location := r.locations[index(chunkX, chunkZ)]
+	if location == 0 {
+		return nil, nil
+	}
+	offset, count := int64(location>>8), int64(location&0xFF)
+
+	header := make([]byte, headerLength)
+	if _, err := r.file.ReadAt(header, offset*sectorSize); err != nil {
+		return nil, err
+	}
+	length := int64(binary.BigEndian.Uint32(header))
+	if length < 1 || length+4 > count*sectorSize {
+		return nil, fmt.Errorf("invalid length of chunk %v;%v: %v", chunkX, chunkZ, length)
+	}
+
+	compressed := make([]byte, length-1)
+	if _, err := r.file.ReadAt(compressed, offset*sectorSize+headerLength); err != nil {
+		return nil, err
+	}
+
+	var reader io.ReadCloser
+	var err error
+	switch Compression(header[4]) {
+	case Gzip:
+		reader, err = gzip.NewReader(bytes.NewReader(compressed))
+	case Zlib:
+		reader, err = zlib.NewReader(bytes.NewReader(compressed))
+	default:
+		return nil, fmt.Errorf("unknown compression type of chunk %v;%v: %v", chunkX, chunkZ, header[4])
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// WriteChunk compresses the data and stores them as chunk at given chunk coordinates.
+func (r *File) WriteChunk(chunkX, chunkZ int32, data []byte) error {
+	var compressed bytes.Buffer
+	compressed.Write(make([]byte, headerLength))
+	writer := zlib.NewWriter(&compressed)
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	payload := compressed.Bytes()
+	binary.BigEndian.PutUint32(payload, uint32(len(payload)-4))
+	payload[4] = byte(Zlib)
+
+	count := (len(payload) + sectorSize - 1) / sectorSize
+	if count > maxSectors {
+		return fmt.Errorf("chunk %v;%v is too large to be stored in a region: %v bytes", chunkX, chunkZ, len(payload))
+	}
+
+	i := index(chunkX, chunkZ)
+	oldOffset, oldCount := int(r.locations[i]>>8), int(r.locations[i]&0xFF)
+	offset := oldOffset
+	if oldCount != count {
+		// release the old sectors and find a new run of free sectors that fits the chunk
+		for sector := oldOffset; sector < oldOffset+oldCount; sector++ {
+			r.free[sector] = true
+		}
+		offset = r.allocate(count)
+	}
+
+	// pad to whole sectors so the file size stays aligned
+	padded := make([]byte, count*sectorSize)
+	copy(padded, payload)
+	if _, err := r.file.WriteAt(padded, int64(offset)*sectorSize); err != nil {
+		return err
+	}
+
+	r.locations[i] = uint32(offset)<<8 | uint32(count)
+	r.timestamps[i] = uint32(time.Now().Unix())
+
+	entry := make([]byte, 4)
+	binary.BigEndian.PutUint32(entry, r.locations[i])
+	if _, err := r.file.WriteAt(entry, int64(i*4)); err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(entry, r.timestamps[i])
+	_, err := r.file.WriteAt(entry, int64(sectorSize+i*4))
+	return err
+}
+
+// allocate marks a run of free sectors of given length as used and returns its offset.
+// The file grows if there is no such run.
+func (r *File) allocate(count int) int {
+	run := 0
+	for sector := 2; sector < len(r.free); sector++ {
+		if !r.free[sector] {
+			run = 0
+			continue
+		}
+		run++
+		if run == count {
+			offset := sector - count + 1
+			for s := offset; s <= sector; s++ {
+				r.free[s] = false
+			}
+			return offset
+		}
+	}
+
+	offset := len(r.free)
+	for s := 0; s < count; s++ {
+		r.free = append(r.free, false)
+	}
+	return offset
+}
+
+// Sync commits the region file to the disk.
+func (r *File) Sync() error {
+	return r.file.Sync()
+}
+
+// Close closes the region file.
+func (r *File) Close() error {
+	return r.file.Close()
+}
+
+// index returns index of a chunk in the region header tables
+func index(chunkX, chunkZ int32) int {
+	return int(chunkX&(Size-1)) + int(chunkZ&(Size-1))*Size
+}
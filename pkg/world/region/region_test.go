@@ -0,0 +1,66 @@
+package region
+
+import (
+	"bytes"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// randomData returns data of given length that do not compress, so they take the sectors they need
+func randomData(r *rand.Rand, length int) []byte {
+	data := make([]byte, length)
+	r.Read(data)
+	return data
+}
+
+func TestFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "region", FileName(-1, 2))
+	r := rand.New(rand.NewSource(1))
+	chunks := []struct {
+		x, z int32
+		data []byte
+	}{
+		{-1, 2, []byte("a small chunk")},
+		{-32, 63, randomData(r, 3*sectorSize)},
+		{-17, 40, randomData(r, 100)},
+		{-1, 2, randomData(r, 2*sectorSize)}, // rewritten larger, it moves to new sectors
+		{-17, 40, []byte("rewritten smaller")},
+	}
+
+	file, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make(map[[2]int32][]byte)
+	for _, chunk := range chunks {
+		if err := file.WriteChunk(chunk.x, chunk.z, chunk.data); err != nil {
+			t.Fatal(err)
+		}
+		want[[2]int32{chunk.x, chunk.z}] = chunk.data
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	for pos, data := range want {
+		got, err := file.ReadChunk(pos[0], pos[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("chunk %v;%v has %v bytes, want %v bytes written", pos[0], pos[1], len(got), len(data))
+		}
+	}
+	if file.HasChunk(-2, 2) {
+		t.Error("chunk -2;2 that was not written is present")
+	}
+	if data, err := file.ReadChunk(-2, 2); data != nil || err != nil {
+		t.Errorf("chunk -2;2 that was not written has %v bytes, error %v", len(data), err)
+	}
+}
@@ -0,0 +1,177 @@
+package world
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/Pesekjak/173go/pkg/nbt"
+	"github.com/Pesekjak/173go/pkg/world/material"
+	"github.com/Pesekjak/173go/pkg/world/region"
+)
+
+// chunkStorage loads and saves chunks of a world in the McRegion format
+type chunkStorage struct {
+	// directory of the region files
+	dir     string
+	regions map[ChunkPos]*region.File
+}
+
+func newChunkStorage(worldDir string) *chunkStorage {
+	return &chunkStorage{
+		dir:     filepath.Join(worldDir, "region"),
+		regions: make(map[ChunkPos]*region.File),
+	}
+}
+
+// region returns the opened region file containing chunk at given position
+func (s *chunkStorage) region(pos ChunkPos) (*region.File, error) {
+	regionPos := NewChunkPos(pos.X>>5, pos.Z>>5)
+	if file, ok := s.regions[regionPos]; ok {
+		return file, nil
+	}
+	file, err := region.Open(filepath.Join(s.dir, region.FileName(pos.X, pos.Z)))
+	if err != nil {
+		return nil, err
+	}
+	s.regions[regionPos] = file
+	return file, nil
+}
+
+// loadChunk reads the chunk at given position into the chunk, ok is false if it has not been saved yet
+func (s *chunkStorage) loadChunk(chunk *Chunk) (ok bool, err error) {
+	file, err := s.region(chunk.pos)
+	if err != nil {
+		return false, err
+	}
+	data, err := file.ReadChunk(chunk.pos.X, chunk.pos.Z)
+	if err != nil || data == nil {
+		return false, err
+	}
+	var root chunkRoot
+	if _, err = nbt.Unmarshal(data, &root); err != nil {
+		return false, err
+	}
+	return true, chunk.readNBT(&root.Level)
+}
+
+// saveChunk writes the chunk to its region file
+func (s *chunkStorage) saveChunk(chunk *Chunk) error {
+	file, err := s.region(chunk.pos)
+	if err != nil {
+		return err
+	}
+	data, err := nbt.Marshal("", chunkRoot{Level: chunk.writeNBT()})
+	if err != nil {
+		return err
+	}
+	return file.WriteChunk(chunk.pos.X, chunk.pos.Z, data)
+}
+
+// sync commits all opened region files to the disk
+func (s *chunkStorage) sync() error {
+	for _, file := range s.regions {
+		if err := file.Sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close closes all opened region files
+func (s *chunkStorage) close() error {
+	var firstErr error
+	for pos, file := range s.regions {
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(s.regions, pos)
+	}
+	return firstErr
+}
+
+// chunkRoot is the root compound of a chunk stored in a region file
+type chunkRoot struct {
+	Level chunkLevel `nbt:"Level"`
+}
+
+// chunkLevel is the McRegion chunk data
+type chunkLevel struct {
+	XPos             int32    `nbt:"xPos"`
+	ZPos             int32    `nbt:"zPos"`
+	LastUpdate       int64    `nbt:"LastUpdate"`
+	TerrainPopulated bool     `nbt:"TerrainPopulated"`
+	Blocks           []byte   `nbt:"Blocks"`
+	Data             []byte   `nbt:"Data"`
+	SkyLight         []byte   `nbt:"SkyLight"`
+	BlockLight       []byte   `nbt:"BlockLight"`
+	HeightMap        []byte   `nbt:"HeightMap"`
+	Entities         nbt.List `nbt:"Entities"`
+	TileEntities     nbt.List `nbt:"TileEntities"`
+}
+
+// readNBT loads the chunk from the McRegion level data
+func (c *Chunk) readNBT(level *chunkLevel) error {
+	if level.XPos != c.pos.X || level.ZPos != c.pos.Z {
+		return fmt.Errorf("chunk %v;%v is stored at wrong position %v;%v", level.XPos, level.ZPos, c.pos.X, c.pos.Z)
+	}
+
+	arrays := []struct {
+		name     string
+		src, dst []byte
+	}{
+		{"Blocks", level.Blocks, c.blockTypes},
+		{"Data", level.Data, c.blockMetadata},
+		{"BlockLight", level.BlockLight, c.blockLight.data},
+		{"SkyLight", level.SkyLight, c.skyLight.data},
+	}
+	for _, array := range arrays {
+		if len(array.src) != len(array.dst) {
+			return fmt.Errorf("missing or invalid %v of chunk %v;%v", array.name, c.pos.X, c.pos.Z)
+		}
+	}
+
+	for _, array := range arrays {
+		copy(array.dst, array.src)
+	}
+	// blocks this server does not know, like the ones of mods, are replaced with air
+	for i, id := range c.blockTypes {
+		if _, ok := material.BlockFromID(id); !ok {
+			c.blockTypes[i] = byte(material.Air.Id())
+			c.blockMetadata[i/2] &^= 0x0F << (4 * (i % 2))
+			c.dirty = true
+		}
+	}
+	c.populated = level.TerrainPopulated
+
+	// entities are kept as they are, so they are not lost when the chunk is saved again
+	c.entitiesNBT = level.Entities
+	c.tileEntitiesNBT = nbt.NewList(nbt.TagCompound)
+	for _, value := range level.TileEntities.Values {
+		if data, ok := value.(nbt.Compound); ok {
+			c.readTileEntityNBT(data)
+		}
+	}
+	return nil
+}
+
+// writeNBT creates the McRegion level data of the chunk
+func (c *Chunk) writeNBT() chunkLevel {
+	entities := c.entitiesNBT
+	if entities.Type == nbt.TagEnd {
+		entities = nbt.NewList(nbt.TagCompound)
+	}
+
+	return chunkLevel{
+		XPos:             c.pos.X,
+		ZPos:             c.pos.Z,
+		LastUpdate:       c.world.time,
+		TerrainPopulated: c.populated,
+		Blocks:           c.blockTypes,
+		Data:             c.blockMetadata,
+		SkyLight:         c.skyLight.data,
+		BlockLight:       c.blockLight.data,
+		HeightMap:        c.heights,
+		Entities:         entities,
+		TileEntities:     c.writeTileEntitiesNBT(),
+	}
+}
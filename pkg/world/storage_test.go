@@ -0,0 +1,198 @@
+package world
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// failingGenerator fails to generate any chunk, the chunks of a reopened world have to be loaded from the disk
+type failingGenerator struct{}
+
+func (failingGenerator) GenerateBlocks(chunk *Chunk) error {
+	return errors.New("chunk was not saved")
+}
+
+// reopenWorld closes the world and opens it again from its directory
+func reopenWorld(t *testing.T, w *World) *World {
+	t.Helper()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewWorld(w.dir, w.dimension, w.seed, func(int64) (Generator, error) {
+		return failingGenerator{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reopened
+}
+
+func TestChunkRoundTrip(t *testing.T) {
+	w := newTestWorld(t)
+	positions := []ChunkPos{NewChunkPos(0, 0), NewChunkPos(-1, 0), NewChunkPos(-33, 40)}
+	for _, pos := range positions {
+		if _, err := w.LoadChunk(pos); err != nil {
+			t.Fatal(err)
+		}
+	}
+	blocks := []struct {
+		pos   BlockPos
+		block *material.Block
+		data  byte
+	}{
+		{NewBlockPos(0, 5, 0), material.Torch, 5},
+		{NewBlockPos(-1, 5, 3), material.Wool, 14},
+		{NewBlockPos(-16, 10, 15), material.GlowstoneBlock, 0},
+		{NewBlockPos(-528, 127, 640), material.Stone, 0},
+		{NewBlockPos(-520, 4, 645), material.Air, 0},
+	}
+	for _, b := range blocks {
+		if err := w.setBlock(b.pos.X, b.pos.Y, b.pos.Z, b.block, b.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.light.flush()
+
+	saved := make(map[ChunkPos]*Chunk)
+	for _, pos := range positions {
+		chunk, _ := w.Chunk(pos)
+		saved[pos] = chunk
+	}
+	w = reopenWorld(t, w)
+	for _, pos := range positions {
+		loaded, err := w.LoadChunk(pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := saved[pos]
+		for _, array := range []struct {
+			name      string
+			got, want []byte
+		}{
+			{"blocks", loaded.blockTypes, want.blockTypes},
+			{"data", loaded.blockMetadata, want.blockMetadata},
+			{"block light", loaded.blockLight.data, want.blockLight.data},
+			{"sky light", loaded.skyLight.data, want.skyLight.data},
+			{"heights", loaded.heights, want.heights},
+		} {
+			if !bytes.Equal(array.got, array.want) {
+				t.Errorf("%v of chunk %v;%v differ after loading", array.name, pos.X, pos.Z)
+			}
+		}
+		if loaded.populated != want.populated {
+			t.Errorf("chunk %v;%v populated %v, want %v", pos.X, pos.Z, loaded.populated, want.populated)
+		}
+	}
+	for _, b := range blocks {
+		if block, data, _ := w.blockAt(b.pos); block != b.block || data != b.data {
+			t.Errorf("block at %v is %v:%v, want %v:%v", b.pos, block, data, b.block, b.data)
+		}
+	}
+}
+
+func TestChunkUnknownBlocks(t *testing.T) {
+	unknown := byte(255)
+	for ; unknown > 0; unknown-- {
+		if _, ok := material.BlockFromID(unknown); !ok {
+			break
+		}
+	}
+
+	w := newTestWorld(t)
+	chunk, err := w.LoadChunk(NewChunkPos(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the unknown block replaces the grass at 0 4 0, its data is kept with it
+	index := blockIndex(0, 4, 0)
+	chunk.blockTypes[index] = unknown
+	chunk.blockMetadata[index/2] |= 0x07 << (4 * (index % 2))
+	chunk.dirty = true
+
+	w = reopenWorld(t, w)
+	for _, c := range []struct {
+		pos   BlockPos
+		block *material.Block
+	}{
+		{NewBlockPos(0, 4, 0), material.Air},
+		{NewBlockPos(1, 4, 0), material.GrassBlock},
+		{NewBlockPos(0, 3, 0), material.Dirt},
+	} {
+		block, data, err := w.blockAt(c.pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if block != c.block || data != 0 {
+			t.Errorf("block at %v is %v:%v, want %v:0", c.pos, block, data, c.block)
+		}
+	}
+}
+
+func TestUnloadChunks(t *testing.T) {
+	w := newTestWorld(t)
+	load := func(from, to ChunkPos) {
+		for x := from.X; x <= to.X; x++ {
+			for z := from.Z; z <= to.Z; z++ {
+				if _, err := w.LoadChunk(NewChunkPos(x, z)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+	// the chunk at 40;40 has been sent to a player, the chunks around it are loaded as well
+	load(NewChunkPos(38, 38), NewChunkPos(42, 42))
+	viewed, _ := w.Chunk(NewChunkPos(40, 40))
+	viewed.viewers[1] = nil
+	// the chunks around the spawn, a chunk with a dropped item and a changed chunk
+	load(NewChunkPos(-1, -1), NewChunkPos(1, 1))
+	if _, err := w.DropItem(NewLocation(80*16+8, 10, 80*16+8, 0, 0),
+		inventory.NewItemStack(material.Stick, 1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	changed := NewBlockPos(60*16+3, 10, 60*16+3)
+	if err := w.setBlock(changed.X, changed.Y, changed.Z, material.GlowstoneBlock, 0); err != nil {
+		t.Fatal(err)
+	}
+	// more chunks than are unloaded at once
+	load(NewChunkPos(-100, 0), NewChunkPos(-1, 0))
+
+	for range 2 {
+		if err := w.UnloadChunks(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []struct {
+		pos    ChunkPos
+		loaded bool
+	}{
+		{NewChunkPos(40, 40), true},
+		{NewChunkPos(39, 41), true},
+		{NewChunkPos(41, 40), true},
+		{NewChunkPos(38, 40), false},
+		{NewChunkPos(42, 42), false},
+		{NewChunkPos(0, 0), true},
+		{NewChunkPos(-8, 0), true},
+		{NewChunkPos(-9, 0), false},
+		{NewChunkPos(-100, 0), false},
+		{NewChunkPos(80, 80), true},
+		{NewChunkPos(60, 60), false},
+	} {
+		if _, loaded := w.Chunk(c.pos); loaded != c.loaded {
+			t.Errorf("chunk %v;%v loaded %v, want %v", c.pos.X, c.pos.Z, loaded, c.loaded)
+		}
+	}
+	// the chunks around the viewed chunk and the spawn, the spawn chunks loaded at -8;0 to -2;0 and the item
+	if want := 9 + 9 + 7 + 1; len(w.chunks) != want {
+		t.Errorf("%v chunks loaded after unloading, want %v", len(w.chunks), want)
+	}
+
+	// the changed chunk was saved before it was unloaded
+	w = reopenWorld(t, w)
+	if block, _, err := w.blockAt(changed); err != nil || block != material.GlowstoneBlock {
+		t.Errorf("block at %v is %v, error %v, want %v", changed, block, err, material.GlowstoneBlock)
+	}
+}
@@ -0,0 +1,97 @@
+package world
+
+import (
+	"fmt"
+
+	"github.com/Pesekjak/173go/pkg/nbt"
+	"github.com/Pesekjak/173go/pkg/prot"
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// Sign is the tile entity of a sign, the four lines of its text.
+type Sign struct {
+	Lines [4]string
+}
+
+func (s *Sign) ReadNBT(data nbt.Compound) {
+	for i := range s.Lines {
+		s.Lines[i], _ = data.String(fmt.Sprintf("Text%v", i+1))
+	}
+}
+
+func (s *Sign) WriteNBT(data nbt.Compound) {
+	for i, line := range s.Lines {
+		data[fmt.Sprintf("Text%v", i+1)] = line
+	}
+}
+
+func (s *Sign) updatePacket(pos BlockPos) prot.PacketOut {
+	return &prot.PacketOutUpdateSign{X: pos.X, Y: int16(pos.Y), Z: pos.Z, Lines: s.Lines}
+}
+
+// MobSpawner is the tile entity of a mob spawner, the kind of the mobs it spawns.
+type MobSpawner struct {
+	// EntityID is the name of the spawned mobs, like "Zombie"
+	EntityID string
+	// Delay is the number of ticks until the next mobs are spawned
+	Delay int16
+}
+
+func newMobSpawner() *MobSpawner {
+	return &MobSpawner{EntityID: "Pig", Delay: 20}
+}
+
+func (s *MobSpawner) ReadNBT(data nbt.Compound) {
+	if id, ok := data.String("EntityId"); ok {
+		s.EntityID = id
+	}
+	if delay, ok := data.Short("Delay"); ok {
+		s.Delay = delay
+	}
+}
+
+func (s *MobSpawner) WriteNBT(data nbt.Compound) {
+	data["EntityId"] = s.EntityID
+	data["Delay"] = s.Delay
+}
+
+// NoteBlock is the tile entity of a note block, the pitch of its note.
+type NoteBlock struct {
+	Note byte
+}
+
+func (n *NoteBlock) ReadNBT(data nbt.Compound) {
+	note, _ := data.Byte("note")
+	n.Note = byte(note)
+}
+
+func (n *NoteBlock) WriteNBT(data nbt.Compound) {
+	data["note"] = int8(n.Note)
+}
+
+// Jukebox is the tile entity of a jukebox, the record put in it.
+type Jukebox struct {
+	// Record is the item id of the record, 0 if the jukebox is empty
+	Record int32
+}
+
+func (j *Jukebox) ReadNBT(data nbt.Compound) {
+	j.Record, _ = data.Int("Record")
+}
+
+func (j *Jukebox) WriteNBT(data nbt.Compound) {
+	data["Record"] = j.Record
+}
+
+// drops returns the record, it is ejected when the jukebox is broken
+func (j *Jukebox) drops() []inventory.ItemStack {
+	if j.Record == 0 {
+		return nil
+	}
+	record, err := material.FromID(uint16(j.Record))
+	if err != nil {
+		return nil
+	}
+	return []inventory.ItemStack{inventory.NewItemStack(record, 1, 0)}
+}
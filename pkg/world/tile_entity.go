@@ -0,0 +1,263 @@
+package world
+
+import (
+	"github.com/Pesekjak/173go/pkg/nbt"
+	"github.com/Pesekjak/173go/pkg/prot"
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// TileEntity is the state of a block its type and data can not hold, like the items of a chest or the text
+// of a sign. Tile entities are kept by the chunks and saved with them, each of them belongs to a single block.
+//
+// The tile entity of a block is created when the block is placed and removed when the block is broken
+// or replaced by a block of another kind.
+type TileEntity interface {
+	// ReadNBT loads the state of the tile entity from its data saved with the chunk
+	ReadNBT(data nbt.Compound)
+	// WriteNBT adds the state of the tile entity to its data saved with the chunk, the data already hold
+	// the id and the position of the tile entity
+	WriteNBT(data nbt.Compound)
+}
+
+// tickingTileEntity is a tile entity updated every tick, like a furnace
+type tickingTileEntity interface {
+	TileEntity
+	// tick updates the tile entity of the block at the position, it reports whether the tile entity changed
+	tick(w *World, pos BlockPos) bool
+}
+
+// droppingTileEntity is a tile entity whose items are dropped when its block is broken, like a chest
+type droppingTileEntity interface {
+	TileEntity
+	drops() []inventory.ItemStack
+}
+
+// updatingTileEntity is a tile entity the clients are told about by its own packet, like the text of a sign
+type updatingTileEntity interface {
+	TileEntity
+	// updatePacket creates the packet with the state of the tile entity of the block at the position
+	updatePacket(pos BlockPos) prot.PacketOut
+}
+
+// tileEntityType is a kind of tile entities, with the id they are saved with and the blocks that have them
+type tileEntityType struct {
+	id     string
+	blocks []*material.Block
+	create func() TileEntity
+}
+
+// tileEntityTypes are the tile entities of the Notchian server
+var tileEntityTypes = []tileEntityType{
+	{"Chest", []*material.Block{material.Chest}, func() TileEntity { return newChest() }},
+	{"Trap", []*material.Block{material.Dispenser}, func() TileEntity { return newDispenser() }},
+	{"Furnace", []*material.Block{material.Furnace, material.FurnaceLit}, func() TileEntity { return newFurnace() }},
+	{"Sign", []*material.Block{material.SignBlock, material.SignWall}, func() TileEntity { return &Sign{} }},
+	{"MobSpawner", []*material.Block{material.MobSpawner}, func() TileEntity { return newMobSpawner() }},
+	{"Music", []*material.Block{material.NoteBlock}, func() TileEntity { return &NoteBlock{} }},
+	{"RecordPlayer", []*material.Block{material.Jukebox}, func() TileEntity { return &Jukebox{} }},
+}
+
+var (
+	// tileEntityIDs are the types of the tile entities by their ids
+	tileEntityIDs = make(map[string]*tileEntityType)
+	// tileEntityBlocks are the types of the tile entities of the blocks that have one
+	tileEntityBlocks = make(map[*material.Block]*tileEntityType)
+)
+
+func init() {
+	for i := range tileEntityTypes {
+		t := &tileEntityTypes[i]
+		tileEntityIDs[t.id] = t
+		for _, block := range t.blocks {
+			tileEntityBlocks[block] = t
+		}
+	}
+}
+
+// TileEntity returns the tile entity of the block at the position, or nil if the block has none.
+// A block that should have a tile entity, but was saved without it, gets a new one.
+func (w *World) TileEntity(pos BlockPos) (TileEntity, error) {
+	block, _, err := w.blockAt(pos)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := tileEntityBlocks[block]
+	if !ok {
+		return nil, nil
+	}
+	chunk, err := w.LoadChunk(pos.ToChunkPos())
+	if err != nil {
+		return nil, err
+	}
+	if tileEntity, ok := chunk.tileEntities[pos]; ok {
+		return tileEntity, nil
+	}
+	tileEntity := t.create()
+	chunk.setTileEntity(pos, tileEntity)
+	return tileEntity, nil
+}
+
+// UpdateTileEntity sends the state of the tile entity of the block at the position to the players who have
+// its chunk loaded. It is sent at the end of the tick, after the changed blocks.
+func (w *World) UpdateTileEntity(pos BlockPos) {
+	chunk, ok := w.chunks[pos.ToChunkPos()]
+	if !ok || len(chunk.viewers) == 0 {
+		return
+	}
+	if chunk.changedTileEntities == nil {
+		chunk.changedTileEntities = make(map[BlockPos]struct{})
+	}
+	chunk.changedTileEntities[pos] = struct{}{}
+	w.changedChunks[chunk.pos] = struct{}{}
+}
+
+// removeTileEntity removes the tile entity of the block at the position and returns the items it dropped
+func (w *World) removeTileEntity(pos BlockPos) []inventory.ItemStack {
+	chunk, ok := w.chunks[pos.ToChunkPos()]
+	if !ok {
+		return nil
+	}
+	tileEntity, ok := chunk.tileEntities[pos]
+	if !ok {
+		return nil
+	}
+	delete(chunk.tileEntities, pos)
+	chunk.dirty = true
+	if dropping, ok := tileEntity.(droppingTileEntity); ok {
+		return dropping.drops()
+	}
+	return nil
+}
+
+// tickTileEntities ticks the tile entities of all chunks
+func (w *World) tickTileEntities() {
+	for _, chunk := range w.chunks {
+		for pos, tileEntity := range chunk.tileEntities {
+			if ticking, ok := tileEntity.(tickingTileEntity); ok && ticking.tick(w, pos) {
+				chunk.dirty = true
+			}
+		}
+	}
+}
+
+// setTileEntity puts the tile entity of the block at given world position to the chunk
+func (c *Chunk) setTileEntity(pos BlockPos, tileEntity TileEntity) {
+	if c.tileEntities == nil {
+		c.tileEntities = make(map[BlockPos]TileEntity)
+	}
+	c.tileEntities[pos] = tileEntity
+	c.dirty = true
+}
+
+// replaceTileEntity creates the tile entity of the block placed in place of the old one and removes the tile
+// entity of the old block. The tile entity is kept if both blocks have the same one, like a furnace being lit.
+func (c *Chunk) replaceTileEntity(pos BlockPos, old, block *material.Block) {
+	if old == block {
+		return
+	}
+	oldType, newType := tileEntityBlocks[old], tileEntityBlocks[block]
+	if oldType != nil && oldType == newType {
+		return
+	}
+	delete(c.tileEntities, pos)
+	c.removeTileEntityNBT(pos)
+	if newType != nil {
+		c.setTileEntity(pos, newType.create())
+	}
+}
+
+// SetTileEntityNBT loads the tile entity of the block at given world position from its data, replacing
+// the previous tile entity of the block. The data of unknown tile entities are saved with the chunk as they are,
+// like such tile entities loaded from the disk.
+func (c *Chunk) SetTileEntityNBT(pos BlockPos, data nbt.Compound) {
+	data["x"], data["y"], data["z"] = pos.X, pos.Y, pos.Z
+	c.dirty = true
+	c.removeTileEntityNBT(pos)
+
+	id, _ := data.String("id")
+	if t, ok := tileEntityIDs[id]; ok {
+		tileEntity := t.create()
+		tileEntity.ReadNBT(data)
+		c.setTileEntity(pos, tileEntity)
+		return
+	}
+	delete(c.tileEntities, pos)
+	if c.tileEntitiesNBT.Type == nbt.TagEnd {
+		c.tileEntitiesNBT = nbt.NewList(nbt.TagCompound)
+	}
+	c.tileEntitiesNBT.Values = append(c.tileEntitiesNBT.Values, data)
+}
+
+// removeTileEntityNBT removes the data of the unknown tile entity of the block at given world position
+func (c *Chunk) removeTileEntityNBT(pos BlockPos) {
+	for i, value := range c.tileEntitiesNBT.Values {
+		if data, ok := value.(nbt.Compound); ok && tileEntityAt(data, pos) {
+			c.tileEntitiesNBT.Values = append(c.tileEntitiesNBT.Values[:i], c.tileEntitiesNBT.Values[i+1:]...)
+			return
+		}
+	}
+}
+
+// tileEntityAt checks whether the tile entity data belong to the block at given position
+func tileEntityAt(data nbt.Compound, pos BlockPos) bool {
+	x, okX := data.Int("x")
+	y, okY := data.Int("y")
+	z, okZ := data.Int("z")
+	return okX && okY && okZ && x == pos.X && y == pos.Y && z == pos.Z
+}
+
+// readTileEntityNBT loads the tile entity saved with the chunk. The tile entities of other chunks and those
+// whose block does not have them are dropped, the unknown ones are kept as they are.
+func (c *Chunk) readTileEntityNBT(data nbt.Compound) {
+	x, _ := data.Int("x")
+	y, _ := data.Int("y")
+	z, _ := data.Int("z")
+	chunkPos, cx, cy, cz := WorldToChunkLocal(x, y, z)
+	if chunkPos != c.pos || y < 0 || y >= int32(ChunkHeight) {
+		return
+	}
+	id, _ := data.String("id")
+	t, ok := tileEntityIDs[id]
+	if !ok {
+		c.tileEntitiesNBT.Values = append(c.tileEntitiesNBT.Values, data)
+		return
+	}
+	if tileEntityBlocks[c.blockMaterial(cx, cy, cz)] != t {
+		return
+	}
+	tileEntity := t.create()
+	tileEntity.ReadNBT(data)
+	if c.tileEntities == nil {
+		c.tileEntities = make(map[BlockPos]TileEntity)
+	}
+	c.tileEntities[NewBlockPos(x, y, z)] = tileEntity
+}
+
+// writeTileEntitiesNBT creates the list of the tile entities saved with the chunk
+func (c *Chunk) writeTileEntitiesNBT() nbt.List {
+	tileEntities := nbt.NewList(nbt.TagCompound, c.tileEntitiesNBT.Values...)
+	for pos, tileEntity := range c.tileEntities {
+		_, cx, cy, cz := WorldToChunkLocal(pos.X, pos.Y, pos.Z)
+		t, ok := tileEntityBlocks[c.blockMaterial(cx, cy, cz)]
+		if !ok {
+			continue
+		}
+		data := nbt.Compound{"id": t.id, "x": pos.X, "y": pos.Y, "z": pos.Z}
+		tileEntity.WriteNBT(data)
+		tileEntities.Values = append(tileEntities.Values, data)
+	}
+	return tileEntities
+}
+
+// tileEntityPackets creates the packets with the state of all tile entities of the chunk the clients
+// are told about, they are sent with the whole chunk
+func (c *Chunk) tileEntityPackets() []prot.PacketOut {
+	var packets []prot.PacketOut
+	for pos, tileEntity := range c.tileEntities {
+		if updating, ok := tileEntity.(updatingTileEntity); ok {
+			packets = append(packets, updating.updatePacket(pos))
+		}
+	}
+	return packets
+}
@@ -0,0 +1,138 @@
+package world
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Pesekjak/173go/pkg/nbt"
+	"github.com/Pesekjak/173go/pkg/world/inventory"
+	"github.com/Pesekjak/173go/pkg/world/material"
+)
+
+// placeTileEntity places the block and returns its new tile entity
+func placeTileEntity(t *testing.T, w *World, pos BlockPos, block *material.Block, data byte) TileEntity {
+	t.Helper()
+	if err := w.setBlock(pos.X, pos.Y, pos.Z, block, data); err != nil {
+		t.Fatal(err)
+	}
+	tileEntity, err := w.TileEntity(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tileEntity
+}
+
+func TestTileEntityRoundTrip(t *testing.T) {
+	w := newTestWorld(t)
+	chestPos, furnacePos := NewBlockPos(-20, 5, 7), NewBlockPos(4, 5, 4)
+	chestItems := map[int]inventory.ItemStack{
+		0:  inventory.NewItemStack(material.Cobblestone, 64, 0),
+		13: inventory.NewItemStack(material.Wool, 5, 14),
+		26: inventory.NewItemStack(material.DiamondPickaxe, 1, 700),
+	}
+	chest := placeTileEntity(t, w, chestPos, material.Chest, 0).(*Chest)
+	for slot, stack := range chestItems {
+		chest.Inventory.SetSlot(slot, stack)
+	}
+
+	// the furnace is saved in the middle of smelting, while burning the first of its coal
+	furnace := placeTileEntity(t, w, furnacePos, material.Furnace, 3).(*Furnace)
+	furnace.Inventory.SetSlot(inventory.FurnaceIngredientSlot, inventory.NewItemStack(material.IronOre, 10, 0))
+	furnace.Inventory.SetSlot(inventory.FurnaceFuelSlot, inventory.NewItemStack(material.Coal, 3, 0))
+	for range 250 {
+		w.Tick()
+	}
+	saved := nbt.Compound{}
+	furnace.WriteNBT(saved)
+
+	w = reopenWorld(t, w)
+	loaded, err := w.TileEntity(chestPos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadedChest, ok := loaded.(*Chest)
+	if !ok {
+		t.Fatalf("tile entity of the chest is %T", loaded)
+	}
+	for slot := 0; slot < loadedChest.Inventory.Size(); slot++ {
+		want, ok := chestItems[slot]
+		if !ok {
+			want = inventory.EmptyStack()
+		}
+		if got := loadedChest.Inventory.Slot(slot); got != want {
+			t.Errorf("slot %v of the chest has %v:%v, want %v:%v", slot, got, got.Data, want, want.Data)
+		}
+	}
+
+	loadedFurnace, err := w.Furnace(furnacePos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block, data, _ := w.blockAt(furnacePos); block != material.FurnaceLit || data != 3 {
+		t.Errorf("furnace block is %v:%v, want %v:3", block, data, material.FurnaceLit)
+	}
+	if got, want := loadedFurnace.ProgressBars(), []int16{50, 1351, 1600}; !reflect.DeepEqual(got, want) {
+		t.Errorf("progress bars of the furnace are %v, want %v", got, want)
+	}
+	for slot, want := range []inventory.ItemStack{
+		inventory.NewItemStack(material.IronOre, 9, 0),
+		inventory.NewItemStack(material.Coal, 2, 0),
+		inventory.NewItemStack(material.IronIngot, 1, 0),
+	} {
+		if got := loadedFurnace.Inventory.Slot(slot); got != want {
+			t.Errorf("slot %v of the furnace has %v, want %v", slot, got, want)
+		}
+	}
+	written := nbt.Compound{}
+	loadedFurnace.WriteNBT(written)
+	if !reflect.DeepEqual(written, saved) {
+		t.Errorf("furnace is written as %v after loading, want %v", written, saved)
+	}
+
+	// the loaded furnace keeps smelting where it stopped
+	for range 150 {
+		w.Tick()
+	}
+	if got := loadedFurnace.Inventory.Slot(inventory.FurnaceResultSlot).Count; got != 2 {
+		t.Errorf("loaded furnace smelted %v iron ingots, want 2", got)
+	}
+}
+
+func TestContainerNBT(t *testing.T) {
+	furnace := newFurnace()
+	furnace.Inventory.SetSlot(inventory.FurnaceFuelSlot, inventory.NewItemStack(material.LavaBucket, 1, 0))
+	furnace.Inventory.SetSlot(inventory.FurnaceResultSlot, inventory.NewItemStack(material.Stone, 12, 0))
+	furnace.burnTime, furnace.cookTime = 20, 199
+	data := nbt.Compound{}
+	furnace.WriteNBT(data)
+
+	// the items and the times are saved as the Notchian server saves them
+	want := nbt.Compound{
+		"Items": nbt.NewList(nbt.TagCompound,
+			nbt.Compound{"Slot": int8(1), "id": int16(material.LavaBucket.Id()), "Count": int8(1), "Damage": int16(0)},
+			nbt.Compound{"Slot": int8(2), "id": int16(material.Stone.Id()), "Count": int8(12), "Damage": int16(0)},
+		),
+		"BurnTime": int16(20),
+		"CookTime": int16(199),
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("furnace is written as %v, want %v", data, want)
+	}
+
+	// the items of unknown materials and outside the chest are left out
+	chest := newChest()
+	chest.ReadNBT(nbt.Compound{"Items": nbt.NewList(nbt.TagCompound,
+		nbt.Compound{"Slot": int8(3), "id": int16(material.Torch.Id()), "Count": int8(7), "Damage": int16(0)},
+		nbt.Compound{"Slot": int8(4), "id": int16(4000), "Count": int8(1), "Damage": int16(0)},
+		nbt.Compound{"Slot": int8(27), "id": int16(material.Stone.Id()), "Count": int8(1), "Damage": int16(0)},
+	)})
+	for slot := 0; slot < chest.Inventory.Size(); slot++ {
+		want := inventory.EmptyStack()
+		if slot == 3 {
+			want = inventory.NewItemStack(material.Torch, 7, 0)
+		}
+		if got := chest.Inventory.Slot(slot); got != want {
+			t.Errorf("slot %v of the chest has %v, want %v", slot, got, want)
+		}
+	}
+}
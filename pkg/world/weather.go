@@ -0,0 +1,72 @@
+package world
+
+import "github.com/Pesekjak/173go/pkg/prot"
+
+// weather is the rain and thunder state of a world.
+// Both run in cycles, when their time runs out the state is flipped and a new time is picked.
+type weather struct {
+	raining     bool
+	rainTime    int32
+	thundering  bool
+	thunderTime int32
+}
+
+// tickWeather advances the weather cycles of the world
+func (w *World) tickWeather() {
+	if w.dimension == Hell {
+		return // there is no weather without sky
+	}
+
+	if w.weather.thunderTime <= 0 {
+		w.weather.thunderTime = w.nextWeatherTime(w.weather.thundering)
+	} else if w.weather.thunderTime--; w.weather.thunderTime <= 0 {
+		w.weather.thundering = !w.weather.thundering
+	}
+
+	if w.weather.rainTime <= 0 {
+		w.weather.rainTime = w.nextWeatherTime(w.weather.raining)
+	} else if w.weather.rainTime--; w.weather.rainTime <= 0 {
+		w.SetRaining(!w.weather.raining)
+	}
+}
+
+// nextWeatherTime picks duration of the current weather state, weather lasts shorter than clear sky
+func (w *World) nextWeatherTime(active bool) int32 {
+	if active {
+		return w.random.Int31n(12000) + 3600
+	}
+	return w.random.Int31n(168000) + 12000
+}
+
+// Raining returns whether it rains (or snows in cold biomes) in the world.
+func (w *World) Raining() bool {
+	return w.weather.raining
+}
+
+// Thundering returns whether there is a thunderstorm in the world.
+func (w *World) Thundering() bool {
+	return w.weather.thundering
+}
+
+// SetRaining starts or stops the rain and notifies the players.
+func (w *World) SetRaining(raining bool) {
+	if w.weather.raining == raining {
+		return
+	}
+	w.weather.raining = raining
+	w.weather.rainTime = w.nextWeatherTime(raining)
+	for _, player := range w.Players() {
+		if err := w.sendWeather(player); err != nil {
+			player.Disconnect(err)
+		}
+	}
+}
+
+// sendWeather sends the rain state to the player
+func (w *World) sendWeather(player PlayerEntity) error {
+	reason := prot.StateEndRain
+	if w.weather.raining {
+		reason = prot.StateBeginRain
+	}
+	return player.Connection().WritePacket(&prot.PacketOutNewState{Reason: reason}, true)
+}
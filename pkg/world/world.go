@@ -2,47 +2,215 @@ package world
 
 import (
 	"fmt"
+	"math/rand"
+	"path/filepath"
 
+	"github.com/Pesekjak/173go/pkg/nbt"
 	"github.com/Pesekjak/173go/pkg/prot"
+	"github.com/Pesekjak/173go/pkg/world/material"
 )
 
+const (
+	// timeUpdateInterval is the number of ticks between two time updates sent to the players
+	timeUpdateInterval = 20
+	// maxUnloadedChunks is the maximum number of chunks unloaded at once, as on Notchian
+	maxUnloadedChunks = 100
+	// spawnChunkRadius is the distance in chunks from the spawn point within which the chunks
+	// of the overworld are never unloaded
+	spawnChunkRadius = 8
+)
+
+// World is a single dimension of the game.
+//
+// A World is not safe for concurrent use. It is owned by a single goroutine (the server goroutine)
+// and all of its methods, as well as methods of its chunks and blocks, must be called from that
+// goroutine only. Other goroutines must hand the work over to the owner instead of calling into the world.
 type World struct {
+	dir string
+
 	dimension  Dimension
+	seed       int64
 	SpawnPoint BlockPos
 	time       int64
+	weather    weather
+	random     *rand.Rand
+
+	// player is the single player data from level.dat, kept as it is
+	player nbt.Compound
 
 	generator Generator
+	populator Populator
+	biomes    BiomeSource
+	storage   *chunkStorage
 
 	chunks map[ChunkPos]*Chunk
+	light  *lightEngine
+	// changedChunks are the chunks with blocks changed during the current tick
+	changedChunks map[ChunkPos]struct{}
 
 	entities map[int32]Entity
 }
 
-func NewWorld() (*World, error) {
-	dimension := Overworld
-	spawnPoint := NewBlockPos(0, 60, 0)
+// NewWorld creates a world of given dimension stored in given directory. If the directory contains level.dat,
+// the world metadata are loaded from it, otherwise a new world with given seed is created.
+// Chunks that have not been saved to the directory yet are generated by a generator
+// created for the seed of the world.
+func NewWorld(dir string, dimension Dimension, seed int64, newGenerator GeneratorFactory) (*World, error) {
+	spawnPoint := NewBlockPos(0, 64, 0)
 	time := int64(0)
 
-	generator, err := MakeStandardFlatGenerator()
-	if err != nil {
-		return nil, err
-	}
-
 	chunks := make(map[ChunkPos]*Chunk)
 
 	entities := make(map[int32]Entity)
 
-	return &World{
+	w := &World{
+		dir: dir,
+
 		dimension:  dimension,
+		seed:       seed,
 		SpawnPoint: spawnPoint,
 		time:       time,
+		random:     rand.New(rand.NewSource(seed)),
 
-		generator: generator,
+		storage: newChunkStorage(dir),
 
-		chunks: chunks,
+		chunks:        chunks,
+		changedChunks: make(map[ChunkPos]struct{}),
 
 		entities: entities,
-	}, nil
+	}
+	w.light = newLightEngine(w)
+
+	level, ok, err := readLevel(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %v of world %v: %v", levelFile, dir, err)
+	}
+	if ok {
+		w.seed = level.RandomSeed
+		w.SpawnPoint = NewBlockPos(level.SpawnX, level.SpawnY, level.SpawnZ)
+		w.time = level.Time
+		w.weather = weather{
+			raining:     level.Raining,
+			rainTime:    level.RainTime,
+			thundering:  level.Thundering,
+			thunderTime: level.ThunderTime,
+		}
+		w.player = level.Player
+	}
+
+	generator, err := newGenerator(w.seed)
+	if err != nil {
+		return nil, err
+	}
+	w.generator = generator
+	w.populator, _ = generator.(Populator)
+	if biomes, ok := generator.(BiomeSource); ok {
+		w.biomes = biomes
+	} else {
+		w.biomes = newFixedBiomeSource(w.dimension)
+	}
+
+	if !ok {
+		if err = w.findSpawn(); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// maxSpawnAttempts limits the search for the spawn of a new world
+const maxSpawnAttempts = 1000
+
+// findSpawn picks spawn point of a new world by a random walk from the origin,
+// until a column accepted by the generator is found
+func (w *World) findSpawn() error {
+	checker, ok := w.generator.(SpawnChecker)
+	if !ok {
+		return nil
+	}
+	var x, z int32
+	for attempt := 0; attempt < maxSpawnAttempts; attempt++ {
+		if valid, err := checker.CanSpawnAt(w, x, z); err != nil {
+			return fmt.Errorf("failed to find the spawn point: %v", err)
+		} else if valid {
+			w.SpawnPoint = NewBlockPos(x, 64, z)
+			return nil
+		}
+		x += w.random.Int31n(64) - w.random.Int31n(64)
+		z += w.random.Int31n(64) - w.random.Int31n(64)
+	}
+	return nil // keep the default spawn
+}
+
+// SpawnLocation returns location players spawn at, on top of the highest block in the column of the spawn point.
+// The nether has a ceiling, so players spawn on top of the first uncovered block above the sea level there.
+func (w *World) SpawnLocation() (Location, error) {
+	var y int32
+	if w.dimension == Hell {
+		block, err := w.FirstUncoveredBlock(w.SpawnPoint.X, w.SpawnPoint.Z)
+		if err != nil {
+			return Location{}, err
+		}
+		y = block.Position().Y
+	} else {
+		var err error
+		if y, err = w.HighestBlockY(w.SpawnPoint.X, w.SpawnPoint.Z); err != nil {
+			return Location{}, err
+		}
+	}
+	return NewLocation(float64(w.SpawnPoint.X)+0.5, float64(y+1), float64(w.SpawnPoint.Z)+0.5, 0, 0), nil
+}
+
+// GetBlock returns block at given world coordinates, loading its chunk if needed.
+func (w *World) GetBlock(x, y, z int32) (Block, error) {
+	if y < 0 || y >= int32(ChunkHeight) {
+		return nil, fmt.Errorf("y coordinate %v is out of bounds of the world", y)
+	}
+	pos, cx, cy, cz := WorldToChunkLocal(x, y, z)
+	chunk, err := w.LoadChunk(pos)
+	if err != nil {
+		return nil, err
+	}
+	return chunk.GetBlock(cx, cy, cz)
+}
+
+// HighestBlockY returns Y coordinate of the highest non-air block in the column, or -1 if the column is empty.
+func (w *World) HighestBlockY(x, z int32) (int32, error) {
+	for y := int32(ChunkHeight) - 1; y >= 0; y-- {
+		block, err := w.GetBlock(x, y, z)
+		if err != nil {
+			return 0, err
+		}
+		if block.Material() != material.Air {
+			return y, nil
+		}
+	}
+	return -1, nil
+}
+
+// FirstUncoveredBlock returns the first block above the sea level with air above it in the column.
+func (w *World) FirstUncoveredBlock(x, z int32) (Block, error) {
+	y := int32(63)
+	for ; y < int32(ChunkHeight)-1; y++ {
+		above, err := w.GetBlock(x, y+1, z)
+		if err != nil {
+			return nil, err
+		}
+		if above.Material() == material.Air {
+			break
+		}
+	}
+	return w.GetBlock(x, y, z)
+}
+
+// Seed returns the seed the world is generated from.
+func (w *World) Seed() int64 {
+	return w.seed
+}
+
+// Name returns name of the world, the name of its directory.
+func (w *World) Name() string {
+	return filepath.Base(w.dir)
 }
 
 func (w *World) Dimension() Dimension {
@@ -53,42 +221,165 @@ func (w *World) Time() int64 {
 	return w.time
 }
 
+// Tick advances the world by a single tick.
+func (w *World) Tick() {
+	w.time++
+	w.tickWeather()
+	w.tickItems()
+	w.tickTileEntities()
+	w.light.flush()
+	w.sendBlockChanges()
+
+	if w.time%timeUpdateInterval == 0 {
+		for _, player := range w.Players() {
+			err := player.Connection().WritePacket(&prot.PacketOutTimeUpdate{Time: w.time}, true)
+			if err != nil {
+				player.Disconnect(err)
+			}
+		}
+	}
+}
+
+// Players returns all players in this world.
+func (w *World) Players() []PlayerEntity {
+	var players []PlayerEntity
+	for _, entity := range w.entities {
+		if player, ok := entity.(PlayerEntity); ok {
+			players = append(players, player)
+		}
+	}
+	return players
+}
+
 func (w *World) SpawnPlayer(player PlayerEntity) error {
 	if _, ok := w.entities[player.Id()]; ok {
 		return fmt.Errorf("there is already an entity with id %v in this world", player.Id())
 	}
+	w.entities[player.Id()] = player
+	return nil
+}
 
-	connection := player.Connection()
+// RemoveEntity removes the entity from this world. Players stop receiving changes of the chunks they had loaded.
+func (w *World) RemoveEntity(entity Entity) {
+	if current, ok := w.entities[entity.Id()]; ok && current == entity {
+		delete(w.entities, entity.Id())
+	}
+	if player, ok := entity.(PlayerEntity); ok {
+		for _, chunk := range w.chunks {
+			if chunk.viewers[player.Id()] == player {
+				delete(chunk.viewers, player.Id())
+			}
+		}
+	}
+}
 
-	for pos, chunk := range w.chunks {
-		err := connection.WritePacket(&prot.PacketOutPreChunk{
-			X:    pos.X,
-			Z:    pos.Z,
-			Load: true,
-		}, false)
-		if err != nil {
-			return err
+// SendChunk loads the chunk at given position and sends it to the player.
+// The connection is not flushed.
+func (w *World) SendChunk(player PlayerEntity, pos ChunkPos) error {
+	// the chunks around are loaded first, so the chunk is populated from all sides before it is sent
+	for x := pos.X - 1; x <= pos.X+1; x++ {
+		for z := pos.Z - 1; z <= pos.Z+1; z++ {
+			if _, err := w.LoadChunk(NewChunkPos(x, z)); err != nil {
+				return err
+			}
 		}
-		chunkData, err := chunk.data()
-		if err != nil {
+	}
+	chunk, err := w.LoadChunk(pos)
+	if err != nil {
+		return err
+	}
+	// the chunk is sent with the light of the blocks changed during this tick
+	w.light.flush()
+
+	connection := player.Connection()
+	err = connection.WritePacket(&prot.PacketOutPreChunk{
+		X:    pos.X,
+		Z:    pos.Z,
+		Load: true,
+	}, false)
+	if err != nil {
+		return err
+	}
+	packet, err := chunk.dataPacket()
+	if err != nil {
+		return err
+	}
+	if err = connection.WritePacket(packet, false); err != nil {
+		return err
+	}
+	for _, item := range chunk.items {
+		if err = connection.WritePacket(item.spawnPacket(), false); err != nil {
 			return err
 		}
-		dataPacket := prot.PacketOutMapChunk{
-			X:     pos.X * 16,
-			Y:     0,
-			Z:     pos.Z * 16,
-			SizeX: byte(ChunkSize) - 1,
-			SizeY: byte(ChunkHeight) - 1,
-			SizeZ: byte(ChunkSize) - 1,
-			Data:  chunkData,
+	}
+	if err = writePackets(player, chunk.tileEntityPackets()); err != nil {
+		return err
+	}
+	chunk.viewers[player.Id()] = player
+	return nil
+}
+
+// HideChunk makes the player's client unload the chunk at given position.
+// The connection is not flushed.
+func (w *World) HideChunk(player PlayerEntity, pos ChunkPos) error {
+	if chunk, ok := w.chunks[pos]; ok && chunk.viewers[player.Id()] == player {
+		delete(chunk.viewers, player.Id())
+	}
+	return player.Connection().WritePacket(&prot.PacketOutPreChunk{
+		X:    pos.X,
+		Z:    pos.Z,
+		Load: false,
+	}, false)
+}
+
+// UnloadChunks saves and unloads up to maxUnloadedChunks chunks no player needs. The chunks sent to players stay
+// loaded with the chunks next to them, which they are populated and lit with. The chunks with dropped items,
+// which are not saved, and the chunks around the spawn point of the overworld stay loaded as well.
+func (w *World) UnloadChunks() error {
+	// the light spreading from the unloaded chunks is updated before they are gone
+	w.light.flush()
+	unloaded := 0
+	for pos, chunk := range w.chunks {
+		if unloaded == maxUnloadedChunks {
+			break
 		}
-		err = connection.WritePacket(&dataPacket, false)
-		if err != nil {
-			return err
+		if !w.canUnload(chunk) {
+			continue
 		}
+		if chunk.dirty {
+			if err := w.storage.saveChunk(chunk); err != nil {
+				return fmt.Errorf("failed to save chunk %v;%v: %v", pos.X, pos.Z, err)
+			}
+			chunk.dirty = false
+		}
+		delete(w.chunks, pos)
+		delete(w.changedChunks, pos)
+		unloaded++
+	}
+	if unloaded > 0 {
+		w.light.last = nil
 	}
+	return nil
+}
 
-	return connection.Flush()
+// canUnload checks whether the chunk is not needed by any player
+func (w *World) canUnload(chunk *Chunk) bool {
+	if len(chunk.items) > 0 {
+		return false
+	}
+	spawn := w.SpawnPoint.ToChunkPos()
+	if w.dimension == Overworld && chunk.pos.X >= spawn.X-spawnChunkRadius && chunk.pos.X <= spawn.X+spawnChunkRadius &&
+		chunk.pos.Z >= spawn.Z-spawnChunkRadius && chunk.pos.Z <= spawn.Z+spawnChunkRadius {
+		return false
+	}
+	for x := chunk.pos.X - 1; x <= chunk.pos.X+1; x++ {
+		for z := chunk.pos.Z - 1; z <= chunk.pos.Z+1; z++ {
+			if neighbour, ok := w.chunks[NewChunkPos(x, z)]; ok && len(neighbour.viewers) > 0 {
+				return false
+			}
+		}
+	}
+	return true
 }
 
 func (w *World) LoadChunk(pos ChunkPos) (*Chunk, error) {
@@ -96,22 +387,123 @@ func (w *World) LoadChunk(pos ChunkPos) (*Chunk, error) {
 		return loaded, nil
 	}
 
-	chunk, err := newChunk(w, pos, func(block Block) error {
-		return nil // no block updates for now
-	})
+	chunk := newChunk(w, pos, w.queueBlockChange)
+
+	loaded, err := w.storage.loadChunk(chunk)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to load chunk %v;%v: %v", pos.X, pos.Z, err)
 	}
 	w.chunks[pos] = chunk
-	if err = w.generator.GenerateBlocks(chunk); err != nil {
-		return nil, err
+
+	if loaded {
+		chunk.updateHeightMap()
+		// chunks saved before the sky light was computed are lit as if they were generated
+		if w.dimension.HasSkyLight() && chunk.skyLight.isEmpty() {
+			w.light.lightChunk(chunk)
+		}
+		chunk.generated = true
+	} else {
+		if err = w.generator.GenerateBlocks(chunk); err != nil {
+			return nil, err
+		}
+		w.light.lightChunk(chunk)
+		chunk.generated = true
+		chunk.populated = w.populator == nil // there is nothing to populate the chunk with
+		chunk.dirty = true
 	}
-	if err = lightChunkBorders(w, chunk); err != nil {
+
+	if err = w.populateAround(pos); err != nil {
 		return nil, err
 	}
 	return chunk, nil
 }
 
+// populateAround populates the chunks that can be populated since the chunk at given position was loaded.
+// Population of a chunk spans the chunks at +1 in both axes, it waits until all of them are loaded.
+func (w *World) populateAround(pos ChunkPos) error {
+	if w.populator == nil {
+		return nil
+	}
+	corners := [...]ChunkPos{pos, {pos.X - 1, pos.Z}, {pos.X, pos.Z - 1}, {pos.X - 1, pos.Z - 1}}
+	for _, corner := range corners {
+		chunk, ok := w.chunks[corner]
+		if !ok || chunk.populated {
+			continue
+		}
+		if !w.isLoaded(corner.X+1, corner.Z) || !w.isLoaded(corner.X, corner.Z+1) || !w.isLoaded(corner.X+1, corner.Z+1) {
+			continue
+		}
+		chunk.populated = true
+		chunk.dirty = true
+		if err := w.populator.Populate(w, corner); err != nil {
+			return fmt.Errorf("failed to populate chunk %v;%v: %v", corner.X, corner.Z, err)
+		}
+	}
+	return nil
+}
+
+// columnHeight returns the lowest Y of the column above which the sky light is not blocked,
+// ok is false if the chunk of the column is not loaded
+func (w *World) columnHeight(x, z int32) (height uint32, ok bool) {
+	pos, cx, _, cz := WorldToChunkLocal(x, 0, z)
+	chunk, ok := w.chunks[pos]
+	if !ok {
+		return 0, false
+	}
+	return chunk.height(cx, cz), true
+}
+
+func (w *World) isLoaded(x, z int32) bool {
+	_, ok := w.chunks[NewChunkPos(x, z)]
+	return ok
+}
+
+// Save writes the world metadata and all chunks modified since the last save to the disk.
+func (w *World) Save() error {
+	for _, chunk := range w.chunks {
+		if !chunk.dirty {
+			continue
+		}
+		if err := w.storage.saveChunk(chunk); err != nil {
+			return fmt.Errorf("failed to save chunk %v;%v: %v", chunk.pos.X, chunk.pos.Z, err)
+		}
+		chunk.dirty = false
+	}
+	if err := w.storage.sync(); err != nil {
+		return err
+	}
+
+	err := writeLevel(w.dir, &levelData{
+		LevelName:   w.Name(),
+		Version:     mcRegionVersion,
+		RandomSeed:  w.seed,
+		SpawnX:      w.SpawnPoint.X,
+		SpawnY:      w.SpawnPoint.Y,
+		SpawnZ:      w.SpawnPoint.Z,
+		Time:        w.time,
+		LastPlayed:  currentTimeMillis(),
+		SizeOnDisk:  directorySize(w.dir),
+		Raining:     w.weather.raining,
+		RainTime:    w.weather.rainTime,
+		Thundering:  w.weather.thundering,
+		ThunderTime: w.weather.thunderTime,
+		Player:      w.player,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save %v: %v", levelFile, err)
+	}
+	return nil
+}
+
+// Close saves the world and releases its files. The world must not be used afterwards.
+func (w *World) Close() error {
+	if err := w.Save(); err != nil {
+		_ = w.storage.close()
+		return err
+	}
+	return w.storage.close()
+}
+
 func (w *World) Chunk(pos ChunkPos) (*Chunk, bool) {
 	c, ok := w.chunks[pos]
 	return c, ok